@@ -0,0 +1,257 @@
+package blockchain
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/smallnest/blockchain/wallet"
+)
+
+// 写入区块时携带公钥、签名以及防止重放的时间戳和nonce的http头.
+const (
+	HeaderPublicKey = "X-Public-Key"
+	HeaderSignature = "X-Signature"
+	HeaderTimestamp = "X-Timestamp"
+	HeaderNonce     = "X-Nonce"
+)
+
+// DefaultMaxClockSkew 是写入请求的时间戳和服务器时间之间默认允许的最大偏差.
+const DefaultMaxClockSkew = 5 * time.Minute
+
+// nonce的长度限制, 十六进制编码后的字符数.
+const (
+	minNonceSize = 16
+	maxNonceSize = 64
+)
+
+// writeMessagePrefix 是写入请求签名的消息的前缀, 避免签名被用于其它用途.
+const writeMessagePrefix = "blockchain write:\n"
+
+var (
+	// ErrUnauthenticated 没有签名或者签名不正确.
+	ErrUnauthenticated = errors.New("missing or invalid signature")
+	// ErrForbidden 签名正确, 但是公钥没有写入的权限.
+	ErrForbidden = errors.New("submitter is not authorized")
+	// ErrStaleRequest 请求的时间戳超出了允许的时间窗口.
+	ErrStaleRequest = fmt.Errorf("%w: timestamp is outside the allowed window", ErrUnauthenticated)
+	// ErrReplayedRequest 请求的nonce已经使用过, 请求可能是被截获后重放的.
+	ErrReplayedRequest = fmt.Errorf("%w: nonce has already been used", ErrUnauthenticated)
+)
+
+// Credentials 是写入请求的签名. Signature是PublicKey对WriteMessage(Timestamp, Nonce, data)的签名,
+// 签名覆盖了时间戳和nonce, 截获的请求不能在时间窗口之外或者重复使用.
+type Credentials struct {
+	PublicKey string // 十六进制编码的公钥
+	Signature string // 十六进制编码的签名
+	Timestamp int64  // 签名时的unix时间, 单位秒
+	Nonce     string // 十六进制编码的随机数
+}
+
+// SignCredentials 使用私钥对写入的数据签名, 时间戳为当前时间, nonce随机生成.
+// sigType为0时使用没有标记的DER编码的ECDSA签名.
+func SignCredentials(sigType SigType, privateKey string, data []byte) (*Credentials, error) {
+	var nonce [16]byte
+	if _, err := rand.Read(nonce[:]); err != nil {
+		return nil, err
+	}
+
+	c := &Credentials{
+		Timestamp: time.Now().Unix(),
+		Nonce:     hex.EncodeToString(nonce[:]),
+	}
+	msg := WriteMessage(c.Timestamp, c.Nonce, data)
+	var (
+		signed []byte
+		err    error
+	)
+	if sigType == 0 {
+		signed, err = Sign(privateKey, msg)
+	} else {
+		signed, err = SignTagged(sigType, privateKey, msg)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if c.PublicKey, _, err = wallet.GetPublicKey(privateKey); err != nil {
+		return nil, err
+	}
+	c.Signature = hex.EncodeToString(signed)
+	return c, nil
+}
+
+// WriteMessage 返回写入请求实际签名的消息, 包括时间戳、nonce和写入的数据.
+func WriteMessage(timestamp int64, nonce string, data []byte) []byte {
+	msg := writeMessagePrefix + strconv.FormatInt(timestamp, 10) + "\n" + nonce + "\n"
+	return append([]byte(msg), data...)
+}
+
+// validNonce 检查nonce是长度合适的十六进制字符串.
+func validNonce(nonce string) bool {
+	if len(nonce) < minNonceSize || len(nonce) > maxNonceSize {
+		return false
+	}
+	_, err := hex.DecodeString(nonce)
+	return err == nil
+}
+
+// Authorizer 保存允许写入区块的公钥和P2PKH地址, 并记录时间窗口内使用过的nonce.
+type Authorizer struct {
+	keys      map[string]struct{}
+	addresses map[string]struct{}
+	// MaxClockSkew 是请求的时间戳允许的最大偏差, 为0时使用DefaultMaxClockSkew
+	MaxClockSkew time.Duration
+
+	mu        sync.Mutex
+	nonces    map[string]time.Time // 使用过的nonce和它的过期时间
+	lastPrune time.Time
+}
+
+// NewAuthorizer 创建一个Authorizer, entries中的每一项是十六进制的公钥或者P2PKH地址.
+func NewAuthorizer(entries []string) *Authorizer {
+	a := &Authorizer{
+		keys:      make(map[string]struct{}),
+		addresses: make(map[string]struct{}),
+		nonces:    make(map[string]time.Time),
+	}
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if isPublicKey(entry) {
+			a.keys[normalizePublicKey(entry)] = struct{}{}
+		} else {
+			a.addresses[entry] = struct{}{}
+		}
+	}
+	return a
+}
+
+// LoadAuthorizer 从文件中加载Authorizer, 每行一个公钥或者地址, #开头的行是注释.
+// 地址的校验和不正确时返回错误.
+func LoadAuthorizer(file string) (*Authorizer, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []string
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if !isPublicKey(line) {
+			if _, _, err = wallet.DecodeAddress(line); err != nil {
+				return nil, fmt.Errorf("%s:%d: invalid address %q: %v", file, n, line, err)
+			}
+		}
+		entries = append(entries, line)
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, err
+	}
+	return NewAuthorizer(entries), nil
+}
+
+// isPublicKey 判断entry是十六进制的公钥而不是地址.
+func isPublicKey(entry string) bool {
+	_, err := hex.DecodeString(entry)
+	return err == nil && len(entry) > 40
+}
+
+// normalizePublicKey 把公钥统一为小写的压缩公钥, 同一个私钥的压缩和未压缩公钥是同一个写入者.
+// 格式不正确的公钥只转换为小写.
+func normalizePublicKey(publicKey string) string {
+	publicKey = strings.ToLower(publicKey)
+	if compressed, err := wallet.CompressPublicKey(publicKey); err == nil {
+		return compressed
+	}
+	return publicKey
+}
+
+// Authorized 判断公钥是否有写入的权限, 压缩和未压缩的公钥都可以匹配.
+func (a *Authorizer) Authorized(publicKey string) bool {
+	publicKey = strings.ToLower(publicKey)
+	if _, ok := a.keys[normalizePublicKey(publicKey)]; ok {
+		return true
+	}
+	if len(a.addresses) == 0 {
+		return false
+	}
+	address, err := wallet.PublicKey2P2PKH(publicKey)
+	if err != nil {
+		return false
+	}
+	_, ok := a.addresses[address]
+	return ok
+}
+
+// Check 校验c.Signature是c.PublicKey对写入数据data的签名, 时间戳在允许的时间窗口内,
+// nonce没有使用过, 并且公钥有写入的权限. 签名可以是DER编码的ECDSA签名或者带SigType标记的签名.
+func (a *Authorizer) Check(c Credentials, data []byte) error {
+	if c.PublicKey == "" || c.Signature == "" || !validNonce(c.Nonce) {
+		return ErrUnauthenticated
+	}
+
+	now := time.Now()
+	skew := a.maxClockSkew()
+	signedAt := time.Unix(c.Timestamp, 0)
+	if signedAt.Before(now.Add(-skew)) || signedAt.After(now.Add(skew)) {
+		return ErrStaleRequest
+	}
+
+	signed, err := hex.DecodeString(c.Signature)
+	if err != nil {
+		return ErrUnauthenticated
+	}
+	if !VerifyTagged(c.PublicKey, signed, WriteMessage(c.Timestamp, c.Nonce, data)) {
+		return ErrUnauthenticated
+	}
+	if !a.Authorized(c.PublicKey) {
+		return ErrForbidden
+	}
+
+	// 超出时间窗口的请求会因为时间戳被拒绝, nonce只需要保存到那时
+	return a.useNonce(c.PublicKey, c.Nonce, signedAt.Add(skew), now)
+}
+
+func (a *Authorizer) maxClockSkew() time.Duration {
+	if a.MaxClockSkew > 0 {
+		return a.MaxClockSkew
+	}
+	return DefaultMaxClockSkew
+}
+
+// useNonce 记录公钥使用的nonce, 已经使用过时返回ErrReplayedRequest.
+func (a *Authorizer) useNonce(publicKey, nonce string, expires, now time.Time) error {
+	key := strings.ToLower(publicKey) + ":" + strings.ToLower(nonce)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if now.Sub(a.lastPrune) > a.maxClockSkew() {
+		for k, exp := range a.nonces {
+			if now.After(exp) {
+				delete(a.nonces, k)
+			}
+		}
+		a.lastPrune = now
+	}
+
+	if _, ok := a.nonces[key]; ok {
+		return ErrReplayedRequest
+	}
+	a.nonces[key] = expires
+	return nil
+}
@@ -0,0 +1,236 @@
+package blockchain
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/smallnest/blockchain/wallet"
+)
+
+func TestAuthorizedWrite(t *testing.T) {
+	privateKey, _, _, address, _ := wallet.GenerateKeys()
+	otherKey, _, otherPublicKey, _, _ := wallet.GenerateKeys()
+
+	s := &Server{
+		Blockchain: newTestBlockchain(t, 0),
+		Authorizer: NewAuthorizer([]string{address}),
+	}
+	handler := s.configRouter()
+
+	write := func(privateKey string, signedData, data []byte) int {
+		req := httptest.NewRequest(http.MethodPost, "/blocks", bytes.NewReader(data))
+		if privateKey != "" {
+			c, err := SignCredentials(0, privateKey, signedData)
+			if err != nil {
+				t.Fatal(err)
+			}
+			setCredentials(req, c)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	data := []byte("hello")
+	if code := write("", nil, data); code != http.StatusUnauthorized {
+		t.Errorf("expect 401 without signature but got %d", code)
+	}
+	if code := write(privateKey, []byte("other"), data); code != http.StatusUnauthorized {
+		t.Errorf("expect 401 with a wrong signature but got %d", code)
+	}
+	if code := write(otherKey, data, data); code != http.StatusForbidden {
+		t.Errorf("expect 403 for an unknown key but got %d", code)
+	}
+	if code := write(privateKey, data, data); code != http.StatusOK {
+		t.Errorf("expect 200 for an authorized address but got %d", code)
+	}
+
+	s.Authorizer = NewAuthorizer([]string{otherPublicKey})
+	if code := write(otherKey, data, data); code != http.StatusOK {
+		t.Errorf("expect 200 for an authorized key but got %d", code)
+	}
+}
+
+func setCredentials(req *http.Request, c *Credentials) {
+	req.Header.Set(HeaderPublicKey, c.PublicKey)
+	req.Header.Set(HeaderSignature, c.Signature)
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(c.Timestamp, 10))
+	req.Header.Set(HeaderNonce, c.Nonce)
+}
+
+func TestReplayedWrite(t *testing.T) {
+	privateKey, _, publicKey, _, _ := wallet.GenerateKeys()
+	s := &Server{
+		Blockchain: newTestBlockchain(t, 0),
+		Authorizer: NewAuthorizer([]string{publicKey}),
+	}
+	handler := s.configRouter()
+
+	send := func(c *Credentials, data []byte) int {
+		req := httptest.NewRequest(http.MethodPost, "/blocks", bytes.NewReader(data))
+		setCredentials(req, c)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	data := []byte("hello")
+	captured, err := SignCredentials(0, privateKey, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if code := send(captured, data); code != http.StatusOK {
+		t.Fatalf("expect 200 for the first request but got %d", code)
+	}
+	if code := send(captured, data); code != http.StatusUnauthorized {
+		t.Errorf("expect 401 for a replayed request but got %d", code)
+	}
+
+	// 时间戳和nonce都在签名之内, 修改后签名不正确
+	forged := *captured
+	forged.Nonce = "00112233445566778899aabbccddeeff"
+	if code := send(&forged, data); code != http.StatusUnauthorized {
+		t.Errorf("expect 401 for a forged nonce but got %d", code)
+	}
+
+	// 超出时间窗口的请求即使签名正确也会被拒绝
+	stale := &Credentials{
+		PublicKey: publicKey,
+		Timestamp: time.Now().Add(-2 * DefaultMaxClockSkew).Unix(),
+		Nonce:     "ffeeddccbbaa99887766554433221100",
+	}
+	signed, _ := Sign(privateKey, WriteMessage(stale.Timestamp, stale.Nonce, data))
+	stale.Signature = hex.EncodeToString(signed)
+	if code := send(stale, data); code != http.StatusUnauthorized {
+		t.Errorf("expect 401 for a stale request but got %d", code)
+	}
+	if err = s.Authorizer.Check(*stale, data); !errors.Is(err, ErrStaleRequest) {
+		t.Errorf("expect ErrStaleRequest but got %v", err)
+	}
+	if err = s.Authorizer.Check(*captured, data); !errors.Is(err, ErrReplayedRequest) {
+		t.Errorf("expect ErrReplayedRequest but got %v", err)
+	}
+}
+
+func TestAdminAuth(t *testing.T) {
+	privateKey, _, publicKey, _, _ := wallet.GenerateKeys()
+	otherKey, _, _, _, _ := wallet.GenerateKeys()
+	s := &Server{
+		Blockchain: newTestBlockchain(t, 0),
+		Authorizer: NewAuthorizer([]string{publicKey}),
+	}
+	handler := s.configRouter()
+
+	const uri = "/admin/snapshot?name=a"
+	snapshot := func(c *Credentials, state *tls.ConnectionState) int {
+		req := httptest.NewRequest(http.MethodPost, uri, nil)
+		if c != nil {
+			setCredentials(req, c)
+		}
+		req.TLS = state
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w.Code
+	}
+	sign := func(privateKey, uri string) *Credentials {
+		c, err := SignCredentials(0, privateKey, AdminMessage(http.MethodPost, uri))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+
+	if code := snapshot(nil, nil); code != http.StatusUnauthorized {
+		t.Errorf("expect 401 without signature but got %d", code)
+	}
+	if code := snapshot(sign(privateKey, "/admin/snapshot?name=b"), nil); code != http.StatusUnauthorized {
+		t.Errorf("expect 401 for a signature of another request but got %d", code)
+	}
+	if code := snapshot(sign(otherKey, uri), nil); code != http.StatusForbidden {
+		t.Errorf("expect 403 for an unknown key but got %d", code)
+	}
+	// 通过认证后才检查是否开启了快照
+	signed := sign(privateKey, uri)
+	if code := snapshot(signed, nil); code != http.StatusNotImplemented {
+		t.Errorf("expect 501 for an authorized key but got %d", code)
+	}
+	if code := snapshot(signed, nil); code != http.StatusUnauthorized {
+		t.Errorf("expect 401 for a replayed request but got %d", code)
+	}
+
+	// 校验过的客户端证书不需要签名
+	cert := &x509.Certificate{Raw: []byte("client")}
+	verified := &tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}, VerifiedChains: [][]*x509.Certificate{{cert}}}
+	if code := snapshot(nil, verified); code != http.StatusNotImplemented {
+		t.Errorf("expect 501 for a verified client certificate but got %d", code)
+	}
+	unverified := &tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}}
+	if code := snapshot(nil, unverified); code != http.StatusUnauthorized {
+		t.Errorf("expect 401 for an unverified client certificate but got %d", code)
+	}
+
+	// 没有Authorizer时只接受客户端证书
+	s.Authorizer = nil
+	if code := snapshot(sign(privateKey, uri), nil); code != http.StatusUnauthorized {
+		t.Errorf("expect 401 without an authorizer but got %d", code)
+	}
+}
+
+func TestLoadAuthorizer(t *testing.T) {
+	_, _, publicKey, address, _ := wallet.GenerateKeys()
+	file := filepath.Join(t.TempDir(), "authorized")
+
+	content := "# writers\n" + publicKey + "\n" + address + "\n"
+	if err := ioutil.WriteFile(file, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	a, err := LoadAuthorizer(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !a.Authorized(publicKey) {
+		t.Error("expect the public key to be authorized")
+	}
+
+	// 最后一个字符被修改, 校验和不正确
+	last := "a"
+	if address[len(address)-1] == 'a' {
+		last = "b"
+	}
+	bad := address[:len(address)-1] + last
+	if err = ioutil.WriteFile(file, []byte(bad+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = LoadAuthorizer(file); err == nil {
+		t.Error("expect an error for an address with a bad checksum")
+	}
+}
+
+func TestAuthorizedKeyForms(t *testing.T) {
+	privateKey, _, publicKey, _, _ := wallet.GenerateKeys()
+	compressed, _, _ := wallet.GetCompressedPublicKey(privateKey)
+	_, _, otherKey, _, _ := wallet.GenerateKeys()
+
+	for _, entry := range []string{publicKey, strings.ToUpper(publicKey), compressed, strings.ToUpper(compressed)} {
+		a := NewAuthorizer([]string{entry})
+		for _, key := range []string{publicKey, compressed, strings.ToUpper(compressed)} {
+			if !a.Authorized(key) {
+				t.Errorf("%s should be authorized by %s", key, entry)
+			}
+		}
+		if a.Authorized(otherKey) {
+			t.Errorf("%s should not be authorized by %s", otherKey, entry)
+		}
+	}
+}
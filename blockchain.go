@@ -4,9 +4,14 @@ import (
 	"crypto/sha256"
 	"encoding/binary"
 	"encoding/hex"
+	"fmt"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
+
+	"github.com/smallnest/blockchain/chaincfg"
+	"github.com/smallnest/log"
 )
 
 // Block 代表区块链中的一块.
@@ -25,6 +30,10 @@ type Block struct {
 	Nonce uint32 `json:"nonce"`
 	// 本区块中的数据
 	Data []byte `json:"data,omitempty"`
+	// 出块节点的十六进制公钥, 没有签名的区块为空
+	PublicKey string `json:"public_key,omitempty"`
+	// 出块节点对Hash的签名, 以签名算法的标记开头, 参见SignTagged. 不在哈希之内
+	Signature []byte `json:"signature,omitempty"`
 }
 
 // Blockchain 是一条完整的区块链
@@ -34,10 +43,56 @@ type Blockchain struct {
 	Store      Store
 	Difficulty uint32
 	PrefixZero string
+	// 网络参数, 为nil时使用主网的参数
+	Params *chaincfg.NetParams
+	// 只保留最近PruneDepth个区块的数据, 为0则不裁剪. Store需要实现Pruner
+	PruneDepth uint64
+	// SigType不为0时使用PrivateKey对挖出的区块签名
+	SigType    SigType
+	PrivateKey string
+
+	prunedHeight uint64
+	hashIndex    map[string]uint64
+
+	subMu       sync.Mutex
+	subscribers map[chan *Block]uint64 // 订阅者和订阅的起始高度
+
+	stopped int32
 }
 
-// LoadFromStore 从文件中加载blockchain.
+// stopCheckInterval 挖矿时每尝试这么多个随机数检查一次是否需要停止.
+const stopCheckInterval = 1024
+
+// subscriberBuffer 是订阅者的缓冲区大小, 缓冲区满了的订阅者会被断开.
+const subscriberBuffer = 64
+
+// NewBlockchain 使用网络参数中的初始难度创建一条区块链.
+func NewBlockchain(store Store, params *chaincfg.NetParams) *Blockchain {
+	return &Blockchain{
+		Store:      store,
+		Difficulty: params.Difficulty,
+		PrefixZero: strings.Repeat("0", int(params.Difficulty)),
+		Params:     params,
+	}
+}
+
+func (bc *Blockchain) params() *chaincfg.NetParams {
+	if bc.Params == nil {
+		return &chaincfg.MainNet
+	}
+	return bc.Params
+}
+
+// LoadFromStore 从文件中加载blockchain, 创世块不属于当前网络时返回ErrNetworkMismatch.
 func (bc *Blockchain) LoadFromStore() error {
+	if pruner, ok := bc.Store.(Pruner); ok {
+		prunedHeight, err := pruner.PrunedHeight()
+		if err != nil {
+			return err
+		}
+		bc.prunedHeight = prunedHeight
+	}
+
 	var i uint64
 	for {
 		block, err := bc.Store.Get(i)
@@ -47,33 +102,289 @@ func (bc *Blockchain) LoadFromStore() error {
 			}
 			return err
 		}
-		bc.AddBlock(block)
+		if i == 0 {
+			if err = bc.checkGenesis(block); err != nil {
+				return err
+			}
+		}
+		// 重放难度调整, 重启之后的难度和挖矿时一致
+		bc.retarget(block.Height)
+		bc.appendBlock(block)
 		i++
 	}
 }
 
-// GenerateGenesisBlock 初始化创世块.
-func (bc *Blockchain) GenerateGenesisBlock() {
+// GenerateGenesisBlock 按照网络参数初始化创世块.
+func (bc *Blockchain) GenerateGenesisBlock() error {
+	genesis := bc.params().Genesis
+	timestamp := genesis.Timestamp
+	if timestamp == 0 {
+		timestamp = time.Now().Unix()
+	}
 	genesisBlock := &Block{
 		Height:    0,
-		Timestamp: time.Now().Unix(),
-		Hash:      hash(&Block{}),
+		Timestamp: timestamp,
+		Hash:      genesisHash(genesis),
 		PrevHash:  "",
-		Data:      []byte{},
+		Data:      append([]byte{}, genesis.Data...),
 	}
 
 	bc.Lock()
-	bc.AddBlock(genesisBlock)
-	bc.Unlock()
+	defer bc.Unlock()
+	return bc.AddBlock(genesisBlock)
+}
+
+// genesisHash 是创世块的哈希, 只和创世块的数据有关.
+func genesisHash(genesis chaincfg.Genesis) string {
+	return hash(&Block{Data: genesis.Data})
+}
+
+// checkGenesis 校验创世块是否属于当前的网络.
+func (bc *Blockchain) checkGenesis(block *Block) error {
+	params := bc.params()
+	if block.Hash != genesisHash(params.Genesis) ||
+		(params.Genesis.Timestamp != 0 && block.Timestamp != params.Genesis.Timestamp) {
+		return fmt.Errorf("%w: not a %s genesis block", ErrNetworkMismatch, params.Name)
+	}
+	return nil
 }
 
 // AddBlock 在区块链上增加一个区块.
-func (bc *Blockchain) AddBlock(block *Block) {
+func (bc *Blockchain) AddBlock(block *Block) error {
+	if err := bc.Store.Add(block.Height, block); err != nil {
+		return err
+	}
+	if len(bc.Blocks) > 0 {
+		observeInterval(block, bc.Blocks[len(bc.Blocks)-1])
+	}
+	bc.appendBlock(block)
+	bc.publish(block)
+
+	// 区块已经写入, 裁剪失败不影响这次写入, 下次加入区块时会重试
+	if err := bc.prune(); err != nil {
+		log.Errorf("failed to prune blocks: %v", err)
+	}
+	return nil
+}
+
+func (bc *Blockchain) appendBlock(block *Block) {
+	if bc.hashIndex == nil {
+		bc.hashIndex = make(map[string]uint64)
+	}
+	bc.hashIndex[block.Hash] = block.Height
 	bc.Blocks = append(bc.Blocks, block)
-	bc.Store.Add(block.Height, block)
+	bc.observeBlock(block)
+}
+
+// Stop 停止挖矿, 正在进行和之后的MineBlock都会返回ErrStopped.
+func (bc *Blockchain) Stop() {
+	atomic.StoreInt32(&bc.stopped, 1)
+}
+
+// Stopped 判断是否已经停止挖矿.
+func (bc *Blockchain) Stopped() bool {
+	return atomic.LoadInt32(&bc.stopped) == 1
+}
+
+// MineBlock 为数据data挖出一个新的区块并加入区块链.
+func (bc *Blockchain) MineBlock(data []byte) (*Block, error) {
+	if bc.Stopped() {
+		return nil, ErrStopped
+	}
+
+	bc.Lock()
+	defer bc.Unlock()
+
+	prevBlock := bc.Blocks[len(bc.Blocks)-1]
+	newBlock := bc.generateBlock(prevBlock, data)
+	if newBlock == nil {
+		return nil, ErrStopped
+	}
+	if bc.SigType != 0 {
+		if err := newBlock.Sign(bc.SigType, bc.PrivateKey); err != nil {
+			return nil, err
+		}
+	}
+
+	if !validateBlock(newBlock, prevBlock) {
+		return nil, ErrInvalidBlock
+	}
+	if err := bc.AddBlock(newBlock); err != nil {
+		return nil, err
+	}
+	return newBlock, nil
+}
+
+// Subscribe 订阅新增的区块.
+// 返回从高度from开始已经存在的区块, 以及一个接收后续新区块的channel, channel只推送高度不小于from的区块.
+// 数据已经被裁剪的区块不会推送, from小于裁剪高度时backlog从裁剪高度开始.
+// 如果订阅者处理不及时, channel会被关闭, 订阅者可以从断开的高度重新订阅.
+// 不再需要时必须调用cancel.
+func (bc *Blockchain) Subscribe(from uint64) (backlog []*Block, ch <-chan *Block, cancel func()) {
+	c := make(chan *Block, subscriberBuffer)
+
+	bc.RLock()
+	start := from
+	if start < bc.prunedHeight {
+		start = bc.prunedHeight
+	}
+	if start < uint64(len(bc.Blocks)) {
+		backlog = make([]*Block, len(bc.Blocks)-int(start))
+		copy(backlog, bc.Blocks[start:])
+	}
+	bc.subMu.Lock()
+	if bc.subscribers == nil {
+		bc.subscribers = make(map[chan *Block]uint64)
+	}
+	bc.subscribers[c] = from
+	bc.subMu.Unlock()
+	bc.RUnlock()
+
+	cancel = func() {
+		bc.subMu.Lock()
+		if _, ok := bc.subscribers[c]; ok {
+			delete(bc.subscribers, c)
+			close(c)
+		}
+		bc.subMu.Unlock()
+	}
+	return backlog, c, cancel
 }
 
-// generateBlock 为数据Data创建一个新的区块
+// publish 把新区块发送给所有的订阅者.
+func (bc *Blockchain) publish(block *Block) {
+	bc.subMu.Lock()
+	defer bc.subMu.Unlock()
+
+	for c, from := range bc.subscribers {
+		if block.Height < from {
+			continue
+		}
+		select {
+		case c <- block:
+		default:
+			delete(bc.subscribers, c)
+			close(c)
+		}
+	}
+}
+
+// prune 丢弃超过PruneDepth的旧区块的数据.
+func (bc *Blockchain) prune() error {
+	if bc.PruneDepth == 0 || uint64(len(bc.Blocks)) <= bc.PruneDepth {
+		return nil
+	}
+
+	height := uint64(len(bc.Blocks)) - bc.PruneDepth
+	if height <= bc.prunedHeight {
+		return nil
+	}
+
+	pruner, ok := bc.Store.(Pruner)
+	if !ok {
+		return ErrPruneUnsupported
+	}
+	if err := pruner.Prune(height); err != nil {
+		return err
+	}
+
+	// 其它goroutine可能还持有原来的区块, 替换为没有数据的副本而不是修改它
+	for i := bc.prunedHeight; i < height; i++ {
+		pruned := *bc.Blocks[i]
+		pruned.Data = nil
+		bc.Blocks[i] = &pruned
+	}
+	bc.prunedHeight = height
+	return nil
+}
+
+// GetBlock 返回指定高度的区块.
+// 如果区块的数据已经被裁剪, 返回ErrPruned.
+func (bc *Blockchain) GetBlock(height uint64) (*Block, error) {
+	bc.RLock()
+	defer bc.RUnlock()
+
+	if height >= uint64(len(bc.Blocks)) {
+		return nil, ErrNotFound
+	}
+	if height < bc.prunedHeight {
+		return nil, ErrPruned
+	}
+	return bc.Blocks[height], nil
+}
+
+// GetBlockByHash 返回指定哈希的区块.
+// 如果区块的数据已经被裁剪, 返回ErrPruned.
+func (bc *Blockchain) GetBlockByHash(hash string) (*Block, error) {
+	bc.RLock()
+	height, ok := bc.hashIndex[hash]
+	bc.RUnlock()
+
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return bc.GetBlock(height)
+}
+
+// Tip 返回区块链中最新的区块.
+func (bc *Blockchain) Tip() (*Block, error) {
+	bc.RLock()
+	defer bc.RUnlock()
+
+	if len(bc.Blocks) == 0 {
+		return nil, ErrNotFound
+	}
+	return bc.Blocks[len(bc.Blocks)-1], nil
+}
+
+// IsPruned 判断指定高度的区块数据是否已经被裁剪.
+func (bc *Blockchain) IsPruned(height uint64) bool {
+	bc.RLock()
+	defer bc.RUnlock()
+	return height < bc.prunedHeight
+}
+
+// VerifyChain 校验整条区块链的连接关系和区块的签名.
+// 被裁剪的区块只校验高度和哈希的连接, 未被裁剪的区块还会重新计算哈希.
+// Schnorr签名批量校验, 批量校验失败时再逐个校验以找到无效的区块.
+func (bc *Blockchain) VerifyChain() error {
+	bc.RLock()
+	defer bc.RUnlock()
+
+	var schnorrSigned []*Block
+	for i := 1; i < len(bc.Blocks); i++ {
+		block, prevBlock := bc.Blocks[i], bc.Blocks[i-1]
+		if prevBlock.Height+1 != block.Height || prevBlock.Hash != block.PrevHash {
+			return fmt.Errorf("%w: block %d does not follow block %d", ErrInvalidBlock, block.Height, prevBlock.Height)
+		}
+		if uint64(i) >= bc.prunedHeight && hash(block) != block.Hash {
+			return fmt.Errorf("%w: hash mismatch of block %d", ErrInvalidBlock, block.Height)
+		}
+		switch {
+		case !block.Signed():
+		case block.SigType() == SigSchnorr:
+			schnorrSigned = append(schnorrSigned, block)
+		case !block.VerifySignature():
+			return fmt.Errorf("%w: invalid signature of block %d", ErrInvalidBlock, block.Height)
+		}
+	}
+
+	batch := make([]SignedData, len(schnorrSigned))
+	for i, block := range schnorrSigned {
+		batch[i] = SignedData{PublicKey: block.PublicKey, Signature: block.Signature[1:], Data: []byte(block.Hash)}
+	}
+	if BatchVerifySchnorr(batch) {
+		return nil
+	}
+	for _, block := range schnorrSigned {
+		if !block.VerifySignature() {
+			return fmt.Errorf("%w: invalid signature of block %d", ErrInvalidBlock, block.Height)
+		}
+	}
+	return nil
+}
+
+// generateBlock 为数据Data创建一个新的区块, 停止挖矿时返回nil.
 func (bc *Blockchain) generateBlock(prevBlock *Block, data []byte) *Block {
 	var newBlock = &Block{}
 	newBlock.Height = prevBlock.Height + 1
@@ -84,20 +395,22 @@ func (bc *Blockchain) generateBlock(prevBlock *Block, data []byte) *Block {
 
 	newBlock.Difficulty = bc.Difficulty
 
+	start := time.Now()
 	for i := uint32(0); ; i++ {
+		if i%stopCheckInterval == 0 && bc.Stopped() {
+			return nil
+		}
 		newBlock.Nonce = i
 		if !validateHash(hash(newBlock), bc.PrefixZero) {
 			continue
 		} else {
 			newBlock.Hash = hash(newBlock)
+			observeHashes(uint64(i)+1, time.Since(start))
 			break
 		}
 	}
 
-	if newBlock.Height > 1 && newBlock.Height%3600 == 0 {
-		bc.adjustDifficulty()
-	}
-
+	bc.retarget(newBlock.Height)
 	return newBlock
 }
 
@@ -127,6 +440,10 @@ func validateBlock(newBlock, prevBlock *Block) bool {
 		return false
 	}
 
+	if newBlock.Signed() && !newBlock.VerifySignature() {
+		return false
+	}
+
 	return true
 }
 
@@ -134,16 +451,30 @@ func validateHash(hash string, prefixZero string) bool {
 	return strings.HasPrefix(hash, prefixZero)
 }
 
+// retarget 在产生高度为height的区块之后调整难度, 此时这个区块还没有加入区块链.
+func (bc *Blockchain) retarget(height uint64) {
+	if interval := bc.params().RetargetInterval; interval > 0 && height > 1 && height%interval == 0 {
+		bc.adjustDifficulty()
+	}
+}
+
+// adjustDifficulty 根据最近RetargetInterval个区块的平均出块时间调整难度.
 func (bc *Blockchain) adjustDifficulty() {
+	params := bc.params()
+	interval := int(params.RetargetInterval)
 	last := len(bc.Blocks) - 1
+	if last < interval {
+		return
+	}
 
-	tookMs := (bc.Blocks[last].Timestamp - bc.Blocks[last-3600].Timestamp) / 1e6
-	tookMs = tookMs / 3600
-	if tookMs > 2000 {
-		bc.Difficulty--
-		bc.PrefixZero = strings.Repeat("0", int(bc.Difficulty))
-	} else if tookMs < 500 {
-		bc.Difficulty++
-		bc.PrefixZero = strings.Repeat("0", int(bc.Difficulty))
+	// 区块的时间戳以秒为单位
+	took := time.Duration(bc.Blocks[last].Timestamp-bc.Blocks[last-interval].Timestamp) * time.Second / time.Duration(interval)
+	difficulty := bc.Difficulty
+	if took > params.SlowBlockTime && difficulty > 0 {
+		difficulty--
+	} else if took < params.FastBlockTime {
+		difficulty++
 	}
+	bc.Difficulty = params.ClampDifficulty(difficulty)
+	bc.PrefixZero = strings.Repeat("0", int(bc.Difficulty))
 }
@@ -0,0 +1,246 @@
+package blockchain
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/smallnest/blockchain/chaincfg"
+	"github.com/smallnest/blockchain/wallet"
+)
+
+func TestPrune(t *testing.T) {
+	bc := newTestBlockchain(t, 0)
+	bc.PruneDepth = 3
+
+	var held *Block
+	for i := 0; i < 10; i++ {
+		prevBlock := bc.Blocks[len(bc.Blocks)-1]
+		if err := bc.AddBlock(bc.generateBlock(prevBlock, []byte("data"))); err != nil {
+			t.Fatal(err)
+		}
+		if i == 0 {
+			held, _ = bc.GetBlock(1)
+		}
+	}
+
+	// 裁剪不修改其它地方还持有的区块
+	if string(held.Data) != "data" || bc.Blocks[1].Data != nil {
+		t.Fatal("pruned block should be replaced by a copy without data")
+	}
+
+	if _, err := bc.GetBlock(7); !errors.Is(err, ErrPruned) {
+		t.Fatalf("expect ErrPruned but got %v", err)
+	}
+	block, err := bc.GetBlock(8)
+	if err != nil || string(block.Data) != "data" {
+		t.Fatalf("expect block 8 with data but got %v", err)
+	}
+	if _, err := bc.Store.Get(7); err != nil {
+		t.Fatalf("header of pruned block should be kept: %v", err)
+	}
+
+	if err := bc.VerifyChain(); err != nil {
+		t.Fatal(err)
+	}
+	bc.Blocks[5].PrevHash = "broken"
+	if err := bc.VerifyChain(); !errors.Is(err, ErrInvalidBlock) {
+		t.Fatalf("expect ErrInvalidBlock but got %v", err)
+	}
+}
+
+func TestSubscribe(t *testing.T) {
+	bc := newTestBlockchain(t, 0)
+	bc.PruneDepth = 3
+	for i := 0; i < 5; i++ {
+		if err := bc.AddBlock(bc.generateBlock(bc.Blocks[len(bc.Blocks)-1], []byte("data"))); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 数据已经被裁剪的区块不推送
+	backlog, _, cancel := bc.Subscribe(0)
+	cancel()
+	if len(backlog) != 3 || backlog[0].Height != 3 {
+		t.Fatalf("backlog should start at the pruned height but got %d blocks", len(backlog))
+	}
+	for _, block := range backlog {
+		if string(block.Data) != "data" {
+			t.Fatalf("block %d has no data", block.Height)
+		}
+	}
+
+	// 起始高度之前的新区块不推送
+	backlog, blocks, cancel := bc.Subscribe(8)
+	defer cancel()
+	if len(backlog) != 0 {
+		t.Fatalf("unexpected backlog of %d blocks", len(backlog))
+	}
+	for i := 0; i < 3; i++ {
+		if err := bc.AddBlock(bc.generateBlock(bc.Blocks[len(bc.Blocks)-1], []byte("data"))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if block := <-blocks; block.Height != 8 {
+		t.Fatalf("expect block 8 but got %d", block.Height)
+	}
+	select {
+	case block := <-blocks:
+		t.Fatalf("unexpected block %d", block.Height)
+	default:
+	}
+}
+
+// 裁剪失败时区块已经写入, AddBlock不返回错误.
+func TestPruneUnsupported(t *testing.T) {
+	bc := &Blockchain{Store: unprunableStore{newMemStore()}, PruneDepth: 1}
+	if err := bc.GenerateGenesisBlock(); err != nil {
+		t.Fatal(err)
+	}
+	if err := bc.AddBlock(bc.generateBlock(bc.Blocks[0], []byte("data"))); err != nil {
+		t.Fatalf("expect the block to be added but got %v", err)
+	}
+	if len(bc.Blocks) != 2 || bc.IsPruned(0) {
+		t.Fatal("block should be added without pruning")
+	}
+}
+
+func TestSignedBlocks(t *testing.T) {
+	privateKey, _, _, _, _ := wallet.GenerateKeys()
+	bc := newTestBlockchain(t, 1)
+	bc.PrivateKey = privateKey
+	for _, sigType := range []SigType{SigECDSA, SigSchnorr, SigSchnorr} {
+		bc.SigType = sigType
+		block, err := bc.MineBlock([]byte(sigType.String()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if block.SigType() != sigType || !block.VerifySignature() {
+			t.Fatalf("%s: block should carry a valid signature", sigType)
+		}
+
+		// 签名随区块一起编码
+		data, err := EncodeBlock(block)
+		if err != nil {
+			t.Fatal(err)
+		}
+		decoded, err := DecodeBlock(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if decoded.PublicKey != block.PublicKey || !bytes.Equal(decoded.Signature, block.Signature) {
+			t.Fatalf("%s: signature is lost after encoding", sigType)
+		}
+		if _, err = DecodeBlock(data[:len(data)-1]); !errors.Is(err, ErrMalformedBlock) {
+			t.Errorf("%s: expect ErrMalformedBlock but got %v", sigType, err)
+		}
+	}
+	if bc.Blocks[1].Signed() || bc.Blocks[1].VerifySignature() {
+		t.Error("block mined without SigType should be unsigned")
+	}
+	if err := bc.VerifyChain(); err != nil {
+		t.Fatal(err)
+	}
+
+	// 导出和导入保留签名
+	for _, format := range []string{FormatBinary, FormatJSON} {
+		var buf bytes.Buffer
+		if _, err := ExportBlocks(&buf, bc.Store, format, nil); err != nil {
+			t.Fatal(err)
+		}
+		dst := &Blockchain{Store: newMemStore(), Difficulty: 1, PrefixZero: "0"}
+		if _, err := dst.ImportBlocks(&buf, format, nil); err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		if dst.Blocks[4].SigType() != SigSchnorr || !dst.Blocks[4].VerifySignature() {
+			t.Errorf("%s: signature is lost after import", format)
+		}
+	}
+
+	_, _, otherKey, _, _ := wallet.GenerateKeys()
+	for _, height := range []int{2, 3, 4} {
+		block := *bc.Blocks[height]
+		bc.Blocks[height] = &block
+		block.PublicKey = otherKey
+		if err := bc.VerifyChain(); !errors.Is(err, ErrInvalidBlock) {
+			t.Errorf("block %d: expect ErrInvalidBlock for a forged signature but got %v", height, err)
+		}
+		if validateBlock(&block, bc.Blocks[height-1]) {
+			t.Errorf("block %d: block with a forged signature should be invalid", height)
+		}
+		block.PublicKey = ""
+		block.Signature = nil
+		if err := bc.VerifyChain(); err != nil {
+			t.Errorf("block %d: unsigned block should be valid: %v", height, err)
+		}
+	}
+
+	bc.SigType, bc.PrivateKey = SigSchnorr, "00"
+	if _, err := bc.MineBlock([]byte("data")); err == nil {
+		t.Error("expect error for an invalid private key")
+	}
+}
+
+// unprunableStore 隐藏了memStore的Prune方法.
+type unprunableStore struct {
+	Store
+}
+
+func TestNetworkGenesis(t *testing.T) {
+	bc := NewBlockchain(newMemStore(), &chaincfg.RegTest)
+	if err := bc.GenerateGenesisBlock(); err != nil {
+		t.Fatal(err)
+	}
+	other := NewBlockchain(newMemStore(), &chaincfg.RegTest)
+	if err := other.GenerateGenesisBlock(); err != nil {
+		t.Fatal(err)
+	}
+	if bc.Blocks[0].Hash != other.Blocks[0].Hash || bc.Blocks[0].Timestamp != chaincfg.RegTest.Genesis.Timestamp {
+		t.Fatal("regtest genesis blocks should be identical")
+	}
+
+	loaded := NewBlockchain(bc.Store, &chaincfg.RegTest)
+	if err := loaded.LoadFromStore(); err != nil {
+		t.Fatal(err)
+	}
+	for _, params := range []*chaincfg.NetParams{&chaincfg.MainNet, &chaincfg.TestNet} {
+		loaded = NewBlockchain(bc.Store, params)
+		if err := loaded.LoadFromStore(); !errors.Is(err, ErrNetworkMismatch) {
+			t.Errorf("%s: expect ErrNetworkMismatch but got %v", params.Name, err)
+		}
+	}
+}
+
+func TestAdjustDifficulty(t *testing.T) {
+	params := chaincfg.MainNet
+	params.RetargetInterval = 2
+	params.MaxDifficulty = 3
+
+	bc := &Blockchain{Difficulty: 2, Params: &params}
+	add := func(timestamps ...int64) {
+		for _, ts := range timestamps {
+			bc.Blocks = append(bc.Blocks, &Block{Height: uint64(len(bc.Blocks)), Timestamp: ts})
+		}
+	}
+
+	// 平均10秒一个区块, 降低难度
+	add(0, 10, 20)
+	bc.adjustDifficulty()
+	if bc.Difficulty != 1 || bc.PrefixZero != "0" {
+		t.Fatalf("expect difficulty 1 but got %d", bc.Difficulty)
+	}
+	// 不能低于MinDifficulty
+	bc.adjustDifficulty()
+	if bc.Difficulty != 1 {
+		t.Fatalf("expect difficulty 1 but got %d", bc.Difficulty)
+	}
+
+	// 同一秒内产生的区块, 增加难度, 不能超过MaxDifficulty
+	add(20, 20)
+	for i := 0; i < 5; i++ {
+		bc.adjustDifficulty()
+	}
+	if bc.Difficulty != 3 || bc.PrefixZero != "000" {
+		t.Fatalf("expect difficulty 3 but got %d", bc.Difficulty)
+	}
+}
@@ -0,0 +1,160 @@
+// Package chaincfg 定义区块链网络的参数, 包括地址的版本字节、创世块、默认端口和难度规则.
+package chaincfg
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// ErrUnknownNetwork 没有这个名字的网络.
+var ErrUnknownNetwork = errors.New("unknown network")
+
+// Genesis 描述创世块.
+type Genesis struct {
+	// 创世块的时间戳, 为0时使用创建创世块的时间
+	Timestamp int64
+	// 创世块中的数据
+	Data []byte
+}
+
+// NetParams 是一个网络的参数, 不同网络的地址和私钥不能混用.
+type NetParams struct {
+	// 网络的名字, 用于-network参数
+	Name string
+
+	// P2PKH地址的版本字节
+	PubKeyHashAddrID byte
+	// P2SH地址的版本字节
+	ScriptHashAddrID byte
+	// WIF私钥的版本字节
+	PrivateKeyID byte
+	// 隔离见证地址的human-readable部分
+	Bech32HRP string
+	// BIP-32扩展私钥和扩展公钥的版本字节
+	HDPrivateKeyID [4]byte
+	HDPublicKeyID  [4]byte
+	// BIP-44路径中的币种
+	HDCoinType uint32
+
+	Genesis Genesis
+	// http rpc服务的默认端口
+	DefaultPort string
+
+	// 初始的难度, 即区块哈希开头0的个数
+	Difficulty    uint32
+	MinDifficulty uint32
+	MaxDifficulty uint32
+	// 每隔RetargetInterval个区块调整一次难度, 为0则不调整
+	RetargetInterval uint64
+	// 平均出块时间小于FastBlockTime时增加难度, 大于SlowBlockTime时降低难度
+	FastBlockTime time.Duration
+	SlowBlockTime time.Duration
+}
+
+// MainNet 是主网的参数.
+var MainNet = NetParams{
+	Name:             "mainnet",
+	PubKeyHashAddrID: 0x00,
+	ScriptHashAddrID: 0x05,
+	PrivateKeyID:     0x80,
+	Bech32HRP:        "bc",
+	HDPrivateKeyID:   [4]byte{0x04, 0x88, 0xad, 0xe4}, // xprv
+	HDPublicKeyID:    [4]byte{0x04, 0x88, 0xb2, 0x1e}, // xpub
+	HDCoinType:       0,
+
+	// 主网的创世块在第一次启动时生成, 兼容已有的数据
+	Genesis:     Genesis{},
+	DefaultPort: "8972",
+
+	Difficulty:       5,
+	MinDifficulty:    1,
+	MaxDifficulty:    64,
+	RetargetInterval: 3600,
+	FastBlockTime:    500 * time.Millisecond,
+	SlowBlockTime:    2 * time.Second,
+}
+
+// TestNet 是测试网的参数, 难度较低.
+var TestNet = NetParams{
+	Name:             "testnet",
+	PubKeyHashAddrID: 0x6f,
+	ScriptHashAddrID: 0xc4,
+	PrivateKeyID:     0xef,
+	Bech32HRP:        "tb",
+	HDPrivateKeyID:   [4]byte{0x04, 0x35, 0x83, 0x94}, // tprv
+	HDPublicKeyID:    [4]byte{0x04, 0x35, 0x87, 0xcf}, // tpub
+	HDCoinType:       1,
+
+	Genesis: Genesis{
+		Timestamp: 1577836800,
+		Data:      []byte("blockchain testnet"),
+	},
+	DefaultPort: "18972",
+
+	Difficulty:       3,
+	MinDifficulty:    1,
+	MaxDifficulty:    64,
+	RetargetInterval: 3600,
+	FastBlockTime:    500 * time.Millisecond,
+	SlowBlockTime:    2 * time.Second,
+}
+
+// RegTest 是本地回归测试网络的参数, 难度固定为1, 可以很快地产生区块.
+var RegTest = NetParams{
+	Name:             "regtest",
+	PubKeyHashAddrID: 0x6f,
+	ScriptHashAddrID: 0xc4,
+	PrivateKeyID:     0xef,
+	Bech32HRP:        "bcrt",
+	HDPrivateKeyID:   [4]byte{0x04, 0x35, 0x83, 0x94}, // tprv
+	HDPublicKeyID:    [4]byte{0x04, 0x35, 0x87, 0xcf}, // tpub
+	HDCoinType:       1,
+
+	Genesis: Genesis{
+		Timestamp: 1577836800,
+		Data:      []byte("blockchain regtest"),
+	},
+	DefaultPort: "28972",
+
+	Difficulty:    1,
+	MinDifficulty: 1,
+	MaxDifficulty: 1,
+}
+
+var networks = map[string]*NetParams{
+	MainNet.Name: &MainNet,
+	TestNet.Name: &TestNet,
+	RegTest.Name: &RegTest,
+}
+
+// Lookup 根据名字查找内置的网络参数.
+func Lookup(name string) (*NetParams, error) {
+	params, ok := networks[name]
+	if !ok {
+		return nil, fmt.Errorf("%w %q, valid networks are %v", ErrUnknownNetwork, name, Names())
+	}
+	return params, nil
+}
+
+// Names 返回内置的网络的名字.
+func Names() []string {
+	names := make([]string, 0, len(networks))
+	for name := range networks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ClampDifficulty 把难度限制在[MinDifficulty, MaxDifficulty]之内.
+func (p *NetParams) ClampDifficulty(difficulty uint32) uint32 {
+	if difficulty < p.MinDifficulty {
+		return p.MinDifficulty
+	}
+	if p.MaxDifficulty > 0 && difficulty > p.MaxDifficulty {
+		return p.MaxDifficulty
+	}
+	return difficulty
+}
@@ -0,0 +1,35 @@
+package chaincfg
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestLookup(t *testing.T) {
+	for _, name := range []string{"mainnet", "testnet", "regtest"} {
+		params, err := Lookup(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if params.Name != name {
+			t.Errorf("Lookup(%s) returns %s", name, params.Name)
+		}
+		if params.Difficulty < params.MinDifficulty || params.Difficulty > params.MaxDifficulty {
+			t.Errorf("%s: initial difficulty %d is out of range", name, params.Difficulty)
+		}
+	}
+
+	if _, err := Lookup("simnet"); !errors.Is(err, ErrUnknownNetwork) {
+		t.Errorf("expect ErrUnknownNetwork, got %v", err)
+	}
+}
+
+func TestClampDifficulty(t *testing.T) {
+	params := NetParams{MinDifficulty: 1, MaxDifficulty: 8}
+	tests := map[uint32]uint32{0: 1, 1: 1, 5: 5, 8: 8, 9: 8}
+	for in, want := range tests {
+		if got := params.ClampDifficulty(in); got != want {
+			t.Errorf("ClampDifficulty(%d) = %d, want %d", in, got, want)
+		}
+	}
+}
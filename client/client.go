@@ -0,0 +1,358 @@
+// Package client 是区块链http rpc服务的Go客户端.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/smallnest/blockchain"
+)
+
+// Error 是服务器返回的http错误.
+type Error struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%d %s: %s", e.StatusCode, http.StatusText(e.StatusCode), e.Message)
+}
+
+// Unwrap 把状态码映射为blockchain包中的错误, 可以使用errors.Is判断.
+func (e *Error) Unwrap() error {
+	switch e.StatusCode {
+	case http.StatusNotFound:
+		return blockchain.ErrNotFound
+	case http.StatusGone:
+		return blockchain.ErrPruned
+	default:
+		return nil
+	}
+}
+
+// SnapshotResult 是生成快照的结果.
+type SnapshotResult struct {
+	Path   string `json:"path"`
+	Height int    `json:"height"`
+}
+
+var (
+	jsonHeader   = http.Header{"Content-Type": {"application/json"}}
+	binaryHeader = http.Header{"Content-Type": {"application/octet-stream"}}
+)
+
+// Client 访问区块链的http rpc服务.
+// 幂等的请求在网络错误或者服务器暂时不可用时会按照指数退避重试.
+type Client struct {
+	// 服务器地址, 比如 http://127.0.0.1:8972
+	BaseURL    string
+	HTTPClient *http.Client
+	// 每次请求的超时时间, 不包含推送区块的长连接. 为0则不限制
+	Timeout time.Duration
+	// 最大重试次数
+	MaxRetries int
+	// 第一次重试前的等待时间, 之后每次翻倍, 最多为MaxBackoff
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	// WriteSignedBlock使用的签名算法, 为0时使用不带标记的ECDSA签名
+	SigType blockchain.SigType
+}
+
+// NewClient 创建一个使用默认配置的客户端.
+func NewClient(baseURL string) *Client {
+	return &Client{
+		BaseURL:    strings.TrimRight(baseURL, "/"),
+		HTTPClient: http.DefaultClient,
+		Timeout:    30 * time.Second,
+		MaxRetries: 3,
+		MinBackoff: 100 * time.Millisecond,
+		MaxBackoff: 5 * time.Second,
+	}
+}
+
+// GetBlocks 得到从高度start开始的所有区块, start低于裁剪的高度时返回ErrPruned.
+func (c *Client) GetBlocks(ctx context.Context, start uint64) ([]*blockchain.Block, error) {
+	query := url.Values{"start": {strconv.FormatUint(start, 10)}}
+	var blocks []*blockchain.Block
+	err := c.do(ctx, http.MethodGet, "/blocks?"+query.Encode(), nil, nil, true, &blocks)
+	return blocks, err
+}
+
+// GetBlock 得到指定高度的区块. 区块不存在时返回的错误满足errors.Is(err, blockchain.ErrNotFound),
+// 数据已经被裁剪时满足errors.Is(err, blockchain.ErrPruned).
+func (c *Client) GetBlock(ctx context.Context, height uint64) (*blockchain.Block, error) {
+	var block blockchain.Block
+	err := c.do(ctx, http.MethodGet, "/block/"+strconv.FormatUint(height, 10), nil, nil, true, &block)
+	if err != nil {
+		return nil, err
+	}
+	return &block, nil
+}
+
+// WriteBlock 把数据写入一个新的区块. 写入不是幂等的, 所以不会重试.
+func (c *Client) WriteBlock(ctx context.Context, data []byte) (*blockchain.Block, error) {
+	var block blockchain.Block
+	err := c.do(ctx, http.MethodPost, "/blocks", binaryHeader, data, false, &block)
+	if err != nil {
+		return nil, err
+	}
+	return &block, nil
+}
+
+// WriteSignedBlock 使用私钥对数据签名后写入一个新的区块, 用于要求签名的服务器.
+func (c *Client) WriteSignedBlock(ctx context.Context, data []byte, privateKey string) (*blockchain.Block, error) {
+	var block blockchain.Block
+	err := c.doSigned(ctx, http.MethodPost, "/blocks", binaryHeader, data, privateKey, data, &block)
+	if err != nil {
+		return nil, err
+	}
+	return &block, nil
+}
+
+// VerifyMessage 让服务器校验blockchain.SignMessage生成的消息签名, 签名有效时结果中包含签名者的公钥.
+// 地址或者签名的格式不正确时服务器返回400.
+func (c *Client) VerifyMessage(ctx context.Context, address, message, signature string) (*blockchain.VerifyMessageResult, error) {
+	body, err := json.Marshal(blockchain.VerifyMessageRequest{Address: address, Message: message, Signature: signature})
+	if err != nil {
+		return nil, err
+	}
+	var result blockchain.VerifyMessageResult
+	if err = c.do(ctx, http.MethodPost, "/verify", jsonHeader, body, true, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// Healthz 检查服务器是否存活. 检查的是当前的状态, 所以不会重试.
+func (c *Client) Healthz(ctx context.Context) error {
+	return c.do(ctx, http.MethodGet, "/healthz", nil, nil, false, nil)
+}
+
+// Readyz 检查服务器是否可以处理请求, 服务器正在关闭或者已经停止挖矿时返回状态码为503的*Error.
+// 检查的是当前的状态, 所以不会重试.
+func (c *Client) Readyz(ctx context.Context) error {
+	return c.do(ctx, http.MethodGet, "/readyz", nil, nil, false, nil)
+}
+
+// Snapshot 让服务器生成一个数据快照, format为tar或者dir, 参数为空时使用服务器的默认值.
+// 服务器要求HTTPClient出示客户端证书, 没有使用mutual TLS时使用SignedSnapshot.
+func (c *Client) Snapshot(ctx context.Context, name, format string) (*SnapshotResult, error) {
+	return c.snapshot(ctx, name, format, "")
+}
+
+// SignedSnapshot 和Snapshot一样, 但是使用有写入权限的私钥对请求签名.
+func (c *Client) SignedSnapshot(ctx context.Context, name, format, privateKey string) (*SnapshotResult, error) {
+	return c.snapshot(ctx, name, format, privateKey)
+}
+
+func (c *Client) snapshot(ctx context.Context, name, format, privateKey string) (*SnapshotResult, error) {
+	query := url.Values{}
+	if name != "" {
+		query.Set("name", name)
+	}
+	if format != "" {
+		query.Set("format", format)
+	}
+	path := "/admin/snapshot?" + query.Encode()
+
+	var result SnapshotResult
+	var err error
+	if privateKey == "" {
+		err = c.do(ctx, http.MethodPost, path, nil, nil, false, &result)
+	} else {
+		err = c.doSigned(ctx, http.MethodPost, path, nil, nil, privateKey, blockchain.AdminMessage(http.MethodPost, path), &result)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// setCredentials 把签名写入http头.
+func setCredentials(header http.Header, credentials *blockchain.Credentials) {
+	header.Set(blockchain.HeaderPublicKey, credentials.PublicKey)
+	header.Set(blockchain.HeaderSignature, credentials.Signature)
+	header.Set(blockchain.HeaderTimestamp, strconv.FormatInt(credentials.Timestamp, 10))
+	header.Set(blockchain.HeaderNonce, credentials.Nonce)
+}
+
+// Call 调用JSON-RPC方法, 结果解析到result中. 服务器返回的错误类型为*blockchain.RPCError.
+func (c *Client) Call(ctx context.Context, method string, params interface{}, result interface{}) error {
+	req := struct {
+		JSONRPC string      `json:"jsonrpc"`
+		Method  string      `json:"method"`
+		Params  interface{} `json:"params,omitempty"`
+		ID      int         `json:"id"`
+	}{"2.0", method, params, 1}
+
+	body, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+
+	var resp blockchain.RPCResponse
+	if err = c.do(ctx, http.MethodPost, "/rpc", jsonHeader, body, false, &resp); err != nil {
+		// 被限流时返回429, 响应体中仍然是JSON-RPC的错误
+		var apiErr *Error
+		if errors.As(err, &apiErr) && json.Unmarshal([]byte(apiErr.Message), &resp) == nil && resp.Error != nil {
+			return resp.Error
+		}
+		return err
+	}
+	if resp.Error != nil {
+		return resp.Error
+	}
+	if result == nil {
+		return nil
+	}
+	return json.Unmarshal(resp.Result, result)
+}
+
+// do 发送请求并把返回的JSON解析到result中.
+// 不幂等的请求只在服务器明确拒绝(429)时重试, 因为此时请求还没有被处理.
+func (c *Client) do(ctx context.Context, method, path string, header http.Header, body []byte, idempotent bool, result interface{}) error {
+	return c.retry(ctx, idempotent, func() (bool, time.Duration, error) {
+		return c.doOnce(ctx, method, path, header, body, result)
+	})
+}
+
+// doSigned 发送使用私钥对signedData签名的请求. 每次发送前重新签名,
+// 重试的请求使用新的时间戳和nonce, 不会被服务器当作重放拒绝.
+func (c *Client) doSigned(ctx context.Context, method, path string, header http.Header, body []byte,
+	privateKey string, signedData []byte, result interface{}) error {
+	return c.retry(ctx, false, func() (bool, time.Duration, error) {
+		credentials, err := blockchain.SignCredentials(c.SigType, privateKey, signedData)
+		if err != nil {
+			return false, 0, err
+		}
+		h := header.Clone()
+		if h == nil {
+			h = http.Header{}
+		}
+		setCredentials(h, credentials)
+		return c.doOnce(ctx, method, path, h, body, result)
+	})
+}
+
+// retry 按照重试策略调用send.
+func (c *Client) retry(ctx context.Context, idempotent bool, send func() (bool, time.Duration, error)) error {
+	var err error
+	for attempt := 0; ; attempt++ {
+		var retry bool
+		var wait time.Duration
+		retry, wait, err = send()
+		if err == nil || !retry || attempt >= c.MaxRetries {
+			return err
+		}
+		if apiErr, ok := err.(*Error); !idempotent && (!ok || apiErr.StatusCode != http.StatusTooManyRequests) {
+			return err
+		}
+
+		if backoff := c.backoff(attempt); wait < backoff {
+			wait = backoff
+		}
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return err
+		}
+	}
+}
+
+// doOnce 发送一次请求, 返回是否可以重试以及服务器要求的等待时间.
+func (c *Client) doOnce(ctx context.Context, method, path string, header http.Header, body []byte, result interface{}) (bool, time.Duration, error) {
+	if c.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.Timeout)
+		defer cancel()
+	}
+
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequest(method, c.BaseURL+path, reader)
+	if err != nil {
+		return false, 0, err
+	}
+	req = req.WithContext(ctx)
+	for k, v := range header {
+		req.Header[k] = v
+	}
+
+	resp, err := c.httpClient().Do(req)
+	if err != nil {
+		// 网络错误, 如果调用者的ctx还没有结束就可以重试
+		return ctx.Err() == nil, 0, err
+	}
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return true, 0, err
+	}
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		apiErr := &Error{
+			StatusCode: resp.StatusCode,
+			Message:    strings.TrimSpace(string(data)),
+		}
+		return retryable(resp.StatusCode), retryAfter(resp), apiErr
+	}
+
+	if result == nil {
+		return false, 0, nil
+	}
+	return false, 0, json.Unmarshal(data, result)
+}
+
+func (c *Client) httpClient() *http.Client {
+	if c.HTTPClient != nil {
+		return c.HTTPClient
+	}
+	return http.DefaultClient
+}
+
+// backoff 计算第attempt次重试前的等待时间, 带有随机抖动.
+func (c *Client) backoff(attempt int) time.Duration {
+	d := c.MinBackoff
+	for i := 0; i < attempt && d < c.MaxBackoff; i++ {
+		d *= 2
+	}
+	if c.MaxBackoff > 0 && d > c.MaxBackoff {
+		d = c.MaxBackoff
+	}
+	if d <= 0 {
+		return 0
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+func retryable(code int) bool {
+	switch code {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
+
+// retryAfter 解析Retry-After头, 只支持秒数.
+func retryAfter(resp *http.Response) time.Duration {
+	seconds, err := strconv.Atoi(resp.Header.Get("Retry-After"))
+	if err != nil || seconds < 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
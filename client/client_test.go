@@ -0,0 +1,207 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/smallnest/blockchain"
+	"github.com/smallnest/blockchain/store"
+	"github.com/smallnest/blockchain/wallet"
+)
+
+// newTestServer 创建一个测试服务器, 只有privateKey可以写入区块和调用管理接口.
+func newTestServer(t *testing.T) (bc *blockchain.Blockchain, ts *httptest.Server, privateKey string) {
+	s, err := store.NewLevelDBStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Close() })
+
+	bc = &blockchain.Blockchain{
+		Store:      s,
+		Difficulty: 1,
+		PrefixZero: "0",
+		PruneDepth: 3,
+	}
+	if err = bc.GenerateGenesisBlock(); err != nil {
+		t.Fatal(err)
+	}
+
+	privateKey, _, publicKey, _, err := wallet.GenerateKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	server, err := blockchain.NewServer("", "", bc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server.SnapshotDir = t.TempDir()
+	server.Authorizer = blockchain.NewAuthorizer([]string{publicKey})
+	ts = httptest.NewServer(server.Handler())
+	t.Cleanup(ts.Close)
+	return bc, ts, privateKey
+}
+
+func TestClient(t *testing.T) {
+	_, ts, privateKey := newTestServer(t)
+	c := NewClient(ts.URL)
+	ctx := context.Background()
+
+	var apiErr *Error
+	if _, err := c.WriteBlock(ctx, []byte("hello")); !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expect 401 for an unsigned write but got %v", err)
+	}
+	for i := 0; i < 5; i++ {
+		block, err := c.WriteSignedBlock(ctx, []byte("hello"), privateKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if block.Height != uint64(i+1) {
+			t.Fatalf("expect height %d but got %d", i+1, block.Height)
+		}
+	}
+
+	blocks, err := c.GetBlocks(ctx, 3)
+	if err != nil || len(blocks) != 3 {
+		t.Fatalf("expect 3 blocks: %v", err)
+	}
+	if _, err = c.GetBlocks(ctx, 2); !errors.Is(err, blockchain.ErrPruned) {
+		t.Fatalf("expect ErrPruned but got %v", err)
+	}
+
+	block, err := c.GetBlock(ctx, 5)
+	if err != nil || string(block.Data) != "hello" {
+		t.Fatalf("failed to get block 5: %v", err)
+	}
+	if _, err = c.GetBlock(ctx, 1); !errors.Is(err, blockchain.ErrPruned) {
+		t.Fatalf("expect ErrPruned but got %v", err)
+	}
+	if _, err = c.GetBlock(ctx, 100); !errors.Is(err, blockchain.ErrNotFound) {
+		t.Fatalf("expect ErrNotFound but got %v", err)
+	}
+
+	var tip blockchain.Block
+	if err = c.Call(ctx, "getTip", nil, &tip); err != nil || tip.Height != 5 {
+		t.Fatalf("failed to call getTip: %v", err)
+	}
+	var rpcErr *blockchain.RPCError
+	if err = c.Call(ctx, "getBlockByHeight", []uint64{100}, nil); !errors.As(err, &rpcErr) || rpcErr.Code != blockchain.RPCNotFound {
+		t.Fatalf("expect rpc not found error but got %v", err)
+	}
+
+	if _, err = c.Snapshot(ctx, "test", ""); !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expect 401 for an unsigned snapshot but got %v", err)
+	}
+	snapshot, err := c.SignedSnapshot(ctx, "test", "", privateKey)
+	if err != nil || snapshot.Height != 5 {
+		t.Fatalf("failed to create snapshot: %v", err)
+	}
+}
+
+func TestClientVerifyMessage(t *testing.T) {
+	bc, ts, _ := newTestServer(t)
+	c := NewClient(ts.URL)
+	ctx := context.Background()
+
+	privateKey, _, publicKey, address, _ := wallet.GenerateKeys()
+	signature, err := blockchain.SignMessage(privateKey, "hello", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := c.VerifyMessage(ctx, address, "hello", signature)
+	if err != nil || !result.Valid || result.PublicKey != publicKey {
+		t.Fatalf("signature should be valid: %+v, %v", result, err)
+	}
+	if result, err = c.VerifyMessage(ctx, address, "other", signature); err != nil || result.Valid {
+		t.Fatalf("signature of another message should be invalid: %+v, %v", result, err)
+	}
+	var apiErr *Error
+	if _, err = c.VerifyMessage(ctx, address, "hello", "invalid"); !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expect 400 for a malformed signature but got %v", err)
+	}
+
+	if err = c.Healthz(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err = c.Readyz(ctx); err != nil {
+		t.Fatal(err)
+	}
+	bc.Stop()
+	if err = c.Readyz(ctx); !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("expect 503 after stopping but got %v", err)
+	}
+	if err = c.Healthz(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestClientStream(t *testing.T) {
+	bc, ts, _ := newTestServer(t)
+	c := NewClient(ts.URL)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	go func() {
+		for i := 0; i < 3; i++ {
+			if _, err := bc.MineBlock([]byte("stream")); err != nil {
+				t.Error(err)
+			}
+		}
+	}()
+
+	// PruneDepth为3, 挖出区块3之后创世块被裁剪, 订阅时可能已经不再推送
+	from := uint64(0)
+	var heights []uint64
+	err := c.StreamBlocks(ctx, &from, func(block *blockchain.Block) error {
+		heights = append(heights, block.Height)
+		if block.Height == 3 {
+			return errors.New("done")
+		}
+		return nil
+	})
+	if err == nil || err.Error() != "done" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for i, height := range heights {
+		if height != heights[0]+uint64(i) || heights[0] > 1 {
+			t.Fatalf("unexpected heights: %v", heights)
+		}
+	}
+}
+
+func TestClientRetry(t *testing.T) {
+	var calls int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			http.Error(w, "busy", http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"height":1}`))
+	}))
+	defer ts.Close()
+
+	c := NewClient(ts.URL)
+	c.MinBackoff = time.Millisecond
+	block, err := c.GetBlock(context.Background(), 1)
+	if err != nil || block.Height != 1 {
+		t.Fatalf("expect success after retries: %v", err)
+	}
+	if calls != 3 {
+		t.Fatalf("expect 3 calls but got %d", calls)
+	}
+
+	// 写入不会在服务器出错时重试
+	atomic.StoreInt32(&calls, 0)
+	if _, err = c.WriteBlock(context.Background(), nil); err == nil {
+		t.Fatal("expect error")
+	}
+	if calls != 1 {
+		t.Fatalf("expect 1 call but got %d", calls)
+	}
+}
@@ -0,0 +1,126 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/smallnest/blockchain"
+)
+
+// errStreamClosed 推送连接被服务器关闭.
+var errStreamClosed = errors.New("block stream closed by server")
+
+// StreamBlocks 通过Server-Sent Events接收新的区块, from不为nil时先接收从该高度开始已有的区块.
+// 每个区块都会调用fn, fn返回错误或者ctx结束时停止接收.
+// 连接断开时会自动从下一个高度重连, 所以不会漏掉区块, 连续失败超过MaxRetries次后返回错误.
+func (c *Client) StreamBlocks(ctx context.Context, from *uint64, fn func(*blockchain.Block) error) error {
+	var next *uint64
+	if from != nil {
+		height := *from
+		next = &height
+	}
+
+	var failures int
+	for {
+		received, err := c.streamOnce(ctx, next, func(block *blockchain.Block) error {
+			height := block.Height + 1
+			next = &height
+			return fn(block)
+		})
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if cbErr, ok := err.(callbackError); ok {
+			return cbErr.error
+		}
+
+		if received {
+			failures = 0
+		} else {
+			failures++
+		}
+		if failures > c.MaxRetries {
+			return err
+		}
+
+		select {
+		case <-time.After(c.backoff(failures)):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// callbackError 包装了fn返回的错误, 这种错误不会重连.
+type callbackError struct {
+	error
+}
+
+// streamOnce 建立一次推送连接, 返回是否收到过区块.
+func (c *Client) streamOnce(ctx context.Context, from *uint64, fn func(*blockchain.Block) error) (bool, error) {
+	path := "/blocks/stream"
+	if from != nil {
+		path += "?" + url.Values{"from": {strconv.FormatUint(*from, 10)}}.Encode()
+	}
+
+	req, err := http.NewRequest(http.MethodGet, c.BaseURL+path, nil)
+	if err != nil {
+		return false, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Accept", "text/event-stream")
+
+	resp, err := c.httpClient().Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		data, _ := ioutil.ReadAll(resp.Body)
+		return false, &Error{StatusCode: resp.StatusCode, Message: strings.TrimSpace(string(data))}
+	}
+
+	var received bool
+	var event, data string
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 64<<20)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			if data != "" && (event == "" || event == "block") {
+				var block blockchain.Block
+				if err := json.Unmarshal([]byte(data), &block); err != nil {
+					return received, err
+				}
+				received = true
+				if err := fn(&block); err != nil {
+					return received, callbackError{err}
+				}
+			}
+			event, data = "", ""
+		case strings.HasPrefix(line, ":"):
+			// 心跳
+		case strings.HasPrefix(line, "event:"):
+			event = strings.TrimSpace(line[len("event:"):])
+		case strings.HasPrefix(line, "data:"):
+			if data != "" {
+				data += "\n"
+			}
+			data += strings.TrimPrefix(line[len("data:"):], " ")
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return received, err
+	}
+	return received, errStreamClosed
+}
@@ -1,37 +1,121 @@
 package main
 
 import (
+	"context"
+	"encoding/hex"
+	"errors"
 	"flag"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/smallnest/blockchain"
+	"github.com/smallnest/blockchain/chaincfg"
+	"github.com/smallnest/blockchain/grpcapi"
 	"github.com/smallnest/blockchain/store"
+	"github.com/smallnest/blockchain/wallet"
+	"github.com/smallnest/blockchain/wallet/signer"
 	"github.com/smallnest/log"
+	"golang.org/x/time/rate"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 )
 
 var (
-	privateKey = flag.String("privateKey", "", "private key")
-	addr       = flag.String("addr", ":8972", "listened address")
+	network    = flag.String("network", chaincfg.MainNet.Name, "network to join: mainnet, testnet or regtest")
+	secp       = flag.String("signer", signer.Default().Name(), "secp256k1 implementation: "+strings.Join(signer.Names(), " or "))
+	privateKey = flag.String("privateKey", "", "private key, visible to other users of the host, prefer keystore")
+	keystore   = flag.String("keystore", "", "keystore file of the private key created by the key new command")
+	password   = flag.String("password-file", "", "file containing the password of the keystore")
+	addr       = flag.String("addr", "", "listened address, defaults to the port of the network")
+	grpcAddr   = flag.String("grpcAddr", "", "listened address of the gRPC server, empty disables it")
 	dataFile   = flag.String("data", "./data", "data file")
+	compress   = flag.String("compress", "", "compression of block data: none, snappy or zstd")
+	storeKey   = flag.String("storeKey", "", "hex encoded AES key to encrypt block data")
+	encrypt    = flag.Bool("encrypt", false, "encrypt block data with a key derived from the private key if storeKey is not set")
+	pruneDepth = flag.Uint64("pruneDepth", 0, "keep data of the latest pruneDepth blocks only, 0 disables pruning")
+	sigType    = flag.String("sigType", "", "sign mined blocks with the private key: ecdsa or schnorr, empty leaves blocks unsigned")
+	tlsCert    = flag.String("tlsCert", "", "TLS certificate file, enables TLS together with tlsKey")
+	tlsKey     = flag.String("tlsKey", "", "TLS private key file")
+	clientCA   = flag.String("tlsClientCA", "", "CA file of client certificates, enables mutual TLS")
+	authorized = flag.String("authorized", "", "file of public keys or P2PKH addresses allowed to write blocks, one per line")
+	snapshot   = flag.String("snapshotDir", "", "directory to save snapshots created by POST /admin/snapshot, which requires a verified client certificate or an authorized signature")
+
+	rateLimit    = flag.Float64("rateLimit", 0, "blocks per second each client IP may write, 0 disables the limit")
+	rateBurst    = flag.Int("rateBurst", 5, "burst of blocks each client IP may write")
+	keyRateLimit = flag.Float64("keyRateLimit", 0, "blocks per second each authorized public key may write, 0 disables the limit")
+	keyRateBurst = flag.Int("keyRateBurst", 5, "burst of blocks each authorized public key may write")
+	maxBodySize  = flag.Int64("maxBodySize", blockchain.DefaultMaxBodySize, "max size in bytes of the data of a write request over http or gRPC, 0 disables the limit")
+
+	shutdownTimeout = flag.Duration("shutdownTimeout", 30*time.Second, "max time to wait for in-flight requests on shutdown")
 )
 
+// grpcMsgOverhead 是gRPC写入请求中数据之外的字段的最大长度.
+const grpcMsgOverhead = 4 << 10
+
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "migrate":
+			migrate(os.Args[2:])
+			return
+		case "export":
+			export(os.Args[2:])
+			return
+		case "import":
+			importChain(os.Args[2:])
+			return
+		case "restore":
+			restore(os.Args[2:])
+			return
+		}
+	}
+
 	flag.Parse()
+	params := netParams(*network)
+	wallet.SetNetParams(params)
+	if err := signer.Use(*secp); err != nil {
+		log.Fatal(err)
+	}
+	if *addr == "" {
+		*addr = ":" + params.DefaultPort
+	}
+
+	var err error
+	if *privateKey, err = unlockPrivateKey(*privateKey, *keystore, *password); err != nil {
+		log.Fatalf("failed to unlock %s: %v", *keystore, err)
+	}
 	if *privateKey == "" {
 		log.Info("请使用key命令行生成你自己的私钥，并且妥善保存。一旦丢失，无法找回!")
 		return
 	}
 
-	store, err := store.NewLevelDBStore(*dataFile)
+	key, err := codecKey(*storeKey, *encrypt, *privateKey)
+	if err != nil {
+		log.Fatalf("invalid store key: %v", err)
+	}
+	store, err := openStore(*dataFile, *compress, key)
 	if err != nil {
 		log.Fatalf("failed to create leveldb store: %v", err)
 	}
 	defer store.Close()
 
+	if version := store.FormatVersion(); version != blockchain.BlockFormatVersion {
+		log.Fatalf("data format version %d is outdated, run `bc migrate -data %s` to upgrade it to version %d",
+			version, *dataFile, blockchain.BlockFormatVersion)
+	}
+
 	// 创建一个区块链
-	var bc = &blockchain.Blockchain{
-		Store:      store,
-		Difficulty: 5,
-		PrefixZero: "00000",
+	var bc = blockchain.NewBlockchain(blockchain.NewInstrumentedStore(store), params)
+	bc.PruneDepth = *pruneDepth
+	if *sigType != "" {
+		if bc.SigType, err = blockchain.ParseSigType(*sigType); err != nil {
+			log.Fatal(err)
+		}
+		bc.PrivateKey = *privateKey
 	}
 
 	err = bc.LoadFromStore()
@@ -40,17 +124,165 @@ func main() {
 	}
 
 	if len(bc.Blocks) == 0 {
-		bc.GenerateGenesisBlock()
+		if err := bc.GenerateGenesisBlock(); err != nil {
+			log.Fatal(err)
+		}
 	}
 
 	// 创建 rpc server
-	var server = blockchain.NewServer(*addr, bc)
+	server, err := blockchain.NewServer(*privateKey, *addr, bc)
+	if err != nil {
+		log.Fatalf("invalid private key: %v", err)
+	}
+	server.SnapshotDir = *snapshot
+	if *authorized != "" {
+		authorizer, err := blockchain.LoadAuthorizer(*authorized)
+		if err != nil {
+			log.Fatalf("failed to load authorized submitters: %v", err)
+		}
+		server.Authorizer = authorizer
+	}
+	server.MaxBodySize = *maxBodySize
+	if *rateLimit > 0 || *keyRateLimit > 0 {
+		server.RateLimiter = blockchain.NewRateLimiter(blockchain.RateLimitOptions{
+			PerIP:    rate.Limit(*rateLimit),
+			IPBurst:  *rateBurst,
+			PerKey:   rate.Limit(*keyRateLimit),
+			KeyBurst: *keyRateBurst,
+		})
+	}
+
+	var grpcOpts []grpc.ServerOption
+	if *clientCA != "" && (*tlsCert == "" || *tlsKey == "") {
+		log.Fatal("-tlsClientCA requires -tlsCert and -tlsKey")
+	}
+	if *tlsCert != "" || *tlsKey != "" {
+		tlsConfig, err := blockchain.NewTLSConfig(blockchain.TLSOptions{
+			CertFile:     *tlsCert,
+			KeyFile:      *tlsKey,
+			ClientCAFile: *clientCA,
+		})
+		if err != nil {
+			log.Fatalf("failed to load TLS certificates: %v", err)
+		}
+		server.TLSConfig = tlsConfig
+		grpcOpts = append(grpcOpts, grpc.Creds(credentials.NewTLS(tlsConfig)))
+	}
+
+	var grpcServer *grpcapi.Server
+	if *grpcAddr != "" {
+		if *maxBodySize > 0 {
+			// 消息中除了数据还有公钥和签名等字段
+			grpcOpts = append(grpcOpts, grpc.MaxRecvMsgSize(int(*maxBodySize)+grpcMsgOverhead))
+		}
+		grpcServer = grpcapi.NewServer(*grpcAddr, bc, grpcOpts...)
+		grpcServer.Authorizer = server.Authorizer
+		grpcServer.RateLimiter = server.RateLimiter
+		grpcServer.MaxDataSize = *maxBodySize
+		go func() {
+			if err := grpcServer.Serve(); err != nil {
+				log.Errorf("failed to serve gRPC: %v", err)
+			}
+		}()
+	}
 
 	// 启动服务
-	if err := server.Serve(); err != nil {
-		log.Errorf("failed to serve: %v", err)
-		return
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- server.Serve()
+	}()
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	select {
+	case sig := <-signals:
+		log.Infof("received %v, shutting down", sig)
+	case err := <-serveErr:
+		if err != http.ErrServerClosed {
+			log.Errorf("failed to serve: %v", err)
+		}
+	}
+	signal.Stop(signals)
+
+	// 先停止挖矿, 再等待正在处理的请求结束, 最后由defer关闭Store把数据写入磁盘
+	bc.Stop()
+	ctx, cancel := context.WithTimeout(context.Background(), *shutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		log.Errorf("failed to shutdown http server: %v", err)
+	}
+	if grpcServer != nil {
+		grpcServer.Stop()
 	}
 
 	log.Info("exit mormally")
 }
+
+// netParams 返回名字为name的网络参数, 未知的网络直接退出.
+func netParams(name string) *chaincfg.NetParams {
+	params, err := chaincfg.Lookup(name)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return params
+}
+
+// unlockPrivateKey 返回私钥, 指定了keystore时使用密码文件中的密码解密.
+func unlockPrivateKey(privateKey, keystore, passwordFile string) (string, error) {
+	if keystore == "" {
+		return privateKey, nil
+	}
+	if passwordFile == "" {
+		return "", errors.New("-password-file is required to unlock the keystore")
+	}
+	password, err := wallet.ReadPasswordFile(passwordFile)
+	if err != nil {
+		return "", err
+	}
+	return wallet.UnlockFile(keystore, password)
+}
+
+// codecKey 得到加密区块数据的密钥, 如果不需要加密则返回nil.
+func codecKey(hexKey string, derive bool, privateKey string) ([]byte, error) {
+	if hexKey != "" {
+		return hex.DecodeString(hexKey)
+	}
+	if derive {
+		return store.DeriveKey(privateKey)
+	}
+	return nil, nil
+}
+
+// openStore 打开数据目录, 如果指定了压缩或者加密，则使用CodecStore包装.
+func openStore(dataFile, compression string, key []byte) (*leveldbCodecStore, error) {
+	c, err := store.ParseCompression(compression)
+	if err != nil {
+		return nil, err
+	}
+
+	s, err := store.NewLevelDBStore(dataFile)
+	if err != nil {
+		return nil, err
+	}
+
+	cs, err := store.NewCodecStore(s, store.CodecOptions{
+		Compression: c,
+		Key:         key,
+	})
+	if err != nil {
+		s.Close()
+		return nil, err
+	}
+	return &leveldbCodecStore{CodecStore: cs, LevelDBStore: s}, nil
+}
+
+// leveldbCodecStore 是包装了LevelDBStore的CodecStore.
+type leveldbCodecStore struct {
+	*store.CodecStore
+	LevelDBStore *store.LevelDBStore
+}
+
+// FormatVersion 返回数据目录使用的编码版本.
+func (s *leveldbCodecStore) FormatVersion() byte {
+	return s.LevelDBStore.FormatVersion()
+}
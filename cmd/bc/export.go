@@ -0,0 +1,128 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"os"
+
+	"github.com/smallnest/blockchain"
+	"github.com/smallnest/blockchain/chaincfg"
+	"github.com/smallnest/log"
+)
+
+// progressInterval 每处理这么多区块报告一次进度.
+const progressInterval = 1000
+
+// storeFlags 是打开数据目录需要的命令行参数.
+type storeFlags struct {
+	network      *string
+	dataFile     *string
+	privateKey   *string
+	keystore     *string
+	passwordFile *string
+	compress     *string
+	storeKey     *string
+	encrypt      *bool
+}
+
+func addStoreFlags(fs *flag.FlagSet) *storeFlags {
+	return &storeFlags{
+		network:      fs.String("network", chaincfg.MainNet.Name, "network of the data: mainnet, testnet or regtest"),
+		dataFile:     fs.String("data", "./data", "data file"),
+		privateKey:   fs.String("privateKey", "", "private key, used to derive the store key"),
+		keystore:     fs.String("keystore", "", "keystore file of the private key"),
+		passwordFile: fs.String("password-file", "", "file containing the password of the keystore"),
+		compress:     fs.String("compress", "", "compression of block data: none, snappy or zstd"),
+		storeKey:     fs.String("storeKey", "", "hex encoded AES key to encrypt block data"),
+		encrypt:      fs.Bool("encrypt", false, "encrypt block data with a key derived from the private key if storeKey is not set"),
+	}
+}
+
+func (f *storeFlags) open() (*leveldbCodecStore, error) {
+	privateKey, err := unlockPrivateKey(*f.privateKey, *f.keystore, *f.passwordFile)
+	if err != nil {
+		return nil, err
+	}
+	key, err := codecKey(*f.storeKey, *f.encrypt, privateKey)
+	if err != nil {
+		return nil, err
+	}
+	return openStore(*f.dataFile, *f.compress, key)
+}
+
+func reportProgress(action string) func(n uint64) {
+	return func(n uint64) {
+		if n%progressInterval == 0 {
+			log.Infof("%s %d blocks", action, n)
+		}
+	}
+}
+
+// export 把数据目录中的区块导出到文件.
+func export(args []string) {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	sf := addStoreFlags(fs)
+	out := fs.String("out", "-", "output file, - for stdout")
+	format := fs.String("format", blockchain.FormatBinary, "export format: binary or json")
+	fs.Parse(args)
+
+	s, err := sf.open()
+	if err != nil {
+		log.Fatalf("failed to open %s: %v", *sf.dataFile, err)
+	}
+	defer s.Close()
+
+	var w io.Writer = os.Stdout
+	if *out != "-" {
+		f, err := os.Create(*out)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+		w = f
+	}
+
+	n, err := blockchain.ExportBlocks(w, s, *format, reportProgress("exported"))
+	if err != nil {
+		log.Fatalf("failed to export block %d: %v", n, err)
+	}
+	log.Infof("exported %d blocks", n)
+}
+
+// importChain 把导出文件中的区块校验后导入到数据目录.
+func importChain(args []string) {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	sf := addStoreFlags(fs)
+	in := fs.String("in", "-", "input file, - for stdin")
+	format := fs.String("format", blockchain.FormatBinary, "export format: binary or json")
+	fs.Parse(args)
+
+	s, err := sf.open()
+	if err != nil {
+		log.Fatalf("failed to open %s: %v", *sf.dataFile, err)
+	}
+	defer s.Close()
+
+	var r io.Reader = os.Stdin
+	if *in != "-" {
+		f, err := os.Open(*in)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+		r = f
+	}
+
+	// 按照网络参数的难度校验导入的区块
+	bc := blockchain.NewBlockchain(s, netParams(*sf.network))
+	if err = bc.LoadFromStore(); err != nil {
+		log.Fatal(err)
+	}
+
+	n, err := bc.ImportBlocks(r, *format, reportProgress("read"))
+	if err != nil {
+		log.Errorf("imported %d blocks before failure: %v", n, err)
+		return
+	}
+	log.Infof("imported %d blocks, chain height is %d", n, len(bc.Blocks)-1)
+}
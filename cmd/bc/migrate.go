@@ -0,0 +1,110 @@
+package main
+
+import (
+	"flag"
+	"os"
+
+	"github.com/smallnest/blockchain"
+	"github.com/smallnest/log"
+)
+
+// migrate 使用最新的编码版本, 按照新的压缩和加密设置重写整个数据目录.
+// 旧的数据目录会被保留为 <data>.bak.
+func migrate(args []string) {
+	fs := flag.NewFlagSet("migrate", flag.ExitOnError)
+	var (
+		dataFile   = fs.String("data", "./data", "data file")
+		privateKey = fs.String("privateKey", "", "private key, used to derive store keys")
+		keystore   = fs.String("keystore", "", "keystore file of the private key")
+		password   = fs.String("password-file", "", "file containing the password of the keystore")
+		oldKey     = fs.String("oldKey", "", "hex encoded AES key of the existing data")
+		oldEncrypt = fs.Bool("oldEncrypt", false, "existing data is encrypted with a key derived from the private key")
+		compress   = fs.String("compress", "", "new compression of block data: none, snappy or zstd")
+		storeKey   = fs.String("storeKey", "", "new hex encoded AES key to encrypt block data")
+		encrypt    = fs.Bool("encrypt", false, "encrypt block data with a key derived from the private key if storeKey is not set")
+	)
+	fs.Parse(args)
+
+	var err error
+	if *privateKey, err = unlockPrivateKey(*privateKey, *keystore, *password); err != nil {
+		log.Fatalf("failed to unlock %s: %v", *keystore, err)
+	}
+	srcKey, err := codecKey(*oldKey, *oldEncrypt, *privateKey)
+	if err != nil {
+		log.Fatalf("invalid old store key: %v", err)
+	}
+	dstKey, err := codecKey(*storeKey, *encrypt, *privateKey)
+	if err != nil {
+		log.Fatalf("invalid store key: %v", err)
+	}
+
+	src, err := openStore(*dataFile, "", srcKey)
+	if err != nil {
+		log.Fatalf("failed to open %s: %v", *dataFile, err)
+	}
+	srcVersion := src.FormatVersion()
+
+	tmpFile := *dataFile + ".migrating"
+	if err := os.RemoveAll(tmpFile); err != nil {
+		log.Fatal(err)
+	}
+	dst, err := openStore(tmpFile, *compress, dstKey)
+	if err != nil {
+		log.Fatalf("failed to create %s: %v", tmpFile, err)
+	}
+
+	n, err := copyBlocks(dst, src)
+	src.Close()
+	dst.Close()
+	if err != nil {
+		log.Fatalf("failed to migrate block %d: %v", n, err)
+	}
+
+	backup := *dataFile + ".bak"
+	if err := os.Rename(*dataFile, backup); err != nil {
+		log.Fatal(err)
+	}
+	if err := os.Rename(tmpFile, *dataFile); err != nil {
+		log.Fatal(err)
+	}
+
+	log.Infof("migrated %d blocks from format version %d to %d, the old data is kept in %s",
+		n, srcVersion, blockchain.BlockFormatVersion, backup)
+}
+
+// copyBlocks 把src中的区块逐个复制到dst中, 返回复制的区块数.
+// src被裁剪过时, dst也裁剪到相同的高度.
+func copyBlocks(dst, src blockchain.Store) (uint64, error) {
+	var i uint64
+	for {
+		block, err := src.Get(i)
+		if err == blockchain.ErrNotFound {
+			return i, copyPrunedHeight(dst, src)
+		}
+		if err != nil {
+			return i, err
+		}
+		if err := dst.Add(i, block); err != nil {
+			return i, err
+		}
+		i++
+	}
+}
+
+// copyPrunedHeight 把src裁剪到的高度记录到dst中.
+func copyPrunedHeight(dst, src blockchain.Store) error {
+	srcPruner, ok := src.(blockchain.Pruner)
+	if !ok {
+		return nil
+	}
+	height, err := srcPruner.PrunedHeight()
+	if err != nil || height == 0 {
+		return err
+	}
+
+	dstPruner, ok := dst.(blockchain.Pruner)
+	if !ok {
+		return blockchain.ErrPruneUnsupported
+	}
+	return dstPruner.Prune(height)
+}
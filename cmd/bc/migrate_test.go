@@ -0,0 +1,46 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/smallnest/blockchain"
+)
+
+func TestCopyPrunedBlocks(t *testing.T) {
+	dir := t.TempDir()
+	src, err := openStore(filepath.Join(dir, "src"), "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer src.Close()
+	for i := uint64(0); i < 5; i++ {
+		if err = src.Add(i, &blockchain.Block{Height: i, Data: []byte("data")}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = src.Prune(3); err != nil {
+		t.Fatal(err)
+	}
+
+	dst, err := openStore(filepath.Join(dir, "dst"), "zstd", make([]byte, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dst.Close()
+	n, err := copyBlocks(dst, src)
+	if err != nil || n != 5 {
+		t.Fatalf("expect 5 copied blocks but got %d: %v", n, err)
+	}
+
+	if height, err := dst.PrunedHeight(); err != nil || height != 3 {
+		t.Fatalf("expect pruned height 3 but got %d: %v", height, err)
+	}
+	blocks, err := dst.GetBatch(0, 5)
+	if err != nil || len(blocks) != 5 {
+		t.Fatalf("expect 5 blocks: %v", err)
+	}
+	if len(blocks[2].Data) != 0 || string(blocks[3].Data) != "data" {
+		t.Fatalf("unexpected data %q of pruned block and %q of kept block", blocks[2].Data, blocks[3].Data)
+	}
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"flag"
+
+	"github.com/smallnest/blockchain/store"
+	"github.com/smallnest/log"
+)
+
+// restore 用快照恢复数据目录.
+func restore(args []string) {
+	fs := flag.NewFlagSet("restore", flag.ExitOnError)
+	var (
+		from     = fs.String("from", "", "snapshot directory or tarball")
+		dataFile = fs.String("data", "./data", "data file to restore, must not exist")
+	)
+	fs.Parse(args)
+
+	if *from == "" {
+		log.Fatal("snapshot is not specified")
+	}
+
+	if err := store.Restore(*from, *dataFile); err != nil {
+		log.Fatalf("failed to restore %s: %v", *from, err)
+	}
+	log.Infof("restored %s to %s", *from, *dataFile)
+}
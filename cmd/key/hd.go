@@ -0,0 +1,123 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"log"
+
+	"github.com/smallnest/blockchain/wallet"
+)
+
+// hdKeyFlags 是派生密钥时指定根密钥的参数, 使用助记词或者扩展密钥.
+type hdKeyFlags struct {
+	mnemonic   *string
+	passphrase *string
+	extended   *string
+}
+
+func addHDKeyFlags(fs *flag.FlagSet) *hdKeyFlags {
+	return &hdKeyFlags{
+		mnemonic:   fs.String("mnemonic", "", "BIP-39 mnemonic of the master key"),
+		passphrase: fs.String("passphrase", "", "BIP-39 passphrase of the mnemonic"),
+		extended:   fs.String("key", "", "extended key (xprv or xpub) to derive from instead of the mnemonic"),
+	}
+}
+
+func (f *hdKeyFlags) key() (*wallet.HDKey, error) {
+	switch {
+	case *f.extended != "":
+		return wallet.ParseExtendedKey(*f.extended)
+	case *f.mnemonic != "":
+		return wallet.NewMasterKeyFromMnemonic(*f.mnemonic, *f.passphrase)
+	default:
+		return nil, errors.New("either -mnemonic or -key is required")
+	}
+}
+
+// derive 按照路径派生密钥.
+func derive(args []string) {
+	fs := flag.NewFlagSet("derive", flag.ExitOnError)
+	keyFlags := addHDKeyFlags(fs)
+	var (
+		path    = fs.String("path", "", "derivation path, defaults to the BIP-44 path of account/change/index")
+		account = fs.Uint("account", 0, "BIP-44 account")
+		change  = fs.Uint("change", 0, "BIP-44 change, 0 for receiving and 1 for change addresses")
+		index   = fs.Uint("index", 0, "BIP-44 address index")
+	)
+	fs.Parse(args)
+
+	root, err := keyFlags.key()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *path == "" {
+		*path = wallet.BIP44Path(uint32(*account), uint32(*change), uint32(*index))
+	}
+	key, err := root.Derive(*path)
+	if err != nil {
+		log.Fatalf("failed to derive %s: %v", *path, err)
+	}
+
+	log.Printf("path       : %s\n", *path)
+	if key.IsPrivate() {
+		priKey, _ := key.PrivateKey()
+		wif, _ := key.WIF()
+		log.Printf("private key: %s\n", priKey)
+		log.Printf("wif        : %s\n", wif)
+		log.Printf("xprv       : %s\n", key)
+	}
+	publicKey, err := key.PublicKey()
+	if err != nil {
+		log.Fatal(err)
+	}
+	address, _ := key.Address()
+	log.Printf("public  key: %s\n", publicKey)
+	log.Printf("address    : %s\n", address)
+	log.Printf("xpub       : %s\n", key.Neuter())
+}
+
+// exportXpub 导出账户的扩展公钥, 可以交给只负责收款的服务派生地址.
+func exportXpub(args []string) {
+	fs := flag.NewFlagSet("xpub", flag.ExitOnError)
+	keyFlags := addHDKeyFlags(fs)
+	account := fs.Uint("account", 0, "BIP-44 account")
+	fs.Parse(args)
+
+	root, err := keyFlags.key()
+	if err != nil {
+		log.Fatal(err)
+	}
+	path := wallet.BIP44AccountPath(uint32(*account))
+	key, err := root.Derive(path)
+	if err != nil {
+		log.Fatalf("failed to derive %s: %v", path, err)
+	}
+
+	log.Printf("path: %s\n", path)
+	log.Printf("xprv: %s\n", key)
+	log.Printf("xpub: %s\n", key.Neuter())
+}
+
+// addresses 从账户的扩展公钥派生地址, 不需要私钥.
+func addresses(args []string) {
+	fs := flag.NewFlagSet("addresses", flag.ExitOnError)
+	var (
+		xpub   = fs.String("xpub", "", "extended public key of a BIP-44 account")
+		change = fs.Uint("change", 0, "0 for receiving and 1 for change addresses")
+		start  = fs.Uint("start", 0, "index of the first address")
+		count  = fs.Uint("count", 10, "number of addresses")
+	)
+	fs.Parse(args)
+
+	key, err := wallet.ParseExtendedKey(*xpub)
+	if err != nil {
+		log.Fatal(err)
+	}
+	list, err := key.Addresses(uint32(*change), uint32(*start), uint32(*count))
+	if err != nil {
+		log.Fatal(err)
+	}
+	for i, address := range list {
+		log.Printf("%d/%d: %s\n", *change, uint(*start)+uint(i), address)
+	}
+}
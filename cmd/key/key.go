@@ -1,20 +1,94 @@
 package main
 
 import (
+	"flag"
 	"log"
+	"strings"
 
+	"github.com/smallnest/blockchain/chaincfg"
 	"github.com/smallnest/blockchain/wallet"
+	"github.com/smallnest/blockchain/wallet/signer"
+)
+
+var (
+	network = flag.String("network", chaincfg.MainNet.Name, "network of the keys and addresses: mainnet, testnet or regtest")
+	secp    = flag.String("signer", signer.Default().Name(), "secp256k1 implementation: "+strings.Join(signer.Names(), " or "))
 )
 
 func main() {
-	priKey, wif, pubKey, address := wallet.GenerateKeys()
+	flag.Parse()
+	params, err := chaincfg.Lookup(*network)
+	if err != nil {
+		log.Fatal(err)
+	}
+	wallet.SetNetParams(params)
+	if err = signer.Use(*secp); err != nil {
+		log.Fatal(err)
+	}
+
+	if args := flag.Args(); len(args) > 0 {
+		switch args[0] {
+		case "derive":
+			derive(args[1:])
+			return
+		case "xpub":
+			exportXpub(args[1:])
+			return
+		case "addresses":
+			addresses(args[1:])
+			return
+		case "new":
+			newKey(args[1:])
+			return
+		case "import":
+			importKey(args[1:])
+			return
+		case "export":
+			exportKey(args[1:])
+			return
+		case "list":
+			listKeys(args[1:])
+			return
+		case "multisig":
+			multisig(args[1:])
+			return
+		case "sign":
+			signMessage(args[1:])
+			return
+		case "verify":
+			verifyMessage(args[1:])
+			return
+		default:
+			log.Fatalf("unknown command %q", args[0])
+		}
+	}
+
+	priKey, wif, pubKey, address, err := wallet.GenerateKeys()
+	if err != nil {
+		log.Fatalf("failed to generate keys: %v", err)
+	}
 	log.Println("===============生成公私钥===============")
 	log.Printf("private key: %s\n", priKey)
 	log.Printf("wif        : %s\n", wif)
 	log.Printf("public  key: %s\n", pubKey)
 	log.Printf("address    : %s\n\n", address)
 
-	mnemonic, priKey, wif, pubKey, address := wallet.GenerateBIP39("this is a test")
+	compressedPubKey, compressedAddress, err := wallet.GetCompressedPublicKey(priKey)
+	if err != nil {
+		log.Fatal(err)
+	}
+	segwitAddress, _ := wallet.PublicKey2P2WPKH(compressedPubKey)
+	compressedWif, _ := wallet.PrivateKey2CompressedWif(priKey)
+	log.Println("===============压缩公钥===============")
+	log.Printf("wif        : %s\n", compressedWif)
+	log.Printf("public  key: %s\n", compressedPubKey)
+	log.Printf("address    : %s\n", compressedAddress)
+	log.Printf("segwit     : %s\n\n", segwitAddress)
+
+	mnemonic, priKey, wif, pubKey, address, err := wallet.GenerateBIP39("this is a test")
+	if err != nil {
+		log.Fatalf("failed to generate BIP-39 keys: %v", err)
+	}
 	log.Println("===============根据BIP-39生成公私钥===============")
 	log.Printf("mnemonic   : %s\n", mnemonic)
 	log.Printf("private key: %s\n", priKey)
@@ -22,7 +96,10 @@ func main() {
 	log.Printf("public  key: %s\n", pubKey)
 	log.Printf("address    : %s\n\n", address)
 
-	priKey, wif, pubKey, address = wallet.RecoverBIP39(mnemonic, "this is a test")
+	priKey, wif, pubKey, address, err = wallet.RecoverBIP39(mnemonic, "this is a test")
+	if err != nil {
+		log.Fatalf("failed to recover BIP-39 keys: %v", err)
+	}
 	log.Println("===============根据BIP-39恢复公私钥===============")
 	log.Printf("private key: %s\n", priKey)
 	log.Printf("wif        : %s\n", wif)
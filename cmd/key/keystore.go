@@ -0,0 +1,155 @@
+package main
+
+import (
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/smallnest/blockchain/wallet"
+	"golang.org/x/term"
+)
+
+// keystoreFlags 是访问keystore的参数.
+type keystoreFlags struct {
+	dir          *string
+	passwordFile *string
+	light        *bool
+}
+
+func addKeystoreFlags(fs *flag.FlagSet) *keystoreFlags {
+	return &keystoreFlags{
+		dir:          fs.String("keystore", "./keystore", "keystore directory"),
+		passwordFile: fs.String("password-file", "", "file containing the password, prompt for it if not set"),
+		light:        fs.Bool("light", false, "use light scrypt parameters, faster but less secure"),
+	}
+}
+
+func (f *keystoreFlags) keystore() *wallet.Keystore {
+	ks := wallet.NewKeystore(*f.dir)
+	if *f.light {
+		ks.ScryptN, ks.ScryptP = wallet.LightScryptN, wallet.LightScryptP
+	}
+	return ks
+}
+
+// password 从密码文件读取密码, 没有指定文件时在终端输入, confirm为true时需要输入两次.
+func (f *keystoreFlags) password(confirm bool) (string, error) {
+	if *f.passwordFile != "" {
+		return wallet.ReadPasswordFile(*f.passwordFile)
+	}
+
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return "", errors.New("-password-file is required when stdin is not a terminal")
+	}
+	fmt.Fprint(os.Stderr, "Password: ")
+	password, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", err
+	}
+	if confirm {
+		fmt.Fprint(os.Stderr, "Repeat password: ")
+		repeated, err := term.ReadPassword(fd)
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			return "", err
+		}
+		if string(repeated) != string(password) {
+			return "", errors.New("passwords do not match")
+		}
+	}
+	return string(password), nil
+}
+
+// newKey 生成一个新的私钥并加密保存到keystore.
+func newKey(args []string) {
+	fs := flag.NewFlagSet("new", flag.ExitOnError)
+	ksFlags := addKeystoreFlags(fs)
+	fs.Parse(args)
+
+	password, err := ksFlags.password(true)
+	if err != nil {
+		log.Fatal(err)
+	}
+	account, err := ksFlags.keystore().NewAccount(password)
+	if err != nil {
+		log.Fatalf("failed to create key: %v", err)
+	}
+	log.Printf("address: %s\n", account.Address)
+	log.Printf("file   : %s\n", account.Path)
+}
+
+// importKey 把已有的十六进制私钥加密保存到keystore.
+func importKey(args []string) {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	ksFlags := addKeystoreFlags(fs)
+	keyFile := fs.String("keyfile", "", "file containing the hex private key to import")
+	fs.Parse(args)
+
+	if *keyFile == "" {
+		log.Fatal("-keyfile is required")
+	}
+	// 从文件读取私钥, 避免私钥出现在命令行中
+	privateKey, err := wallet.ReadPasswordFile(*keyFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if _, err = hex.DecodeString(privateKey); err != nil {
+		log.Fatalf("invalid private key: %v", err)
+	}
+
+	password, err := ksFlags.password(true)
+	if err != nil {
+		log.Fatal(err)
+	}
+	account, err := ksFlags.keystore().Import(privateKey, password)
+	if err != nil {
+		log.Fatalf("failed to import key: %v", err)
+	}
+	log.Printf("address: %s\n", account.Address)
+	log.Printf("file   : %s\n", account.Path)
+}
+
+// exportKey 解密keystore中的私钥.
+func exportKey(args []string) {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	ksFlags := addKeystoreFlags(fs)
+	address := fs.String("address", "", "address of the key to export")
+	fs.Parse(args)
+
+	password, err := ksFlags.password(false)
+	if err != nil {
+		log.Fatal(err)
+	}
+	privateKey, err := ksFlags.keystore().Unlock(*address, password)
+	if err != nil {
+		log.Fatalf("failed to unlock %s: %v", *address, err)
+	}
+	publicKey, _, err := wallet.GetPublicKey(privateKey)
+	if err != nil {
+		log.Fatal(err)
+	}
+	wif, _ := wallet.PrivateKey2Wif(privateKey)
+	log.Printf("private key: %s\n", privateKey)
+	log.Printf("wif        : %s\n", wif)
+	log.Printf("public  key: %s\n", publicKey)
+}
+
+// listKeys 列出keystore中的地址.
+func listKeys(args []string) {
+	fs := flag.NewFlagSet("list", flag.ExitOnError)
+	ksFlags := addKeystoreFlags(fs)
+	fs.Parse(args)
+
+	accounts, err := ksFlags.keystore().Accounts()
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, account := range accounts {
+		log.Printf("%s %s\n", account.Address, account.Path)
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"flag"
+	"log"
+
+	"github.com/smallnest/blockchain"
+)
+
+// signMessage 使用keystore中的私钥对消息签名, 验证者只需要地址就可以校验签名.
+func signMessage(args []string) {
+	fs := flag.NewFlagSet("sign", flag.ExitOnError)
+	ksFlags := addKeystoreFlags(fs)
+	var (
+		address = fs.String("address", "", "address of the key to sign with")
+		message = fs.String("message", "", "message to sign")
+	)
+	fs.Parse(args)
+
+	password, err := ksFlags.password(false)
+	if err != nil {
+		log.Fatal(err)
+	}
+	privateKey, err := ksFlags.keystore().Unlock(*address, password)
+	if err != nil {
+		log.Fatalf("failed to unlock %s: %v", *address, err)
+	}
+	// keystore中的地址使用未压缩的公钥
+	signature, err := blockchain.SignMessage(privateKey, *message, false)
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("address  : %s\n", *address)
+	log.Printf("signature: %s\n", signature)
+}
+
+// verifyMessage 校验消息的签名是否由地址对应的私钥生成, 签名无效时以非0状态退出.
+func verifyMessage(args []string) {
+	fs := flag.NewFlagSet("verify", flag.ExitOnError)
+	var (
+		address   = fs.String("address", "", "P2PKH or P2WPKH address of the signer")
+		message   = fs.String("message", "", "signed message")
+		signature = fs.String("signature", "", "base64 encoded signature created by the sign command")
+	)
+	fs.Parse(args)
+
+	result, err := blockchain.VerifyMessage(*address, *signature, *message)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if !result.Valid {
+		log.Fatalf("signature is not signed by %s", *address)
+	}
+	log.Printf("signature is valid\n")
+	log.Printf("public key: %s\n", result.PublicKey)
+}
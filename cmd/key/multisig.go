@@ -0,0 +1,39 @@
+package main
+
+import (
+	"encoding/hex"
+	"flag"
+	"log"
+	"strings"
+
+	"github.com/smallnest/blockchain"
+)
+
+// multisig 根据M个签名和N个公钥生成多重签名的赎回脚本和P2SH地址.
+func multisig(args []string) {
+	fs := flag.NewFlagSet("multisig", flag.ExitOnError)
+	var (
+		m    = fs.Int("m", 2, "number of signatures required")
+		keys = fs.String("keys", "", "comma separated hex encoded public keys")
+	)
+	fs.Parse(args)
+
+	var publicKeys []string
+	for _, key := range strings.Split(*keys, ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			publicKeys = append(publicKeys, key)
+		}
+	}
+	ms, err := blockchain.NewMultisig(*m, publicKeys)
+	if err != nil {
+		log.Fatal(err)
+	}
+	address, err := ms.Address()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	log.Printf("multisig     : %d-of-%d\n", ms.M, len(ms.PublicKeys))
+	log.Printf("redeem script: %s\n", hex.EncodeToString(ms.RedeemScript()))
+	log.Printf("address      : %s\n", address)
+}
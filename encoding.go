@@ -0,0 +1,143 @@
+package blockchain
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// BlockFormatVersion 是存储区块时使用的最新的编码版本.
+//
+// 每个存储的区块都是 [版本号(1字节)] + [该版本的编码] 的格式.
+// 版本0是最早的没有版本号的gencode编码, 只能根据Store记录的格式识别.
+// 修改Block的字段时需要增加一个新的版本, 并在blockDecoders中保留旧版本的解码器.
+// 版本2在gencode编码之后追加了区块的公钥和签名, 它们都以uvarint长度开头.
+const BlockFormatVersion = 2
+
+var (
+	// ErrUnknownVersion 区块的编码版本未知.
+	ErrUnknownVersion = errors.New("unknown block format version")
+	// ErrMalformedBlock 区块的编码不完整.
+	ErrMalformedBlock = errors.New("malformed block encoding")
+)
+
+// blockDecoders 是各个版本的区块解码器, 输入不含版本号.
+var blockDecoders = map[byte]func(data []byte) (*Block, error){
+	0: decodeBlockV1,
+	1: decodeBlockV1,
+	2: decodeBlockV2,
+}
+
+// EncodeBlock 使用最新的版本编码区块.
+func EncodeBlock(block *Block) ([]byte, error) {
+	size := 1 + block.Size()
+	buf := make([]byte, size, size+2*binary.MaxVarintLen64+uint64(len(block.PublicKey)+len(block.Signature)))
+	buf[0] = BlockFormatVersion
+	if _, err := block.Marshal(buf[1:]); err != nil {
+		return nil, err
+	}
+	buf = appendField(buf, []byte(block.PublicKey))
+	buf = appendField(buf, block.Signature)
+	return buf, nil
+}
+
+// DecodeBlock 根据数据中的版本号解码区块.
+func DecodeBlock(data []byte) (*Block, error) {
+	if len(data) == 0 {
+		return nil, fmt.Errorf("%w: empty data", ErrUnknownVersion)
+	}
+	return DecodeBlockVersion(data[0], data[1:])
+}
+
+// DecodeBlockVersion 使用指定版本的解码器解码区块, data不含版本号.
+// 版本0的数据没有版本号, 需要通过这个函数解码.
+func DecodeBlockVersion(version byte, data []byte) (*Block, error) {
+	decode, ok := blockDecoders[version]
+	if !ok {
+		return nil, fmt.Errorf("%w: %d", ErrUnknownVersion, version)
+	}
+	return decode(data)
+}
+
+// decodeBlockV1 解码gencode编码的区块, 版本0和版本1的区块结构相同, 没有签名.
+func decodeBlockV1(data []byte) (*Block, error) {
+	block, _, err := unmarshalBlock(data)
+	return block, err
+}
+
+// decodeBlockV2 解码gencode编码的区块以及之后的公钥和签名.
+func decodeBlockV2(data []byte) (*Block, error) {
+	block, data, err := unmarshalBlock(data)
+	if err != nil {
+		return nil, err
+	}
+
+	publicKey, data, err := readField(data)
+	if err != nil {
+		return nil, err
+	}
+	if block.Signature, _, err = readField(data); err != nil {
+		return nil, err
+	}
+	block.PublicKey = string(publicKey)
+	return block, nil
+}
+
+// unmarshalBlock 解码gencode编码的区块, 返回区块和之后剩余的数据.
+// 生成的Unmarshal不检查边界, 所以先按照block.schema中字段的顺序检查数据是否完整,
+// 损坏的数据返回ErrMalformedBlock而不是panic.
+func unmarshalBlock(data []byte) (*Block, []byte, error) {
+	rest, err := skipFixed(data, 16) // Height, Timestamp
+	if err == nil {
+		rest, err = skipField(rest) // Hash
+	}
+	if err == nil {
+		rest, err = skipField(rest) // PrevHash
+	}
+	if err == nil {
+		rest, err = skipFixed(rest, 8) // Difficulty, Nonce
+	}
+	if err == nil {
+		rest, err = skipField(rest) // Data
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var block = &Block{}
+	n, err := block.Unmarshal(data[:len(data)-len(rest)])
+	if err != nil {
+		return nil, nil, err
+	}
+	return block, data[n:], nil
+}
+
+func skipFixed(data []byte, n int) ([]byte, error) {
+	if len(data) < n {
+		return nil, ErrMalformedBlock
+	}
+	return data[n:], nil
+}
+
+func skipField(data []byte) ([]byte, error) {
+	_, rest, err := readField(data)
+	return rest, err
+}
+
+func appendField(buf, field []byte) []byte {
+	buf = binary.AppendUvarint(buf, uint64(len(field)))
+	return append(buf, field...)
+}
+
+// readField 读取以uvarint长度开头的字段, 返回字段和剩余的数据. 空的字段返回nil.
+func readField(data []byte) (field, rest []byte, err error) {
+	l, n := binary.Uvarint(data)
+	if n <= 0 || l > uint64(len(data)-n) {
+		return nil, nil, ErrMalformedBlock
+	}
+	data = data[n:]
+	if l == 0 {
+		return nil, data, nil
+	}
+	return data[:l:l], data[l:], nil
+}
@@ -0,0 +1,198 @@
+package blockchain
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// 导出文件的格式.
+const (
+	// FormatBinary 是带长度前缀的区块编码格式, 每个区块按照EncodeBlock编码.
+	FormatBinary = "binary"
+	// FormatJSON 是每行一个区块的JSON格式.
+	FormatJSON = "json"
+)
+
+// maxExportedBlockSize 是导入时单个区块的最大长度, 防止损坏的文件耗尽内存.
+const maxExportedBlockSize = 64 << 20
+
+// exportMagic 是二进制导出文件的文件头.
+// 版本1的文件中每个区块是没有版本号的gencode编码, 版本2的文件中每个区块带有编码版本号.
+var (
+	exportMagic   = []byte("BCEX\x02")
+	exportMagicV1 = []byte("BCEX\x01")
+)
+
+var (
+	// ErrInvalidBlock 区块不合法.
+	ErrInvalidBlock = errors.New("invalid block")
+	// ErrUnknownFormat 未知的导出格式.
+	ErrUnknownFormat = errors.New("unknown export format")
+)
+
+// ExportBlocks 把store中的所有区块按照指定的格式写入w, 返回导出的区块数.
+// 每导出一个区块都会调用progress, progress可以为nil.
+func ExportBlocks(w io.Writer, s Store, format string, progress func(n uint64)) (uint64, error) {
+	bw := bufio.NewWriter(w)
+
+	var writeBlock func(block *Block) error
+	switch format {
+	case FormatBinary:
+		if _, err := bw.Write(exportMagic); err != nil {
+			return 0, err
+		}
+		var lenBuf [binary.MaxVarintLen64]byte
+		writeBlock = func(block *Block) error {
+			buf, err := EncodeBlock(block)
+			if err != nil {
+				return err
+			}
+			n := binary.PutUvarint(lenBuf[:], uint64(len(buf)))
+			if _, err = bw.Write(lenBuf[:n]); err != nil {
+				return err
+			}
+			_, err = bw.Write(buf)
+			return err
+		}
+	case FormatJSON:
+		enc := json.NewEncoder(bw)
+		writeBlock = func(block *Block) error {
+			return enc.Encode(block)
+		}
+	default:
+		return 0, ErrUnknownFormat
+	}
+
+	var n uint64
+	for {
+		block, err := s.Get(n)
+		if err == ErrNotFound {
+			break
+		}
+		if err != nil {
+			return n, err
+		}
+		if err = writeBlock(block); err != nil {
+			return n, err
+		}
+		n++
+		if progress != nil {
+			progress(n)
+		}
+	}
+
+	return n, bw.Flush()
+}
+
+// ImportBlocks 从r中读取导出的区块, 校验之后加入到区块链中, 返回新增的区块数.
+// 已经存在的区块必须和区块链中的一致, 所以可以重复导入同一个文件以继续中断的导入.
+// 每读取一个区块都会调用progress, progress可以为nil.
+func (bc *Blockchain) ImportBlocks(r io.Reader, format string, progress func(n uint64)) (uint64, error) {
+	br := bufio.NewReader(r)
+
+	var readBlock func() (*Block, error)
+	switch format {
+	case FormatBinary:
+		magic := make([]byte, len(exportMagic))
+		if _, err := io.ReadFull(br, magic); err != nil {
+			return 0, err
+		}
+		decode := DecodeBlock
+		switch {
+		case bytes.Equal(magic, exportMagic):
+		case bytes.Equal(magic, exportMagicV1):
+			decode = decodeBlockV1
+		default:
+			return 0, fmt.Errorf("%w: bad file header", ErrUnknownFormat)
+		}
+		readBlock = func() (*Block, error) {
+			size, err := binary.ReadUvarint(br)
+			if err != nil {
+				return nil, err
+			}
+			if size > maxExportedBlockSize {
+				return nil, fmt.Errorf("%w: block too large (%d bytes)", ErrInvalidBlock, size)
+			}
+			buf := make([]byte, size)
+			if _, err = io.ReadFull(br, buf); err != nil {
+				return nil, noEOF(err)
+			}
+			return decode(buf)
+		}
+	case FormatJSON:
+		dec := json.NewDecoder(br)
+		readBlock = func() (*Block, error) {
+			var block = &Block{}
+			err := dec.Decode(block)
+			return block, err
+		}
+	default:
+		return 0, ErrUnknownFormat
+	}
+
+	bc.Lock()
+	defer bc.Unlock()
+
+	var read, added uint64
+	for {
+		block, err := readBlock()
+		if err == io.EOF {
+			return added, nil
+		}
+		if err != nil {
+			return added, err
+		}
+		read++
+		if progress != nil {
+			progress(read)
+		}
+
+		if block.Height < uint64(len(bc.Blocks)) {
+			if bc.Blocks[block.Height].Hash != block.Hash {
+				return added, fmt.Errorf("%w: block %d conflicts with the local chain", ErrInvalidBlock, block.Height)
+			}
+			continue
+		}
+
+		if err = bc.validateImported(block); err != nil {
+			return added, err
+		}
+		bc.retarget(block.Height)
+		if err = bc.AddBlock(block); err != nil {
+			return added, err
+		}
+		added++
+	}
+}
+
+// validateImported 校验导入的区块是否能连接到当前区块链的末尾.
+func (bc *Blockchain) validateImported(block *Block) error {
+	if len(bc.Blocks) == 0 {
+		if block.Height != 0 || block.PrevHash != "" {
+			return fmt.Errorf("%w: block %d is not a genesis block", ErrInvalidBlock, block.Height)
+		}
+		return bc.checkGenesis(block)
+	}
+
+	prevBlock := bc.Blocks[len(bc.Blocks)-1]
+	if !validateBlock(block, prevBlock) {
+		return fmt.Errorf("%w: block %d does not follow block %d", ErrInvalidBlock, block.Height, prevBlock.Height)
+	}
+	// 哈希不包含区块中的Difficulty, 它可以被任意修改, 只能按照本地区块链的难度校验工作量
+	if block.Difficulty != bc.Difficulty || !validateHash(block.Hash, bc.PrefixZero) {
+		return fmt.Errorf("%w: block %d does not meet difficulty %d", ErrInvalidBlock, block.Height, bc.Difficulty)
+	}
+	return nil
+}
+
+func noEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
@@ -0,0 +1,132 @@
+package blockchain
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestExportImport(t *testing.T) {
+	src := newTestBlockchain(t, 10)
+
+	for _, format := range []string{FormatBinary, FormatJSON} {
+		var buf bytes.Buffer
+		n, err := ExportBlocks(&buf, src.Store, format, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != 11 {
+			t.Fatalf("expect 11 exported blocks but got %d", n)
+		}
+		exported := buf.Bytes()
+
+		dst := &Blockchain{Store: newMemStore(), Difficulty: 1, PrefixZero: "0"}
+		n, err = dst.ImportBlocks(bytes.NewReader(exported), format, nil)
+		if err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		if n != 11 || dst.Blocks[10].Hash != src.Blocks[10].Hash {
+			t.Fatalf("%s: imported %d blocks", format, n)
+		}
+
+		// 重复导入不会增加区块
+		n, err = dst.ImportBlocks(bytes.NewReader(exported), format, nil)
+		if err != nil || n != 0 {
+			t.Fatalf("%s: reimported %d blocks: %v", format, n, err)
+		}
+	}
+}
+
+func TestImportTamperedBlock(t *testing.T) {
+	src := newTestBlockchain(t, 3)
+	src.Blocks[2].Data = []byte("tampered")
+
+	var buf bytes.Buffer
+	if _, err := ExportBlocks(&buf, src.Store, FormatBinary, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	dst := &Blockchain{Store: newMemStore(), Difficulty: 1, PrefixZero: "0"}
+	n, err := dst.ImportBlocks(&buf, FormatBinary, nil)
+	if !errors.Is(err, ErrInvalidBlock) {
+		t.Fatalf("expect ErrInvalidBlock but got %v", err)
+	}
+	if n != 2 {
+		t.Fatalf("expect 2 valid blocks but got %d", n)
+	}
+}
+
+// 区块中的Difficulty不在哈希之内, 不能用它校验工作量.
+func TestImportForgedDifficulty(t *testing.T) {
+	src := &Blockchain{Store: newMemStore()}
+	if err := src.GenerateGenesisBlock(); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; len(src.Blocks) < 3; i++ {
+		// 难度为0时任何哈希都可以, 只保留不满足难度1的区块
+		block := src.generateBlock(src.Blocks[len(src.Blocks)-1], []byte{byte(i)})
+		if block.Hash[0] == '0' {
+			continue
+		}
+		if err := src.AddBlock(block); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, forged := range []uint32{0, 1} {
+		var buf bytes.Buffer
+		for _, block := range src.Blocks {
+			block.Difficulty = forged
+		}
+		if _, err := ExportBlocks(&buf, src.Store, FormatJSON, nil); err != nil {
+			t.Fatal(err)
+		}
+		dst := &Blockchain{Store: newMemStore(), Difficulty: 1, PrefixZero: "0"}
+		n, err := dst.ImportBlocks(&buf, FormatJSON, nil)
+		if !errors.Is(err, ErrInvalidBlock) || n != 1 {
+			t.Errorf("difficulty %d: expect ErrInvalidBlock after the genesis block but got %d, %v", forged, n, err)
+		}
+	}
+}
+
+// 截断或者损坏的导出文件返回错误而不是panic.
+func TestImportTruncated(t *testing.T) {
+	dst := &Blockchain{Store: newMemStore(), Difficulty: 1, PrefixZero: "0"}
+	corrupt := append(append([]byte(nil), exportMagic...), 0x02, 0x01, 0x02)
+	if _, err := dst.ImportBlocks(bytes.NewReader(corrupt), FormatBinary, nil); !errors.Is(err, ErrMalformedBlock) {
+		t.Fatalf("expect ErrMalformedBlock but got %v", err)
+	}
+
+	src := newTestBlockchain(t, 3)
+	var buf bytes.Buffer
+	if _, err := ExportBlocks(&buf, src.Store, FormatBinary, nil); err != nil {
+		t.Fatal(err)
+	}
+	exported := buf.Bytes()
+	for i := len(exportMagic); i < len(exported); i++ {
+		dst := &Blockchain{Store: newMemStore(), Difficulty: 1, PrefixZero: "0"}
+		_, err := dst.ImportBlocks(bytes.NewReader(exported[:i]), FormatBinary, nil)
+		if err != nil && !errors.Is(err, ErrMalformedBlock) && !errors.Is(err, io.ErrUnexpectedEOF) {
+			t.Fatalf("%d bytes: unexpected error %v", i, err)
+		}
+	}
+
+	// 每个版本的解码器都拒绝不完整的区块
+	block := &Block{Height: 1, Hash: "hash", PrevHash: "prev", Data: []byte("data"), PublicKey: "key", Signature: []byte{2, 1}}
+	data, err := EncodeBlock(block)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i < len(data); i++ {
+		if _, err = DecodeBlock(data[:i]); !errors.Is(err, ErrMalformedBlock) {
+			t.Fatalf("v2 block of %d bytes: expect ErrMalformedBlock but got %v", i, err)
+		}
+	}
+	v1 := data[1 : 1+block.Size()]
+	for i := 0; i < len(v1); i++ {
+		if _, err = DecodeBlockVersion(1, v1[:i]); !errors.Is(err, ErrMalformedBlock) {
+			t.Fatalf("v1 block of %d bytes: expect ErrMalformedBlock but got %v", i, err)
+		}
+	}
+}
@@ -0,0 +1,573 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.6
+// 	protoc        (unknown)
+// source: blockchain.proto
+
+package grpcapi
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// Block 代表区块链中的一块. signature是出块节点public_key对hash的签名,
+// 以签名算法的标记开头, 没有签名的区块这两个字段为空.
+type Block struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Height        uint64                 `protobuf:"varint,1,opt,name=height,proto3" json:"height,omitempty"`
+	Timestamp     int64                  `protobuf:"varint,2,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	Hash          string                 `protobuf:"bytes,3,opt,name=hash,proto3" json:"hash,omitempty"`
+	PrevHash      string                 `protobuf:"bytes,4,opt,name=prev_hash,json=prevHash,proto3" json:"prev_hash,omitempty"`
+	Difficulty    uint32                 `protobuf:"varint,5,opt,name=difficulty,proto3" json:"difficulty,omitempty"`
+	Nonce         uint32                 `protobuf:"varint,6,opt,name=nonce,proto3" json:"nonce,omitempty"`
+	Data          []byte                 `protobuf:"bytes,7,opt,name=data,proto3" json:"data,omitempty"`
+	PublicKey     string                 `protobuf:"bytes,8,opt,name=public_key,json=publicKey,proto3" json:"public_key,omitempty"`
+	Signature     []byte                 `protobuf:"bytes,9,opt,name=signature,proto3" json:"signature,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Block) Reset() {
+	*x = Block{}
+	mi := &file_blockchain_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Block) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Block) ProtoMessage() {}
+
+func (x *Block) ProtoReflect() protoreflect.Message {
+	mi := &file_blockchain_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Block.ProtoReflect.Descriptor instead.
+func (*Block) Descriptor() ([]byte, []int) {
+	return file_blockchain_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Block) GetHeight() uint64 {
+	if x != nil {
+		return x.Height
+	}
+	return 0
+}
+
+func (x *Block) GetTimestamp() int64 {
+	if x != nil {
+		return x.Timestamp
+	}
+	return 0
+}
+
+func (x *Block) GetHash() string {
+	if x != nil {
+		return x.Hash
+	}
+	return ""
+}
+
+func (x *Block) GetPrevHash() string {
+	if x != nil {
+		return x.PrevHash
+	}
+	return ""
+}
+
+func (x *Block) GetDifficulty() uint32 {
+	if x != nil {
+		return x.Difficulty
+	}
+	return 0
+}
+
+func (x *Block) GetNonce() uint32 {
+	if x != nil {
+		return x.Nonce
+	}
+	return 0
+}
+
+func (x *Block) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+func (x *Block) GetPublicKey() string {
+	if x != nil {
+		return x.PublicKey
+	}
+	return ""
+}
+
+func (x *Block) GetSignature() []byte {
+	if x != nil {
+		return x.Signature
+	}
+	return nil
+}
+
+// GetBlockRequest 按照高度或者哈希查询区块.
+type GetBlockRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to Selector:
+	//
+	//	*GetBlockRequest_Height
+	//	*GetBlockRequest_Hash
+	Selector      isGetBlockRequest_Selector `protobuf_oneof:"selector"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetBlockRequest) Reset() {
+	*x = GetBlockRequest{}
+	mi := &file_blockchain_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetBlockRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetBlockRequest) ProtoMessage() {}
+
+func (x *GetBlockRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_blockchain_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetBlockRequest.ProtoReflect.Descriptor instead.
+func (*GetBlockRequest) Descriptor() ([]byte, []int) {
+	return file_blockchain_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *GetBlockRequest) GetSelector() isGetBlockRequest_Selector {
+	if x != nil {
+		return x.Selector
+	}
+	return nil
+}
+
+func (x *GetBlockRequest) GetHeight() uint64 {
+	if x != nil {
+		if x, ok := x.Selector.(*GetBlockRequest_Height); ok {
+			return x.Height
+		}
+	}
+	return 0
+}
+
+func (x *GetBlockRequest) GetHash() string {
+	if x != nil {
+		if x, ok := x.Selector.(*GetBlockRequest_Hash); ok {
+			return x.Hash
+		}
+	}
+	return ""
+}
+
+type isGetBlockRequest_Selector interface {
+	isGetBlockRequest_Selector()
+}
+
+type GetBlockRequest_Height struct {
+	Height uint64 `protobuf:"varint,1,opt,name=height,proto3,oneof"`
+}
+
+type GetBlockRequest_Hash struct {
+	Hash string `protobuf:"bytes,2,opt,name=hash,proto3,oneof"`
+}
+
+func (*GetBlockRequest_Height) isGetBlockRequest_Selector() {}
+
+func (*GetBlockRequest_Hash) isGetBlockRequest_Selector() {}
+
+type GetTipRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetTipRequest) Reset() {
+	*x = GetTipRequest{}
+	mi := &file_blockchain_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetTipRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetTipRequest) ProtoMessage() {}
+
+func (x *GetTipRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_blockchain_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetTipRequest.ProtoReflect.Descriptor instead.
+func (*GetTipRequest) Descriptor() ([]byte, []int) {
+	return file_blockchain_proto_rawDescGZIP(), []int{2}
+}
+
+// SubmitDataRequest 写入数据. 服务器要求签名时, public_key是十六进制编码的公钥,
+// signature是该公钥对blockchain.WriteMessage(timestamp, nonce, data)的签名,
+// timestamp是签名时的unix时间(秒), nonce是十六进制编码的随机数, 用于防止请求被重放.
+type SubmitDataRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Data          []byte                 `protobuf:"bytes,1,opt,name=data,proto3" json:"data,omitempty"`
+	PublicKey     string                 `protobuf:"bytes,2,opt,name=public_key,json=publicKey,proto3" json:"public_key,omitempty"`
+	Signature     []byte                 `protobuf:"bytes,3,opt,name=signature,proto3" json:"signature,omitempty"`
+	Timestamp     int64                  `protobuf:"varint,4,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	Nonce         string                 `protobuf:"bytes,5,opt,name=nonce,proto3" json:"nonce,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SubmitDataRequest) Reset() {
+	*x = SubmitDataRequest{}
+	mi := &file_blockchain_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SubmitDataRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SubmitDataRequest) ProtoMessage() {}
+
+func (x *SubmitDataRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_blockchain_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SubmitDataRequest.ProtoReflect.Descriptor instead.
+func (*SubmitDataRequest) Descriptor() ([]byte, []int) {
+	return file_blockchain_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *SubmitDataRequest) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+func (x *SubmitDataRequest) GetPublicKey() string {
+	if x != nil {
+		return x.PublicKey
+	}
+	return ""
+}
+
+func (x *SubmitDataRequest) GetSignature() []byte {
+	if x != nil {
+		return x.Signature
+	}
+	return nil
+}
+
+func (x *SubmitDataRequest) GetTimestamp() int64 {
+	if x != nil {
+		return x.Timestamp
+	}
+	return 0
+}
+
+func (x *SubmitDataRequest) GetNonce() string {
+	if x != nil {
+		return x.Nonce
+	}
+	return ""
+}
+
+type GetDifficultyRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetDifficultyRequest) Reset() {
+	*x = GetDifficultyRequest{}
+	mi := &file_blockchain_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetDifficultyRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetDifficultyRequest) ProtoMessage() {}
+
+func (x *GetDifficultyRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_blockchain_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetDifficultyRequest.ProtoReflect.Descriptor instead.
+func (*GetDifficultyRequest) Descriptor() ([]byte, []int) {
+	return file_blockchain_proto_rawDescGZIP(), []int{4}
+}
+
+type GetDifficultyResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Difficulty    uint32                 `protobuf:"varint,1,opt,name=difficulty,proto3" json:"difficulty,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetDifficultyResponse) Reset() {
+	*x = GetDifficultyResponse{}
+	mi := &file_blockchain_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetDifficultyResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetDifficultyResponse) ProtoMessage() {}
+
+func (x *GetDifficultyResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_blockchain_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetDifficultyResponse.ProtoReflect.Descriptor instead.
+func (*GetDifficultyResponse) Descriptor() ([]byte, []int) {
+	return file_blockchain_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *GetDifficultyResponse) GetDifficulty() uint32 {
+	if x != nil {
+		return x.Difficulty
+	}
+	return 0
+}
+
+// SubscribeRequest 订阅新的区块, 设置了from_height时先推送从该高度开始已有的区块.
+type SubscribeRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	FromHeight    *uint64                `protobuf:"varint,1,opt,name=from_height,json=fromHeight,proto3,oneof" json:"from_height,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SubscribeRequest) Reset() {
+	*x = SubscribeRequest{}
+	mi := &file_blockchain_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SubscribeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SubscribeRequest) ProtoMessage() {}
+
+func (x *SubscribeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_blockchain_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SubscribeRequest.ProtoReflect.Descriptor instead.
+func (*SubscribeRequest) Descriptor() ([]byte, []int) {
+	return file_blockchain_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *SubscribeRequest) GetFromHeight() uint64 {
+	if x != nil && x.FromHeight != nil {
+		return *x.FromHeight
+	}
+	return 0
+}
+
+var File_blockchain_proto protoreflect.FileDescriptor
+
+const file_blockchain_proto_rawDesc = "" +
+	"\n" +
+	"\x10blockchain.proto\x12\rblockchain.v1\"\xf5\x01\n" +
+	"\x05Block\x12\x16\n" +
+	"\x06height\x18\x01 \x01(\x04R\x06height\x12\x1c\n" +
+	"\ttimestamp\x18\x02 \x01(\x03R\ttimestamp\x12\x12\n" +
+	"\x04hash\x18\x03 \x01(\tR\x04hash\x12\x1b\n" +
+	"\tprev_hash\x18\x04 \x01(\tR\bprevHash\x12\x1e\n" +
+	"\n" +
+	"difficulty\x18\x05 \x01(\rR\n" +
+	"difficulty\x12\x14\n" +
+	"\x05nonce\x18\x06 \x01(\rR\x05nonce\x12\x12\n" +
+	"\x04data\x18\a \x01(\fR\x04data\x12\x1d\n" +
+	"\n" +
+	"public_key\x18\b \x01(\tR\tpublicKey\x12\x1c\n" +
+	"\tsignature\x18\t \x01(\fR\tsignature\"M\n" +
+	"\x0fGetBlockRequest\x12\x18\n" +
+	"\x06height\x18\x01 \x01(\x04H\x00R\x06height\x12\x14\n" +
+	"\x04hash\x18\x02 \x01(\tH\x00R\x04hashB\n" +
+	"\n" +
+	"\bselector\"\x0f\n" +
+	"\rGetTipRequest\"\x98\x01\n" +
+	"\x11SubmitDataRequest\x12\x12\n" +
+	"\x04data\x18\x01 \x01(\fR\x04data\x12\x1d\n" +
+	"\n" +
+	"public_key\x18\x02 \x01(\tR\tpublicKey\x12\x1c\n" +
+	"\tsignature\x18\x03 \x01(\fR\tsignature\x12\x1c\n" +
+	"\ttimestamp\x18\x04 \x01(\x03R\ttimestamp\x12\x14\n" +
+	"\x05nonce\x18\x05 \x01(\tR\x05nonce\"\x16\n" +
+	"\x14GetDifficultyRequest\"7\n" +
+	"\x15GetDifficultyResponse\x12\x1e\n" +
+	"\n" +
+	"difficulty\x18\x01 \x01(\rR\n" +
+	"difficulty\"H\n" +
+	"\x10SubscribeRequest\x12$\n" +
+	"\vfrom_height\x18\x01 \x01(\x04H\x00R\n" +
+	"fromHeight\x88\x01\x01B\x0e\n" +
+	"\f_from_height2\xf4\x02\n" +
+	"\n" +
+	"Blockchain\x12@\n" +
+	"\bGetBlock\x12\x1e.blockchain.v1.GetBlockRequest\x1a\x14.blockchain.v1.Block\x12<\n" +
+	"\x06GetTip\x12\x1c.blockchain.v1.GetTipRequest\x1a\x14.blockchain.v1.Block\x12D\n" +
+	"\n" +
+	"SubmitData\x12 .blockchain.v1.SubmitDataRequest\x1a\x14.blockchain.v1.Block\x12Z\n" +
+	"\rGetDifficulty\x12#.blockchain.v1.GetDifficultyRequest\x1a$.blockchain.v1.GetDifficultyResponse\x12D\n" +
+	"\tSubscribe\x12\x1f.blockchain.v1.SubscribeRequest\x1a\x14.blockchain.v1.Block0\x01B1Z/github.com/smallnest/blockchain/grpcapi;grpcapib\x06proto3"
+
+var (
+	file_blockchain_proto_rawDescOnce sync.Once
+	file_blockchain_proto_rawDescData []byte
+)
+
+func file_blockchain_proto_rawDescGZIP() []byte {
+	file_blockchain_proto_rawDescOnce.Do(func() {
+		file_blockchain_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_blockchain_proto_rawDesc), len(file_blockchain_proto_rawDesc)))
+	})
+	return file_blockchain_proto_rawDescData
+}
+
+var file_blockchain_proto_msgTypes = make([]protoimpl.MessageInfo, 7)
+var file_blockchain_proto_goTypes = []any{
+	(*Block)(nil),                 // 0: blockchain.v1.Block
+	(*GetBlockRequest)(nil),       // 1: blockchain.v1.GetBlockRequest
+	(*GetTipRequest)(nil),         // 2: blockchain.v1.GetTipRequest
+	(*SubmitDataRequest)(nil),     // 3: blockchain.v1.SubmitDataRequest
+	(*GetDifficultyRequest)(nil),  // 4: blockchain.v1.GetDifficultyRequest
+	(*GetDifficultyResponse)(nil), // 5: blockchain.v1.GetDifficultyResponse
+	(*SubscribeRequest)(nil),      // 6: blockchain.v1.SubscribeRequest
+}
+var file_blockchain_proto_depIdxs = []int32{
+	1, // 0: blockchain.v1.Blockchain.GetBlock:input_type -> blockchain.v1.GetBlockRequest
+	2, // 1: blockchain.v1.Blockchain.GetTip:input_type -> blockchain.v1.GetTipRequest
+	3, // 2: blockchain.v1.Blockchain.SubmitData:input_type -> blockchain.v1.SubmitDataRequest
+	4, // 3: blockchain.v1.Blockchain.GetDifficulty:input_type -> blockchain.v1.GetDifficultyRequest
+	6, // 4: blockchain.v1.Blockchain.Subscribe:input_type -> blockchain.v1.SubscribeRequest
+	0, // 5: blockchain.v1.Blockchain.GetBlock:output_type -> blockchain.v1.Block
+	0, // 6: blockchain.v1.Blockchain.GetTip:output_type -> blockchain.v1.Block
+	0, // 7: blockchain.v1.Blockchain.SubmitData:output_type -> blockchain.v1.Block
+	5, // 8: blockchain.v1.Blockchain.GetDifficulty:output_type -> blockchain.v1.GetDifficultyResponse
+	0, // 9: blockchain.v1.Blockchain.Subscribe:output_type -> blockchain.v1.Block
+	5, // [5:10] is the sub-list for method output_type
+	0, // [0:5] is the sub-list for method input_type
+	0, // [0:0] is the sub-list for extension type_name
+	0, // [0:0] is the sub-list for extension extendee
+	0, // [0:0] is the sub-list for field type_name
+}
+
+func init() { file_blockchain_proto_init() }
+func file_blockchain_proto_init() {
+	if File_blockchain_proto != nil {
+		return
+	}
+	file_blockchain_proto_msgTypes[1].OneofWrappers = []any{
+		(*GetBlockRequest_Height)(nil),
+		(*GetBlockRequest_Hash)(nil),
+	}
+	file_blockchain_proto_msgTypes[6].OneofWrappers = []any{}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_blockchain_proto_rawDesc), len(file_blockchain_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   7,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_blockchain_proto_goTypes,
+		DependencyIndexes: file_blockchain_proto_depIdxs,
+		MessageInfos:      file_blockchain_proto_msgTypes,
+	}.Build()
+	File_blockchain_proto = out.File
+	file_blockchain_proto_goTypes = nil
+	file_blockchain_proto_depIdxs = nil
+}
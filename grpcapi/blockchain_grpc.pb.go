@@ -0,0 +1,281 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.6.2
+// - protoc             (unknown)
+// source: blockchain.proto
+
+package grpcapi
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	Blockchain_GetBlock_FullMethodName      = "/blockchain.v1.Blockchain/GetBlock"
+	Blockchain_GetTip_FullMethodName        = "/blockchain.v1.Blockchain/GetTip"
+	Blockchain_SubmitData_FullMethodName    = "/blockchain.v1.Blockchain/SubmitData"
+	Blockchain_GetDifficulty_FullMethodName = "/blockchain.v1.Blockchain/GetDifficulty"
+	Blockchain_Subscribe_FullMethodName     = "/blockchain.v1.Blockchain/Subscribe"
+)
+
+// BlockchainClient is the client API for Blockchain service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// Blockchain 提供区块的查询、写入和订阅.
+type BlockchainClient interface {
+	GetBlock(ctx context.Context, in *GetBlockRequest, opts ...grpc.CallOption) (*Block, error)
+	GetTip(ctx context.Context, in *GetTipRequest, opts ...grpc.CallOption) (*Block, error)
+	SubmitData(ctx context.Context, in *SubmitDataRequest, opts ...grpc.CallOption) (*Block, error)
+	GetDifficulty(ctx context.Context, in *GetDifficultyRequest, opts ...grpc.CallOption) (*GetDifficultyResponse, error)
+	Subscribe(ctx context.Context, in *SubscribeRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Block], error)
+}
+
+type blockchainClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewBlockchainClient(cc grpc.ClientConnInterface) BlockchainClient {
+	return &blockchainClient{cc}
+}
+
+func (c *blockchainClient) GetBlock(ctx context.Context, in *GetBlockRequest, opts ...grpc.CallOption) (*Block, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Block)
+	err := c.cc.Invoke(ctx, Blockchain_GetBlock_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *blockchainClient) GetTip(ctx context.Context, in *GetTipRequest, opts ...grpc.CallOption) (*Block, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Block)
+	err := c.cc.Invoke(ctx, Blockchain_GetTip_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *blockchainClient) SubmitData(ctx context.Context, in *SubmitDataRequest, opts ...grpc.CallOption) (*Block, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Block)
+	err := c.cc.Invoke(ctx, Blockchain_SubmitData_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *blockchainClient) GetDifficulty(ctx context.Context, in *GetDifficultyRequest, opts ...grpc.CallOption) (*GetDifficultyResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetDifficultyResponse)
+	err := c.cc.Invoke(ctx, Blockchain_GetDifficulty_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *blockchainClient) Subscribe(ctx context.Context, in *SubscribeRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Block], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Blockchain_ServiceDesc.Streams[0], Blockchain_Subscribe_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[SubscribeRequest, Block]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Blockchain_SubscribeClient = grpc.ServerStreamingClient[Block]
+
+// BlockchainServer is the server API for Blockchain service.
+// All implementations must embed UnimplementedBlockchainServer
+// for forward compatibility.
+//
+// Blockchain 提供区块的查询、写入和订阅.
+type BlockchainServer interface {
+	GetBlock(context.Context, *GetBlockRequest) (*Block, error)
+	GetTip(context.Context, *GetTipRequest) (*Block, error)
+	SubmitData(context.Context, *SubmitDataRequest) (*Block, error)
+	GetDifficulty(context.Context, *GetDifficultyRequest) (*GetDifficultyResponse, error)
+	Subscribe(*SubscribeRequest, grpc.ServerStreamingServer[Block]) error
+	mustEmbedUnimplementedBlockchainServer()
+}
+
+// UnimplementedBlockchainServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedBlockchainServer struct{}
+
+func (UnimplementedBlockchainServer) GetBlock(context.Context, *GetBlockRequest) (*Block, error) {
+	return nil, status.Error(codes.Unimplemented, "method GetBlock not implemented")
+}
+func (UnimplementedBlockchainServer) GetTip(context.Context, *GetTipRequest) (*Block, error) {
+	return nil, status.Error(codes.Unimplemented, "method GetTip not implemented")
+}
+func (UnimplementedBlockchainServer) SubmitData(context.Context, *SubmitDataRequest) (*Block, error) {
+	return nil, status.Error(codes.Unimplemented, "method SubmitData not implemented")
+}
+func (UnimplementedBlockchainServer) GetDifficulty(context.Context, *GetDifficultyRequest) (*GetDifficultyResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method GetDifficulty not implemented")
+}
+func (UnimplementedBlockchainServer) Subscribe(*SubscribeRequest, grpc.ServerStreamingServer[Block]) error {
+	return status.Error(codes.Unimplemented, "method Subscribe not implemented")
+}
+func (UnimplementedBlockchainServer) mustEmbedUnimplementedBlockchainServer() {}
+func (UnimplementedBlockchainServer) testEmbeddedByValue()                    {}
+
+// UnsafeBlockchainServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to BlockchainServer will
+// result in compilation errors.
+type UnsafeBlockchainServer interface {
+	mustEmbedUnimplementedBlockchainServer()
+}
+
+func RegisterBlockchainServer(s grpc.ServiceRegistrar, srv BlockchainServer) {
+	// If the following call panics, it indicates UnimplementedBlockchainServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&Blockchain_ServiceDesc, srv)
+}
+
+func _Blockchain_GetBlock_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetBlockRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BlockchainServer).GetBlock(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Blockchain_GetBlock_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BlockchainServer).GetBlock(ctx, req.(*GetBlockRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Blockchain_GetTip_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetTipRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BlockchainServer).GetTip(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Blockchain_GetTip_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BlockchainServer).GetTip(ctx, req.(*GetTipRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Blockchain_SubmitData_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SubmitDataRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BlockchainServer).SubmitData(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Blockchain_SubmitData_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BlockchainServer).SubmitData(ctx, req.(*SubmitDataRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Blockchain_GetDifficulty_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetDifficultyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BlockchainServer).GetDifficulty(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Blockchain_GetDifficulty_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BlockchainServer).GetDifficulty(ctx, req.(*GetDifficultyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Blockchain_Subscribe_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(SubscribeRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(BlockchainServer).Subscribe(m, &grpc.GenericServerStream[SubscribeRequest, Block]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Blockchain_SubscribeServer = grpc.ServerStreamingServer[Block]
+
+// Blockchain_ServiceDesc is the grpc.ServiceDesc for Blockchain service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Blockchain_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "blockchain.v1.Blockchain",
+	HandlerType: (*BlockchainServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetBlock",
+			Handler:    _Blockchain_GetBlock_Handler,
+		},
+		{
+			MethodName: "GetTip",
+			Handler:    _Blockchain_GetTip_Handler,
+		},
+		{
+			MethodName: "SubmitData",
+			Handler:    _Blockchain_SubmitData_Handler,
+		},
+		{
+			MethodName: "GetDifficulty",
+			Handler:    _Blockchain_GetDifficulty_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Subscribe",
+			Handler:       _Blockchain_Subscribe_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "blockchain.proto",
+}
@@ -0,0 +1,120 @@
+package grpcapi
+
+import (
+	"context"
+	"encoding/hex"
+
+	"github.com/smallnest/blockchain"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// Client 是区块链gRPC服务的客户端, 返回的都是blockchain.Block.
+type Client struct {
+	conn *grpc.ClientConn
+	rpc  BlockchainClient
+}
+
+// Dial 连接gRPC服务. 没有指定DialOption时使用不加密的连接.
+func Dial(addr string, opts ...grpc.DialOption) (*Client, error) {
+	if len(opts) == 0 {
+		opts = []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}
+	}
+	conn, err := grpc.NewClient(addr, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &Client{
+		conn: conn,
+		rpc:  NewBlockchainClient(conn),
+	}, nil
+}
+
+// Close 关闭连接.
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+// GetBlock 查询指定高度的区块.
+func (c *Client) GetBlock(ctx context.Context, height uint64) (*blockchain.Block, error) {
+	block, err := c.rpc.GetBlock(ctx, &GetBlockRequest{Selector: &GetBlockRequest_Height{Height: height}})
+	if err != nil {
+		return nil, err
+	}
+	return block.ToBlock(), nil
+}
+
+// GetBlockByHash 查询指定哈希的区块.
+func (c *Client) GetBlockByHash(ctx context.Context, hash string) (*blockchain.Block, error) {
+	block, err := c.rpc.GetBlock(ctx, &GetBlockRequest{Selector: &GetBlockRequest_Hash{Hash: hash}})
+	if err != nil {
+		return nil, err
+	}
+	return block.ToBlock(), nil
+}
+
+// GetTip 查询最新的区块.
+func (c *Client) GetTip(ctx context.Context) (*blockchain.Block, error) {
+	block, err := c.rpc.GetTip(ctx, &GetTipRequest{})
+	if err != nil {
+		return nil, err
+	}
+	return block.ToBlock(), nil
+}
+
+// SubmitData 把数据写入一个新的区块.
+func (c *Client) SubmitData(ctx context.Context, data []byte) (*blockchain.Block, error) {
+	block, err := c.rpc.SubmitData(ctx, &SubmitDataRequest{Data: data})
+	if err != nil {
+		return nil, err
+	}
+	return block.ToBlock(), nil
+}
+
+// SubmitSignedData 使用私钥对数据签名后写入一个新的区块.
+func (c *Client) SubmitSignedData(ctx context.Context, data []byte, privateKey string) (*blockchain.Block, error) {
+	credentials, err := blockchain.SignCredentials(0, privateKey, data)
+	if err != nil {
+		return nil, err
+	}
+	signature, _ := hex.DecodeString(credentials.Signature)
+
+	block, err := c.rpc.SubmitData(ctx, &SubmitDataRequest{
+		Data:      data,
+		PublicKey: credentials.PublicKey,
+		Signature: signature,
+		Timestamp: credentials.Timestamp,
+		Nonce:     credentials.Nonce,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return block.ToBlock(), nil
+}
+
+// GetDifficulty 查询当前的难度系数.
+func (c *Client) GetDifficulty(ctx context.Context) (uint32, error) {
+	resp, err := c.rpc.GetDifficulty(ctx, &GetDifficultyRequest{})
+	if err != nil {
+		return 0, err
+	}
+	return resp.GetDifficulty(), nil
+}
+
+// Subscribe 订阅新的区块, from不为nil时先推送从该高度开始已有的区块.
+// 每个区块都会调用fn, fn返回错误或者ctx结束时停止订阅.
+func (c *Client) Subscribe(ctx context.Context, from *uint64, fn func(*blockchain.Block) error) error {
+	stream, err := c.rpc.Subscribe(ctx, &SubscribeRequest{FromHeight: from})
+	if err != nil {
+		return err
+	}
+	for {
+		block, err := stream.Recv()
+		if err != nil {
+			return err
+		}
+		if err = fn(block.ToBlock()); err != nil {
+			return err
+		}
+	}
+}
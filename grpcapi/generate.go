@@ -0,0 +1,6 @@
+// Package grpcapi 提供区块链的gRPC服务和客户端.
+//
+// blockchain.pb.go 和 blockchain_grpc.pb.go 由 blockchain.proto 生成, 不要手工修改.
+package grpcapi
+
+//go:generate buf generate
@@ -0,0 +1,246 @@
+package grpcapi
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/smallnest/blockchain"
+	"github.com/smallnest/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+var _ BlockchainServer = &Server{}
+
+// Server 基于gRPC提供区块链服务.
+type Server struct {
+	UnimplementedBlockchainServer
+
+	Addr       string
+	Blockchain *blockchain.Blockchain
+	// 允许写入区块的公钥, 为nil则不校验签名
+	Authorizer *blockchain.Authorizer
+	// 写入区块的限流, 为nil则不限流
+	RateLimiter *blockchain.RateLimiter
+	// 写入的数据的最大长度, 为0则不限制. gRPC本身还限制了消息的大小, 参见grpc.MaxRecvMsgSize
+	MaxDataSize int64
+	server      *grpc.Server
+
+	stopOnce sync.Once
+	quit     chan struct{} // 停止时通知推送的流退出
+}
+
+// NewServer 创建一个新的gRPC服务器.
+func NewServer(addr string, bc *blockchain.Blockchain, opts ...grpc.ServerOption) *Server {
+	s := &Server{
+		Addr:        addr,
+		Blockchain:  bc,
+		MaxDataSize: blockchain.DefaultMaxBodySize,
+		quit:        make(chan struct{}),
+	}
+	s.server = grpc.NewServer(opts...)
+	RegisterBlockchainServer(s.server, s)
+	return s
+}
+
+// Serve 开启gRPC服务.
+func (s *Server) Serve() error {
+	ln, err := net.Listen("tcp", s.Addr)
+	if err != nil {
+		return err
+	}
+	return s.server.Serve(ln)
+}
+
+// Stop 停止接收新的请求, 断开推送区块的流, 并等待正在处理的请求结束.
+func (s *Server) Stop() {
+	s.stopOnce.Do(func() { close(s.quit) })
+	s.server.GracefulStop()
+}
+
+// GetBlock 按照高度或者哈希查询区块.
+func (s *Server) GetBlock(ctx context.Context, req *GetBlockRequest) (*Block, error) {
+	var block *blockchain.Block
+	var err error
+	switch selector := req.Selector.(type) {
+	case *GetBlockRequest_Height:
+		block, err = s.Blockchain.GetBlock(selector.Height)
+	case *GetBlockRequest_Hash:
+		block, err = s.Blockchain.GetBlockByHash(selector.Hash)
+	default:
+		return nil, status.Error(codes.InvalidArgument, "height or hash is required")
+	}
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return FromBlock(block), nil
+}
+
+// GetTip 返回最新的区块.
+func (s *Server) GetTip(ctx context.Context, req *GetTipRequest) (*Block, error) {
+	block, err := s.Blockchain.Tip()
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return FromBlock(block), nil
+}
+
+// SubmitData 为数据挖出一个新的区块.
+func (s *Server) SubmitData(ctx context.Context, req *SubmitDataRequest) (*Block, error) {
+	var addr string
+	if p, ok := peer.FromContext(ctx); ok {
+		addr = p.Addr.String()
+	}
+	if err := s.RateLimiter.AllowIP(peerIP(addr)); err != nil {
+		return nil, rateLimited(ctx, err)
+	}
+	if s.MaxDataSize > 0 && int64(len(req.Data)) > s.MaxDataSize {
+		return nil, status.Errorf(codes.ResourceExhausted, "data is larger than %d bytes", s.MaxDataSize)
+	}
+
+	if s.Authorizer != nil {
+		err := s.Authorizer.Check(blockchain.Credentials{
+			PublicKey: req.PublicKey,
+			Signature: hex.EncodeToString(req.Signature),
+			Timestamp: req.Timestamp,
+			Nonce:     req.Nonce,
+		}, req.Data)
+		if err != nil {
+			log.Warnf("audit: rejected gRPC write from %s, public key %q: %v", addr, req.PublicKey, err)
+			return nil, toStatus(err)
+		}
+		if err = s.RateLimiter.AllowKey(strings.ToLower(req.PublicKey)); err != nil {
+			return nil, rateLimited(ctx, err)
+		}
+	}
+
+	block, err := s.Blockchain.MineBlock(req.Data)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return FromBlock(block), nil
+}
+
+// peerIP 去掉地址中的端口.
+func peerIP(addr string) string {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	return host
+}
+
+// rateLimited 在retry-after头中返回建议等待的秒数.
+func rateLimited(ctx context.Context, err error) error {
+	var limited *blockchain.RateLimitError
+	if errors.As(err, &limited) {
+		seconds := int(math.Ceil(limited.RetryAfter.Seconds()))
+		grpc.SetHeader(ctx, metadata.Pairs("retry-after", strconv.Itoa(seconds)))
+	}
+	return toStatus(err)
+}
+
+// GetDifficulty 返回当前的难度系数.
+func (s *Server) GetDifficulty(ctx context.Context, req *GetDifficultyRequest) (*GetDifficultyResponse, error) {
+	s.Blockchain.RLock()
+	defer s.Blockchain.RUnlock()
+	return &GetDifficultyResponse{Difficulty: s.Blockchain.Difficulty}, nil
+}
+
+// Subscribe 推送高度不小于from_height的区块, 数据已经被裁剪的区块不推送.
+func (s *Server) Subscribe(req *SubscribeRequest, stream grpc.ServerStreamingServer[Block]) error {
+	var from uint64
+	if req.FromHeight != nil {
+		from = req.GetFromHeight()
+	} else {
+		s.Blockchain.RLock()
+		from = uint64(len(s.Blockchain.Blocks))
+		s.Blockchain.RUnlock()
+	}
+
+	backlog, blocks, cancel := s.Blockchain.Subscribe(from)
+	defer cancel()
+
+	for _, block := range backlog {
+		if err := stream.Send(FromBlock(block)); err != nil {
+			return err
+		}
+	}
+
+	for {
+		select {
+		case block, ok := <-blocks:
+			if !ok {
+				return status.Error(codes.ResourceExhausted, "subscriber is too slow")
+			}
+			if err := stream.Send(FromBlock(block)); err != nil {
+				return err
+			}
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+		case <-s.quit:
+			return status.Error(codes.Unavailable, "server is shutting down")
+		}
+	}
+}
+
+// toStatus 把区块链的错误转换为gRPC的状态码.
+func toStatus(err error) error {
+	switch {
+	case errors.Is(err, blockchain.ErrNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, blockchain.ErrPruned):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, blockchain.ErrInvalidBlock):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, blockchain.ErrUnauthenticated):
+		return status.Error(codes.Unauthenticated, err.Error())
+	case errors.Is(err, blockchain.ErrForbidden):
+		return status.Error(codes.PermissionDenied, err.Error())
+	case errors.Is(err, blockchain.ErrStopped):
+		return status.Error(codes.Unavailable, err.Error())
+	case errors.Is(err, blockchain.ErrRateLimited):
+		return status.Error(codes.ResourceExhausted, err.Error())
+	default:
+		return status.Error(codes.Internal, err.Error())
+	}
+}
+
+// FromBlock 把区块转换为gRPC消息.
+func FromBlock(block *blockchain.Block) *Block {
+	return &Block{
+		Height:     block.Height,
+		Timestamp:  block.Timestamp,
+		Hash:       block.Hash,
+		PrevHash:   block.PrevHash,
+		Difficulty: block.Difficulty,
+		Nonce:      block.Nonce,
+		Data:       block.Data,
+		PublicKey:  block.PublicKey,
+		Signature:  block.Signature,
+	}
+}
+
+// ToBlock 把gRPC消息转换为区块.
+func (b *Block) ToBlock() *blockchain.Block {
+	return &blockchain.Block{
+		Height:     b.GetHeight(),
+		Timestamp:  b.GetTimestamp(),
+		Hash:       b.GetHash(),
+		PrevHash:   b.GetPrevHash(),
+		Difficulty: b.GetDifficulty(),
+		Nonce:      b.GetNonce(),
+		Data:       b.GetData(),
+		PublicKey:  b.GetPublicKey(),
+		Signature:  b.GetSignature(),
+	}
+}
@@ -0,0 +1,91 @@
+package grpcapi
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/smallnest/blockchain"
+	"github.com/smallnest/blockchain/store"
+	"github.com/smallnest/blockchain/wallet"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func TestServer(t *testing.T) {
+	s, err := store.NewLevelDBStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	bc := &blockchain.Blockchain{
+		Store:      s,
+		Difficulty: 1,
+		PrefixZero: "0",
+	}
+	if err = bc.GenerateGenesisBlock(); err != nil {
+		t.Fatal(err)
+	}
+
+	ln := bufconn.Listen(1 << 20)
+	privateKey, _, publicKey, _, _ := wallet.GenerateKeys()
+	server := NewServer("", bc)
+	server.Authorizer = blockchain.NewAuthorizer([]string{publicKey})
+	server.MaxDataSize = 16
+	go server.server.Serve(ln)
+	defer server.Stop()
+
+	client, err := Dial("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, addr string) (net.Conn, error) {
+			return ln.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if _, err = client.SubmitData(ctx, []byte("hello")); status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("expect Unauthenticated for unsigned data but got %v", err)
+	}
+	if _, err = client.SubmitSignedData(ctx, make([]byte, 17), privateKey); status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("expect ResourceExhausted for large data but got %v", err)
+	}
+	block, err := client.SubmitSignedData(ctx, []byte("hello"), privateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if block.Height != 1 || string(block.Data) != "hello" {
+		t.Fatalf("unexpected block: %+v", block)
+	}
+
+	got, err := client.GetBlockByHash(ctx, block.Hash)
+	if err != nil || got.Height != 1 {
+		t.Fatalf("failed to get block by hash: %v", err)
+	}
+
+	_, err = client.GetBlock(ctx, 100)
+	if status.Code(err) != codes.NotFound {
+		t.Fatalf("expect NotFound but got %v", err)
+	}
+
+	from := uint64(0)
+	var heights []uint64
+	err = client.Subscribe(ctx, &from, func(block *blockchain.Block) error {
+		heights = append(heights, block.Height)
+		if len(heights) == 2 {
+			cancel()
+		}
+		return nil
+	})
+	if status.Code(err) != codes.Canceled || len(heights) != 2 {
+		t.Fatalf("unexpected subscription result %v: %v", heights, err)
+	}
+}
@@ -0,0 +1,171 @@
+package blockchain
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/smallnest/blockchain/wallet"
+	"github.com/smallnest/blockchain/wallet/signer"
+)
+
+// messageMagic 是签名消息的前缀, 和比特币的signmessage相同, 签名的消息不会被当作其它数据的签名.
+const messageMagic = "Bitcoin Signed Message:\n"
+
+// MessageSignatureSize 是可恢复签名的长度: 1字节的恢复标记、32字节的r和32字节的s.
+const MessageSignatureSize = signer.CompactSignatureSize
+
+var (
+	// ErrInvalidMessageSignature 不是合法的可恢复签名.
+	ErrInvalidMessageSignature = errors.New("invalid message signature")
+	// ErrUnsupportedAddress 只能用P2PKH和P2WPKH地址校验消息签名.
+	ErrUnsupportedAddress = errors.New("address does not refer to a public key")
+)
+
+// VerifyMessageRequest 是POST /verify的请求.
+type VerifyMessageRequest struct {
+	Address   string `json:"address"`
+	Message   string `json:"message"`
+	Signature string `json:"signature"`
+}
+
+// VerifyMessageResult 是POST /verify的响应, 签名有效时包含签名者的公钥.
+type VerifyMessageResult struct {
+	Valid     bool   `json:"valid"`
+	PublicKey string `json:"publicKey,omitempty"`
+}
+
+// SignMessage 对文本消息签名, 返回base64编码的可恢复签名, 和比特币的signmessage兼容.
+// compressed表示签名者的地址使用压缩公钥, wallet.GetPublicKey生成的地址使用未压缩的公钥.
+func SignMessage(privateKey, message string, compressed bool) (string, error) {
+	priKey, err := hex.DecodeString(privateKey)
+	if err != nil {
+		return "", err
+	}
+	if !wallet.ValidPrivateKey(priKey) {
+		return "", signer.ErrInvalidPrivateKey
+	}
+	var key [32]byte
+	copy(key[:], priKey)
+
+	// 和比特币一样使用RFC 6979的确定性随机数, 相同的消息得到相同的签名
+	hash := messageHash(message)
+	nonce := btcec.NonceRFC6979(priKey, hash[:], nil, nil, 0)
+	signed, err := signer.Default().SignCompact(hash, key, nonce.Bytes(), compressed)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(signed), nil
+}
+
+// RecoverPublicKey 从消息的签名恢复签名者的十六进制公钥, compressed表示签名者使用压缩公钥.
+func RecoverPublicKey(signature, message string) (publicKey string, compressed bool, err error) {
+	signed, err := decodeMessageSignature(signature)
+	if err != nil {
+		return "", false, err
+	}
+	return recoverPublicKey(signed, message)
+}
+
+// VerifyMessage 校验消息的签名是否由地址address的私钥生成, address可以是P2PKH或者P2WPKH地址.
+// 签名或者地址的格式不正确时返回错误, 签名者不是这个地址时Valid为false, 签名有效时返回签名者的公钥.
+func VerifyMessage(address, signature, message string) (VerifyMessageResult, error) {
+	var result VerifyMessageResult
+	addr, err := wallet.ParseAddress(address)
+	if err != nil {
+		return result, err
+	}
+	if addr.Type != wallet.AddressP2PKH && addr.Type != wallet.AddressP2WPKH {
+		return result, fmt.Errorf("%w: %s", ErrUnsupportedAddress, addr.Type)
+	}
+	signed, err := decodeMessageSignature(signature)
+	if err != nil {
+		return result, err
+	}
+
+	// 格式正确但是无法恢复出公钥的签名是无效的签名
+	publicKey, compressed, err := recoverPublicKey(signed, message)
+	if err != nil {
+		return result, nil
+	}
+
+	var recovered string
+	switch addr.Type {
+	case wallet.AddressP2PKH:
+		if recovered, err = wallet.PublicKey2P2PKH(publicKey); err != nil {
+			return result, err
+		}
+	case wallet.AddressP2WPKH:
+		// 隔离见证地址只能对应压缩公钥
+		if !compressed {
+			return result, nil
+		}
+		if recovered, err = wallet.PublicKey2P2WPKH(publicKey); err != nil {
+			return result, err
+		}
+	}
+	recoveredAddr, err := wallet.ParseAddress(recovered)
+	if err != nil {
+		return result, err
+	}
+	if bytes.Equal(recoveredAddr.Hash, addr.Hash) {
+		result.Valid, result.PublicKey = true, publicKey
+	}
+	return result, nil
+}
+
+func decodeMessageSignature(signature string) ([]byte, error) {
+	signed, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidMessageSignature, err)
+	}
+	if len(signed) != MessageSignatureSize {
+		return nil, fmt.Errorf("%w: length %d", ErrInvalidMessageSignature, len(signed))
+	}
+	return signed, nil
+}
+
+func recoverPublicKey(signed []byte, message string) (publicKey string, compressed bool, err error) {
+	pubKey, compressed, err := signer.Default().RecoverCompact(messageHash(message), signed)
+	if err != nil {
+		return "", false, fmt.Errorf("%w: %v", ErrInvalidMessageSignature, err)
+	}
+	return hex.EncodeToString(pubKey), compressed, nil
+}
+
+// messageHash 计算 sha256(sha256(varstr(messageMagic) || varstr(message))).
+func messageHash(message string) [32]byte {
+	var buf bytes.Buffer
+	writeVarString(&buf, messageMagic)
+	writeVarString(&buf, message)
+	hash := sha256.Sum256(buf.Bytes())
+	return sha256.Sum256(hash[:])
+}
+
+// writeVarString 按照比特币的格式写入字符串: 变长编码的长度, 然后是字符串的内容.
+func writeVarString(buf *bytes.Buffer, s string) {
+	n := uint64(len(s))
+	var b [9]byte
+	switch {
+	case n < 0xfd:
+		buf.WriteByte(byte(n))
+	case n <= 0xffff:
+		b[0] = 0xfd
+		binary.LittleEndian.PutUint16(b[1:], uint16(n))
+		buf.Write(b[:3])
+	case n <= 0xffffffff:
+		b[0] = 0xfe
+		binary.LittleEndian.PutUint32(b[1:], uint32(n))
+		buf.Write(b[:5])
+	default:
+		b[0] = 0xff
+		binary.LittleEndian.PutUint64(b[1:], n)
+		buf.Write(b[:])
+	}
+	buf.WriteString(s)
+}
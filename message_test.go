@@ -0,0 +1,106 @@
+package blockchain
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/smallnest/blockchain/wallet"
+)
+
+// 签名使用RFC 6979生成确定的nonce, 相同的私钥和消息总是得到相同的签名, 消息的哈希格式改变时这个测试会失败.
+func TestSignMessageDeterministic(t *testing.T) {
+	const (
+		privateKey = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+		message    = "vires is numeris"
+	)
+	tests := []struct {
+		compressed         bool
+		address, signature string
+	}{
+		{false, "1HZwkjkeaoZfTSaJxDw6aKkxp45agDiEzN", "GwxMsQJ+s7mMjDBiCFyRfHk5SOdPnfIQbszlBLNIBE6efexHARz5is8619IZ1XLlfXDlTzlYxaY/XaLRv/oKlxQ="},
+		{true, "1F3sAm6ZtwLAUnj7d38pGFxtP3RVEvtsbV", "HwxMsQJ+s7mMjDBiCFyRfHk5SOdPnfIQbszlBLNIBE6efexHARz5is8619IZ1XLlfXDlTzlYxaY/XaLRv/oKlxQ="},
+	}
+	for _, tt := range tests {
+		signature, err := SignMessage(privateKey, message, tt.compressed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if signature != tt.signature {
+			t.Errorf("expect signature %s but got %s", tt.signature, signature)
+		}
+		if result, err := VerifyMessage(tt.address, tt.signature, message); err != nil || !result.Valid {
+			t.Errorf("%s: signature should be valid: %v", tt.address, err)
+		}
+	}
+}
+
+func TestSignMessage(t *testing.T) {
+	privateKey, _, publicKey, address, _ := wallet.GenerateKeys()
+	compressedKey, compressedAddress, _ := wallet.GetCompressedPublicKey(privateKey)
+	segwitAddress, _ := wallet.PublicKey2P2WPKH(compressedKey)
+	message := "飞鸽传输"
+
+	signature, err := SignMessage(privateKey, message, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recovered, compressed, err := RecoverPublicKey(signature, message)
+	if err != nil || compressed || recovered != publicKey {
+		t.Fatalf("expect public key %s but got %s, %v, %v", publicKey, recovered, compressed, err)
+	}
+	if result, err := VerifyMessage(address, signature, message); err != nil || !result.Valid || result.PublicKey != publicKey {
+		t.Errorf("signature should be valid for %s: %+v, %v", address, result, err)
+	}
+	if result, _ := VerifyMessage(compressedAddress, signature, message); result.Valid {
+		t.Error("signature of an uncompressed key should be invalid for the compressed address")
+	}
+	if result, _ := VerifyMessage(address, signature, "other"); result.Valid {
+		t.Error("signature of another message should be invalid")
+	}
+
+	signature, err = SignMessage(privateKey, message, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if recovered, compressed, _ = RecoverPublicKey(signature, message); !compressed || recovered != compressedKey {
+		t.Errorf("expect public key %s but got %s", compressedKey, recovered)
+	}
+	for _, addr := range []string{compressedAddress, segwitAddress} {
+		if result, err := VerifyMessage(addr, signature, message); err != nil || !result.Valid {
+			t.Errorf("signature should be valid for %s: %v", addr, err)
+		}
+	}
+	if result, _ := VerifyMessage(address, signature, message); result.Valid {
+		t.Error("signature of a compressed key should be invalid for the uncompressed address")
+	}
+
+	// 超过252字节的消息使用3字节的长度前缀
+	long := strings.Repeat("a", 300)
+	signature, _ = SignMessage(privateKey, long, false)
+	if result, err := VerifyMessage(address, signature, long); err != nil || !result.Valid {
+		t.Errorf("signature of a long message should be valid: %v", err)
+	}
+}
+
+func TestVerifyMessageErrors(t *testing.T) {
+	privateKey, _, publicKey, address, _ := wallet.GenerateKeys()
+	signature, _ := SignMessage(privateKey, "hello", false)
+
+	for _, sig := range []string{"not base64!", "AAAA"} {
+		if _, err := VerifyMessage(address, sig, "hello"); !errors.Is(err, ErrInvalidMessageSignature) {
+			t.Errorf("%q: expect ErrInvalidMessageSignature but got %v", sig, err)
+		}
+	}
+	if _, err := VerifyMessage(address+"1", signature, "hello"); err == nil {
+		t.Error("expect error for an invalid address")
+	}
+	ms, _ := NewMultisig(1, []string{publicKey})
+	p2sh, _ := ms.Address()
+	if _, err := VerifyMessage(p2sh, signature, "hello"); !errors.Is(err, ErrUnsupportedAddress) {
+		t.Errorf("expect ErrUnsupportedAddress but got %v", err)
+	}
+	if _, err := SignMessage("00", "hello", false); err == nil {
+		t.Error("expect error for an invalid private key")
+	}
+}
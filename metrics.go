@@ -0,0 +1,195 @@
+package blockchain
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	chainHeight = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "blockchain_height",
+		Help: "Height of the latest block.",
+	})
+	chainDifficulty = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "blockchain_difficulty",
+		Help: "Current mining difficulty.",
+	})
+	blockInterval = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "blockchain_block_interval_seconds",
+		Help:    "Time between the timestamps of consecutive blocks.",
+		Buckets: []float64{0.5, 1, 2, 5, 10, 30, 60, 120, 300, 600},
+	})
+	hashRate = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "blockchain_hash_rate",
+		Help: "Hashes per second computed while mining the latest block.",
+	})
+	hashesTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "blockchain_hashes_total",
+		Help: "Total number of hashes computed while mining.",
+	})
+	storeLatency = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "blockchain_store_duration_seconds",
+		Help:    "Latency of store operations.",
+		Buckets: prometheus.ExponentialBuckets(0.0001, 4, 10),
+	}, []string{"op"})
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "blockchain_http_requests_total",
+		Help: "Number of HTTP requests by route, method and status code.",
+	}, []string{"route", "method", "code"})
+	httpLatency = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "blockchain_http_request_duration_seconds",
+		Help:    "Latency of HTTP requests by route and method.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"route", "method"})
+	// peerCount 在节点之间还没有网络连接, 目前总是0
+	peerCount = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "blockchain_peers",
+		Help: "Number of connected peers.",
+	})
+)
+
+func init() {
+	prometheus.MustRegister(chainHeight, chainDifficulty, blockInterval, hashRate, hashesTotal,
+		storeLatency, httpRequests, httpLatency, peerCount)
+}
+
+// observeBlock 记录新加入的区块.
+func (bc *Blockchain) observeBlock(block *Block) {
+	chainHeight.Set(float64(block.Height))
+	chainDifficulty.Set(float64(bc.Difficulty))
+}
+
+// observeInterval 记录新挖出的区块和前一个区块的时间间隔, 区块的时间戳精确到秒.
+func observeInterval(block, prevBlock *Block) {
+	blockInterval.Observe(float64(block.Timestamp - prevBlock.Timestamp))
+}
+
+// observeHashes 记录挖出一个区块计算的哈希次数和耗时.
+func observeHashes(hashes uint64, took time.Duration) {
+	hashesTotal.Add(float64(hashes))
+	if took > 0 {
+		hashRate.Set(float64(hashes) / took.Seconds())
+	}
+}
+
+// InstrumentedStore 记录Store每种操作的耗时.
+type InstrumentedStore struct {
+	Store
+}
+
+// NewInstrumentedStore 包装一个Store.
+func NewInstrumentedStore(s Store) *InstrumentedStore {
+	return &InstrumentedStore{Store: s}
+}
+
+func observeStore(op string, start time.Time) {
+	storeLatency.WithLabelValues(op).Observe(time.Since(start).Seconds())
+}
+
+// Get 读取区块.
+func (s *InstrumentedStore) Get(height uint64) (*Block, error) {
+	defer observeStore("get", time.Now())
+	return s.Store.Get(height)
+}
+
+// Add 写入区块.
+func (s *InstrumentedStore) Add(height uint64, block *Block) error {
+	defer observeStore("add", time.Now())
+	return s.Store.Add(height, block)
+}
+
+// GetBatch 批量读取区块.
+func (s *InstrumentedStore) GetBatch(height uint64, count int) ([]*Block, error) {
+	defer observeStore("get_batch", time.Now())
+	return s.Store.GetBatch(height, count)
+}
+
+// Exist 判断区块是否存在.
+func (s *InstrumentedStore) Exist(height uint64) (bool, error) {
+	defer observeStore("exist", time.Now())
+	return s.Store.Exist(height)
+}
+
+// Snapshot 对底层的Store做快照.
+func (s *InstrumentedStore) Snapshot(path string) (uint64, error) {
+	snapshotter, ok := s.Store.(Snapshotter)
+	if !ok {
+		return 0, ErrSnapshotUnsupported
+	}
+	defer observeStore("snapshot", time.Now())
+	return snapshotter.Snapshot(path)
+}
+
+// Prune 裁剪底层的Store.
+func (s *InstrumentedStore) Prune(height uint64) error {
+	pruner, ok := s.Store.(Pruner)
+	if !ok {
+		return ErrPruneUnsupported
+	}
+	defer observeStore("prune", time.Now())
+	return pruner.Prune(height)
+}
+
+// PrunedHeight 返回底层的Store已经裁剪到的高度.
+func (s *InstrumentedStore) PrunedHeight() (uint64, error) {
+	pruner, ok := s.Store.(Pruner)
+	if !ok {
+		return 0, nil
+	}
+	return pruner.PrunedHeight()
+}
+
+// instrument 记录路由route的请求数和耗时.
+func instrument(route string, h httprouter.Handle) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+		start := time.Now()
+		rw := &statusWriter{ResponseWriter: w, code: http.StatusOK}
+		h(rw, r, params)
+
+		httpRequests.WithLabelValues(route, r.Method, strconv.Itoa(rw.code)).Inc()
+		httpLatency.WithLabelValues(route, r.Method).Observe(time.Since(start).Seconds())
+	}
+}
+
+// statusWriter 记录响应的状态码, 并且保留推送区块需要的Flusher和Hijacker.
+type statusWriter struct {
+	http.ResponseWriter
+	code        int
+	wroteHeader bool
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+	if !w.wroteHeader {
+		w.code = code
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *statusWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *statusWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("hijacking is not supported")
+	}
+	// websocket握手成功
+	w.code = http.StatusSwitchingProtocols
+	return h.Hijack()
+}
+
+// Unwrap 供http.ResponseController使用.
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,43 @@
+package blockchain
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMetrics(t *testing.T) {
+	bc := newTestBlockchain(t, 0)
+	bc.Store = NewInstrumentedStore(bc.Store)
+	s := &Server{Blockchain: bc}
+	ts := httptest.NewServer(s.configRouter())
+	defer ts.Close()
+
+	resp, err := http.Post(ts.URL+"/blocks", "application/octet-stream", strings.NewReader("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	resp, err = http.Get(ts.URL + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	data, _ := ioutil.ReadAll(resp.Body)
+	body := string(data)
+
+	for _, metric := range []string{
+		"blockchain_height 1",
+		"blockchain_hashes_total",
+		"blockchain_block_interval_seconds_count",
+		`blockchain_store_duration_seconds_count{op="add"}`,
+		`blockchain_http_requests_total{code="200",method="POST",route="/blocks"}`,
+	} {
+		if !strings.Contains(body, metric) {
+			t.Errorf("metric %s is missing", metric)
+		}
+	}
+}
@@ -0,0 +1,202 @@
+package blockchain
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/smallnest/blockchain/wallet"
+)
+
+// MaxMultisigKeys 是多重签名最多的公钥个数.
+const MaxMultisigKeys = 16
+
+// 赎回脚本使用的操作码, 和比特币的OP_1...OP_16、OP_CHECKMULTISIG相同.
+const (
+	opSmallInt       = 0x50
+	opCheckMultisig  = 0xae
+	uncompressedSize = 65
+	compressedSize   = 33
+)
+
+var (
+	// ErrInvalidMultisig 多重签名的参数或者赎回脚本不合法.
+	ErrInvalidMultisig = errors.New("invalid multisig")
+	// ErrNotMultisigKey 私钥对应的公钥不在多重签名的公钥中.
+	ErrNotMultisigKey = errors.New("key is not part of the multisig")
+)
+
+// Multisig 是M-of-N多重签名, N个公钥中至少M个的签名才有效.
+type Multisig struct {
+	M int
+	// 十六进制编码的公钥, 压缩和未压缩的格式都可以
+	PublicKeys []string
+}
+
+// NewMultisig 创建一个M-of-N多重签名, 公钥不能重复.
+func NewMultisig(m int, publicKeys []string) (*Multisig, error) {
+	n := len(publicKeys)
+	if m < 1 || m > n || n > MaxMultisigKeys {
+		return nil, fmt.Errorf("%w: %d-of-%d", ErrInvalidMultisig, m, n)
+	}
+
+	keys := make([]string, n)
+	seen := make(map[string]bool, n)
+	for i, publicKey := range publicKeys {
+		publicKey = strings.ToLower(publicKey)
+		pubKey, err := hex.DecodeString(publicKey)
+		if err != nil || !validPublicKey(pubKey) {
+			return nil, fmt.Errorf("%w: invalid public key %q", ErrInvalidMultisig, publicKey)
+		}
+		compressed, _ := wallet.CompressPublicKey(publicKey)
+		if seen[compressed] {
+			return nil, fmt.Errorf("%w: duplicate public key %q", ErrInvalidMultisig, publicKey)
+		}
+		seen[compressed] = true
+		keys[i] = publicKey
+	}
+	return &Multisig{M: m, PublicKeys: keys}, nil
+}
+
+// ParseRedeemScript 解析赎回脚本: OP_M <公钥>... OP_N OP_CHECKMULTISIG.
+func ParseRedeemScript(script []byte) (*Multisig, error) {
+	if len(script) < 3 || script[len(script)-1] != opCheckMultisig {
+		return nil, fmt.Errorf("%w: not a multisig script", ErrInvalidMultisig)
+	}
+	m := int(script[0]) - opSmallInt
+	n := int(script[len(script)-2]) - opSmallInt
+
+	var publicKeys []string
+	for rest := script[1 : len(script)-2]; len(rest) > 0; {
+		size := int(rest[0])
+		if (size != compressedSize && size != uncompressedSize) || len(rest) < size+1 {
+			return nil, fmt.Errorf("%w: malformed public key", ErrInvalidMultisig)
+		}
+		publicKeys = append(publicKeys, hex.EncodeToString(rest[1:size+1]))
+		rest = rest[size+1:]
+	}
+	if len(publicKeys) != n {
+		return nil, fmt.Errorf("%w: expect %d public keys but got %d", ErrInvalidMultisig, n, len(publicKeys))
+	}
+	return NewMultisig(m, publicKeys)
+}
+
+// RedeemScript 返回赎回脚本, 格式和比特币的多重签名脚本相同.
+func (ms *Multisig) RedeemScript() []byte {
+	script := []byte{byte(opSmallInt + ms.M)}
+	for _, publicKey := range ms.PublicKeys {
+		pubKey, _ := hex.DecodeString(publicKey)
+		script = append(script, byte(len(pubKey)))
+		script = append(script, pubKey...)
+	}
+	return append(script, byte(opSmallInt+len(ms.PublicKeys)), opCheckMultisig)
+}
+
+// Address 返回赎回脚本的P2SH地址.
+func (ms *Multisig) Address() (string, error) {
+	return wallet.Script2P2SH(ms.RedeemScript())
+}
+
+// PartialSignature 是多重签名中的一个签名, Index是公钥在PublicKeys中的位置.
+type PartialSignature struct {
+	Index     int
+	Signature []byte
+}
+
+// Sign 使用其中一个私钥对数据data签名.
+func (ms *Multisig) Sign(privateKey string, data []byte) (PartialSignature, error) {
+	index, err := ms.keyIndex(privateKey)
+	if err != nil {
+		return PartialSignature{}, err
+	}
+	if index < 0 {
+		return PartialSignature{}, ErrNotMultisigKey
+	}
+	signed, err := Sign(privateKey, data)
+	if err != nil {
+		return PartialSignature{}, err
+	}
+	return PartialSignature{Index: index, Signature: signed}, nil
+}
+
+func (ms *Multisig) keyIndex(privateKey string) (int, error) {
+	publicKey, _, err := wallet.GetPublicKey(privateKey)
+	if err != nil {
+		return -1, err
+	}
+	compressed, _, err := wallet.GetCompressedPublicKey(privateKey)
+	if err != nil {
+		return -1, err
+	}
+	for i, key := range ms.PublicKeys {
+		if key == publicKey || key == compressed {
+			return i, nil
+		}
+	}
+	return -1, nil
+}
+
+// Aggregate 把各个签名者的签名合并为一个多重签名, 按照公钥的顺序排列.
+// 每个签名编码为: 公钥序号(1字节) + 签名长度(1字节) + 签名.
+func (ms *Multisig) Aggregate(sigs []PartialSignature) ([]byte, error) {
+	sorted := append([]PartialSignature(nil), sigs...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Index < sorted[j].Index })
+
+	var signed []byte
+	for i, sig := range sorted {
+		if sig.Index < 0 || sig.Index >= len(ms.PublicKeys) {
+			return nil, fmt.Errorf("%w: key index %d out of range", ErrInvalidMultisig, sig.Index)
+		}
+		if i > 0 && sorted[i-1].Index == sig.Index {
+			return nil, fmt.Errorf("%w: duplicate signature of key %d", ErrInvalidMultisig, sig.Index)
+		}
+		if len(sig.Signature) == 0 || len(sig.Signature) > 255 {
+			return nil, fmt.Errorf("%w: invalid signature of key %d", ErrInvalidMultisig, sig.Index)
+		}
+		signed = append(signed, byte(sig.Index), byte(len(sig.Signature)))
+		signed = append(signed, sig.Signature...)
+	}
+	return signed, nil
+}
+
+// Verify 校验多重签名, 至少需要M个不同公钥的有效签名. 每个签名可以是ECDSA或者带SigType标记的签名.
+// 同一个公钥的压缩和未压缩格式只算一个, M不合法时签名无效.
+func (ms *Multisig) Verify(signed []byte, data []byte) bool {
+	if ms.M < 1 || ms.M > len(ms.PublicKeys) {
+		return false
+	}
+
+	valid := make(map[string]bool)
+	for len(signed) > 0 {
+		if len(signed) < 2 {
+			return false
+		}
+		index, size := int(signed[0]), int(signed[1])
+		if index >= len(ms.PublicKeys) || len(signed) < 2+size {
+			return false
+		}
+		publicKey := ms.PublicKeys[index]
+		if VerifyTagged(publicKey, signed[2:2+size], data) {
+			compressed, err := wallet.CompressPublicKey(publicKey)
+			if err != nil {
+				return false
+			}
+			valid[compressed] = true
+		}
+		signed = signed[2+size:]
+	}
+	return len(valid) >= ms.M
+}
+
+func validPublicKey(pubKey []byte) bool {
+	switch len(pubKey) {
+	case compressedSize:
+		return pubKey[0] == 0x02 || pubKey[0] == 0x03
+	case uncompressedSize:
+		return pubKey[0] == 0x04
+	default:
+		return false
+	}
+}
@@ -0,0 +1,149 @@
+package blockchain
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/smallnest/blockchain/wallet"
+	"github.com/smallnest/blockchain/wallet/signer"
+)
+
+func TestMultisig(t *testing.T) {
+	var privateKeys, publicKeys []string
+	for i := 0; i < 3; i++ {
+		privateKey, _, publicKey, _, _ := wallet.GenerateKeys()
+		if i == 2 {
+			// 压缩和未压缩的公钥可以混用
+			publicKey, _, _ = wallet.GetCompressedPublicKey(privateKey)
+		}
+		privateKeys = append(privateKeys, privateKey)
+		publicKeys = append(publicKeys, publicKey)
+	}
+	ms, err := NewMultisig(2, publicKeys)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	address, err := ms.Address()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if addr, err := wallet.ParseAddress(address); err != nil || addr.Type != wallet.AddressP2SH || !strings.HasPrefix(address, "3") {
+		t.Fatalf("unexpected P2SH address %s: %v", address, err)
+	}
+	parsed, err := ParseRedeemScript(ms.RedeemScript())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other, _ := parsed.Address(); other != address {
+		t.Fatalf("address of the parsed redeem script is %s, expect %s", other, address)
+	}
+
+	data := []byte("飞鸽传输")
+	sign := func(i int) PartialSignature {
+		sig, err := ms.Sign(privateKeys[i], data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sig.Index != i {
+			t.Fatalf("expect key index %d but got %d", i, sig.Index)
+		}
+		return sig
+	}
+	sig0, sig1, sig2 := sign(0), sign(1), sign(2)
+
+	signed, err := ms.Aggregate([]PartialSignature{sig2, sig0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ms.Verify(signed, data) {
+		t.Error("2 of 3 signatures should be valid")
+	}
+	if ms.Verify(signed, []byte("other")) {
+		t.Error("signatures of other data should be invalid")
+	}
+
+	signed, _ = ms.Aggregate([]PartialSignature{sig1})
+	if ms.Verify(signed, data) {
+		t.Error("1 of 3 signatures should be invalid")
+	}
+	if _, err = ms.Aggregate([]PartialSignature{sig1, sig1}); !errors.Is(err, ErrInvalidMultisig) {
+		t.Errorf("expect ErrInvalidMultisig for duplicate signatures but got %v", err)
+	}
+	// 手工拼接同一个公钥的两个签名, 只算一个
+	once, _ := ms.Aggregate([]PartialSignature{sig1})
+	if ms.Verify(append(once, once...), data) {
+		t.Error("signatures of the same key should be counted once")
+	}
+	// 签名放在错误的公钥序号上
+	signed, _ = ms.Aggregate([]PartialSignature{{Index: 0, Signature: sig1.Signature}, sig2})
+	if ms.Verify(signed, data) {
+		t.Error("signature under a wrong key index should be invalid")
+	}
+
+	otherKey, _, _, _, _ := wallet.GenerateKeys()
+	if _, err = ms.Sign(otherKey, data); !errors.Is(err, ErrNotMultisigKey) {
+		t.Errorf("expect ErrNotMultisigKey but got %v", err)
+	}
+	for _, invalid := range []string{"", "not hex", strings.Repeat("00", 32), strings.Repeat("ff", 32)} {
+		if _, err = ms.Sign(invalid, data); !errors.Is(err, signer.ErrInvalidPrivateKey) {
+			t.Errorf("expect ErrInvalidPrivateKey for %q but got %v", invalid, err)
+		}
+	}
+}
+
+// 没有经过NewMultisig校验的Multisig也不能被绕过.
+func TestMultisigVerifyUnchecked(t *testing.T) {
+	privateKey, _, publicKey, _, _ := wallet.GenerateKeys()
+	compressed, _, _ := wallet.GetCompressedPublicKey(privateKey)
+	data := []byte("data")
+
+	if (&Multisig{}).Verify(nil, data) {
+		t.Error("empty multisig should be invalid")
+	}
+	if (&Multisig{M: 0, PublicKeys: []string{publicKey}}).Verify(nil, data) {
+		t.Error("0-of-1 multisig should be invalid")
+	}
+
+	// 同一个公钥的两种格式只算一个
+	ms := &Multisig{M: 2, PublicKeys: []string{publicKey, compressed}}
+	sig0, err := ms.Sign(privateKey, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig1 := PartialSignature{Index: 1, Signature: sig0.Signature}
+	signed, err := ms.Aggregate([]PartialSignature{sig0, sig1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ms.Verify(signed, data) {
+		t.Error("signatures of the same key in two formats should be counted once")
+	}
+}
+
+func TestNewMultisigInvalid(t *testing.T) {
+	_, _, publicKey, _, _ := wallet.GenerateKeys()
+	compressed, _ := wallet.CompressPublicKey(publicKey)
+
+	tests := []struct {
+		m    int
+		keys []string
+	}{
+		{0, []string{publicKey}},
+		{2, []string{publicKey}},
+		{1, []string{"04abcd"}},
+		{1, []string{"not hex"}},
+		{1, []string{publicKey, compressed}},
+		{1, make([]string, MaxMultisigKeys+1)},
+	}
+	for _, tt := range tests {
+		if _, err := NewMultisig(tt.m, tt.keys); !errors.Is(err, ErrInvalidMultisig) {
+			t.Errorf("%d-of-%d: expect ErrInvalidMultisig but got %v", tt.m, len(tt.keys), err)
+		}
+	}
+
+	if _, err := ParseRedeemScript([]byte{0x52, 0x21, 0x02, 0x52, 0xae}); !errors.Is(err, ErrInvalidMultisig) {
+		t.Errorf("expect ErrInvalidMultisig for a malformed script but got %v", err)
+	}
+}
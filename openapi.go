@@ -0,0 +1,107 @@
+package blockchain
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"io/ioutil"
+	"net/http"
+	"sync"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/gorillamux"
+	"github.com/julienschmidt/httprouter"
+	"github.com/smallnest/log"
+)
+
+// openAPISpec 是http rpc服务的OpenAPI 3文档, 修改路由时需要同步修改.
+//
+//go:embed openapi.json
+var openAPISpec []byte
+
+var (
+	openAPIOnce   sync.Once
+	openAPIDoc    *openapi3.T
+	openAPIRouter routers.Router
+	openAPIErr    error
+)
+
+// OpenAPI 解析并校验OpenAPI文档.
+func OpenAPI() (*openapi3.T, routers.Router, error) {
+	openAPIOnce.Do(func() {
+		doc, err := openapi3.NewLoader().LoadFromData(openAPISpec)
+		if err != nil {
+			openAPIErr = err
+			return
+		}
+		if err = doc.Validate(context.Background()); err != nil {
+			openAPIErr = err
+			return
+		}
+		// 只按路径匹配, 不限制请求的host
+		doc.Servers = nil
+		openAPIDoc = doc
+		openAPIRouter, openAPIErr = gorillamux.NewRouter(doc)
+	})
+	return openAPIDoc, openAPIRouter, openAPIErr
+}
+
+func (s *Server) handleOpenAPI(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(openAPISpec)
+}
+
+// validateRequests 按照OpenAPI文档校验请求的参数、请求头和JSON请求体, 不合法的请求返回400.
+// 原始数据的请求体(POST /blocks)不做校验. 校验前先按照MaxBodySize读取请求体, 超过时返回413.
+// POST /rpc的请求体和Content-Type也不做校验, JSON-RPC 2.0要求由handleRPC返回-32700或者-32600的错误对象.
+func (s *Server) validateRequests(next http.Handler) http.Handler {
+	_, router, err := OpenAPI()
+	if err != nil {
+		log.Errorf("invalid OpenAPI document, requests are not validated: %v", err)
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route, pathParams, err := router.FindRoute(r)
+		if err != nil {
+			// 文档中没有的路由交给httprouter返回404或者405
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		validateBody := hasJSONBody(route.Operation) && route.Path != rpcPath
+		if validateBody {
+			body, err := s.readBody(w, r)
+			if err != nil {
+				respondBodyError(w, err)
+				return
+			}
+			r.Body = ioutil.NopCloser(bytes.NewReader(body))
+		}
+
+		input := &openapi3filter.RequestValidationInput{
+			Request:    r,
+			PathParams: pathParams,
+			Route:      route,
+			Options: &openapi3filter.Options{
+				ExcludeRequestBody: !validateBody,
+				MultiError:         true,
+			},
+		}
+		if err = openapi3filter.ValidateRequest(r.Context(), input); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// hasJSONBody 判断操作的请求体是JSON格式的.
+func hasJSONBody(op *openapi3.Operation) bool {
+	if op == nil || op.RequestBody == nil || op.RequestBody.Value == nil {
+		return false
+	}
+	return op.RequestBody.Value.Content.Get("application/json") != nil
+}
@@ -0,0 +1,179 @@
+package blockchain
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/julienschmidt/httprouter"
+)
+
+// TestOpenAPIRoutes 确保文档中的每个路由都由configRouter处理, 并且configRouter的每个路由都有文档.
+func TestOpenAPIRoutes(t *testing.T) {
+	doc, _, err := OpenAPI()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := &Server{Blockchain: newTestBlockchain(t, 0)}
+	router := s.configRouter().(*httprouter.Router)
+	for path, item := range doc.Paths.Map() {
+		samplePath := strings.NewReplacer("{height}", "1").Replace(path)
+		for method := range item.Operations() {
+			if handle, _, _ := router.Lookup(method, samplePath); handle == nil {
+				t.Errorf("%s %s is documented but not served", method, path)
+			}
+		}
+	}
+
+	for _, rt := range s.routes() {
+		path := strings.NewReplacer(":height", "{height}").Replace(rt.path)
+		item := doc.Paths.Value(path)
+		if item == nil || item.GetOperation(rt.method) == nil {
+			t.Errorf("%s %s is served but not documented", rt.method, rt.path)
+		}
+	}
+}
+
+// TestOpenAPIResponses 确保每个路由的响应都符合文档.
+func TestOpenAPIResponses(t *testing.T) {
+	_, router, err := OpenAPI()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bc := newTestBlockchain(t, 2)
+	s := &Server{Blockchain: bc, SnapshotDir: t.TempDir()}
+	handler := s.Handler()
+
+	cases := []struct {
+		method, path, contentType, body string
+		code                            int
+	}{
+		{"GET", "/blocks", "", "", 200},
+		{"GET", "/blocks?start=100", "", "", 400},
+		{"POST", "/blocks", "application/octet-stream", "hello", 200},
+		{"GET", "/block/0", "", "", 200},
+		{"GET", "/block/3", "", "", 200},
+		{"GET", "/block/100", "", "", 404},
+		{"POST", "/admin/snapshot", "", "", 401},
+		{"POST", "/rpc", "application/json", `{"jsonrpc":"2.0","method":"getTip","id":1}`, 200},
+		{"POST", "/rpc", "application/json", `[{"jsonrpc":"2.0","method":"getBlockByHeight","params":[100],"id":"a"}]`, 200},
+		{"POST", "/rpc", "application/json", `{"jsonrpc":"2.0","method":"getTip"}`, 204},
+		{"POST", "/verify", "application/json", `{"address":"1HZwkjkeaoZfTSaJxDw6aKkxp45agDiEzN","message":"vires is numeris","signature":"GwxMsQJ+s7mMjDBiCFyRfHk5SOdPnfIQbszlBLNIBE6efexHARz5is8619IZ1XLlfXDlTzlYxaY/XaLRv/oKlxQ="}`, 200},
+		{"POST", "/verify", "application/json", `{"address":"1HZwkjkeaoZfTSaJxDw6aKkxp45agDiEzN","message":"other","signature":"GwxMsQJ+s7mMjDBiCFyRfHk5SOdPnfIQbszlBLNIBE6efexHARz5is8619IZ1XLlfXDlTzlYxaY/XaLRv/oKlxQ="}`, 200},
+		{"POST", "/verify", "application/json", `{"address":"1HZwkjkeaoZfTSaJxDw6aKkxp45agDiEzN","message":"","signature":"AAAA"}`, 400},
+		{"GET", "/healthz", "", "", 200},
+		{"GET", "/readyz", "", "", 200},
+		{"GET", "/metrics", "", "", 200},
+		{"GET", "/openapi.json", "", "", 200},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest(c.method, c.path, strings.NewReader(c.body))
+		if c.contentType != "" {
+			req.Header.Set("Content-Type", c.contentType)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		if w.Code != c.code {
+			t.Errorf("%s %s: expect status %d but got %d: %s", c.method, c.path, c.code, w.Code, w.Body.String())
+			continue
+		}
+
+		route, pathParams, err := router.FindRoute(req)
+		if err != nil {
+			t.Errorf("%s %s is not documented: %v", c.method, c.path, err)
+			continue
+		}
+		input := &openapi3filter.ResponseValidationInput{
+			RequestValidationInput: &openapi3filter.RequestValidationInput{
+				Request:    req,
+				PathParams: pathParams,
+				Route:      route,
+			},
+			Status:  w.Code,
+			Header:  w.Header(),
+			Body:    ioutil.NopCloser(bytes.NewReader(w.Body.Bytes())),
+			Options: &openapi3filter.Options{IncludeResponseStatus: true},
+		}
+		if err = openapi3filter.ValidateResponse(context.Background(), input); err != nil {
+			t.Errorf("%s %s: response diverges from the spec: %v", c.method, c.path, err)
+		}
+	}
+}
+
+func TestValidateRequests(t *testing.T) {
+	s := &Server{Blockchain: newTestBlockchain(t, 0)}
+	handler := s.Handler()
+
+	for _, path := range []string{"/block/abc", "/blocks?start=-1", "/blocks/stream?from=x"} {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expect 400 but got %d", path, w.Code)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/unknown", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expect 404 but got %d", w.Code)
+	}
+
+	// JSON请求体按照文档校验, 原始数据的请求体不校验.
+	// JSON-RPC的请求由handleRPC校验, 返回200和JSON-RPC的错误对象
+	s.MaxBodySize = 64
+	bodies := []struct {
+		path, contentType, body string
+		code                    int
+		rpcCode                 int
+	}{
+		{"/verify", "application/json", `{"address":1}`, http.StatusBadRequest, 0},
+		{"/verify", "application/json", `not json`, http.StatusBadRequest, 0},
+		{"/rpc", "application/json", `{"method":"getTip","id":1}`, http.StatusOK, RPCInvalidRequest},
+		{"/rpc", "application/json", `{"jsonrpc":"1.0","method":"getTip","id":1}`, http.StatusOK, RPCInvalidRequest},
+		{"/rpc", "application/json", `[1]`, http.StatusOK, RPCInvalidRequest},
+		{"/rpc", "application/json", `not json`, http.StatusOK, RPCParseError},
+		{"/rpc", "text/plain", `{"jsonrpc":"2.0","method":"getTip","id":1}`, http.StatusOK, 0},
+		{"/rpc", "application/json", `{"jsonrpc":"2.0","method":"getTip","id":1}`, http.StatusOK, 0},
+		{"/rpc", "application/json", `{"jsonrpc":"2.0","method":"` + strings.Repeat("x", 64) + `"}`, http.StatusRequestEntityTooLarge, 0},
+		{"/blocks", "application/octet-stream", `{"not":"validated"}`, http.StatusOK, 0},
+	}
+	for _, b := range bodies {
+		req := httptest.NewRequest(http.MethodPost, b.path, strings.NewReader(b.body))
+		req.Header.Set("Content-Type", b.contentType)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		if w.Code != b.code {
+			t.Errorf("POST %s %s: expect %d but got %d", b.path, b.body, b.code, w.Code)
+			continue
+		}
+		if b.path != "/rpc" || w.Code != http.StatusOK {
+			continue
+		}
+
+		var resp RPCResponse
+		data := w.Body.Bytes()
+		if bytes.HasPrefix(data, []byte("[")) {
+			var batch []RPCResponse
+			if err := json.Unmarshal(data, &batch); err != nil || len(batch) != 1 {
+				t.Fatalf("POST /rpc %s: invalid batch response %s", b.body, data)
+			}
+			resp = batch[0]
+		} else if err := json.Unmarshal(data, &resp); err != nil {
+			t.Fatalf("POST /rpc %s: invalid response %s", b.body, data)
+		}
+		switch {
+		case b.rpcCode == 0 && resp.Error != nil:
+			t.Errorf("POST /rpc %s: unexpected error %v", b.body, resp.Error)
+		case b.rpcCode != 0 && (resp.Error == nil || resp.Error.Code != b.rpcCode):
+			t.Errorf("POST /rpc %s: expect error %d but got %v", b.body, b.rpcCode, resp.Error)
+		}
+	}
+}
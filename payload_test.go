@@ -8,7 +8,7 @@ import (
 )
 
 func TestVerify(t *testing.T) {
-	privateKey, _, publicKey, _ := wallet.GenerateKeys()
+	privateKey, _, publicKey, _, _ := wallet.GenerateKeys()
 
 	data := []byte("飞鸽传输")
 	signed, err := Sign(privateKey, data)
@@ -22,3 +22,22 @@ func TestVerify(t *testing.T) {
 		t.Errorf("verify failed")
 	}
 }
+
+func TestSignRapidCalls(t *testing.T) {
+	privateKey, _, publicKey, _, _ := wallet.GenerateKeys()
+	data := []byte("飞鸽传输")
+
+	for i := 0; i < 100; i++ {
+		signed, err := Sign(privateKey, data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !Verify(publicKey, signed, data) {
+			t.Fatalf("signature %d is invalid", i)
+		}
+	}
+
+	if _, err := Sign("00", data); err == nil {
+		t.Fatal("expect error for an invalid private key")
+	}
+}
@@ -0,0 +1,175 @@
+package blockchain
+
+import (
+	"errors"
+	"io/ioutil"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// DefaultMaxBodySize 是写入区块时数据的默认最大长度.
+const DefaultMaxBodySize = 1 << 20
+
+// limiterIdleTimeout 超过这个时间没有请求的客户端会被清理.
+const limiterIdleTimeout = 10 * time.Minute
+
+// ErrRateLimited 客户端的请求太频繁.
+var ErrRateLimited = errors.New("too many requests")
+
+// RateLimitError 是请求被限流的错误, RetryAfter是建议的等待时间.
+type RateLimitError struct {
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return ErrRateLimited.Error() + ", retry after " + e.RetryAfter.String()
+}
+
+// Unwrap 使得errors.Is(err, ErrRateLimited)成立.
+func (e *RateLimitError) Unwrap() error {
+	return ErrRateLimited
+}
+
+// RateLimitOptions 是写入区块的限流配置, 使用令牌桶算法.
+type RateLimitOptions struct {
+	// 每个IP每秒允许写入的区块数和突发数量, 为0则不限制
+	PerIP   rate.Limit
+	IPBurst int
+	// 每个签名公钥每秒允许写入的区块数和突发数量, 为0则不限制
+	PerKey   rate.Limit
+	KeyBurst int
+}
+
+// RateLimiter 按照客户端IP和签名的公钥限制写入区块的频率.
+type RateLimiter struct {
+	opts RateLimitOptions
+
+	mu        sync.Mutex
+	ips       map[string]*clientLimiter
+	keys      map[string]*clientLimiter
+	cleanedAt time.Time
+}
+
+type clientLimiter struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// NewRateLimiter 创建一个RateLimiter.
+func NewRateLimiter(opts RateLimitOptions) *RateLimiter {
+	if opts.IPBurst <= 0 {
+		opts.IPBurst = 1
+	}
+	if opts.KeyBurst <= 0 {
+		opts.KeyBurst = 1
+	}
+	return &RateLimiter{
+		opts:      opts,
+		ips:       make(map[string]*clientLimiter),
+		keys:      make(map[string]*clientLimiter),
+		cleanedAt: time.Now(),
+	}
+}
+
+// AllowIP 判断IP是否还可以写入, 不能写入时返回*RateLimitError.
+func (l *RateLimiter) AllowIP(ip string) error {
+	if l == nil || l.opts.PerIP == 0 {
+		return nil
+	}
+	return l.allow(l.ips, ip, l.opts.PerIP, l.opts.IPBurst)
+}
+
+// AllowKey 判断公钥是否还可以写入, 不能写入时返回*RateLimitError.
+func (l *RateLimiter) AllowKey(publicKey string) error {
+	if l == nil || l.opts.PerKey == 0 {
+		return nil
+	}
+	return l.allow(l.keys, publicKey, l.opts.PerKey, l.opts.KeyBurst)
+}
+
+func (l *RateLimiter) allow(clients map[string]*clientLimiter, id string, limit rate.Limit, burst int) error {
+	now := time.Now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.cleanup(now)
+	c, ok := clients[id]
+	if !ok {
+		c = &clientLimiter{limiter: rate.NewLimiter(limit, burst)}
+		clients[id] = c
+	}
+	c.lastSeen = now
+
+	r := c.limiter.ReserveN(now, 1)
+	if delay := r.DelayFrom(now); delay > 0 {
+		// 不占用以后的令牌
+		r.CancelAt(now)
+		return &RateLimitError{RetryAfter: delay}
+	}
+	return nil
+}
+
+// cleanup 定期清理长时间没有请求的客户端.
+func (l *RateLimiter) cleanup(now time.Time) {
+	if now.Sub(l.cleanedAt) < limiterIdleTimeout {
+		return
+	}
+	l.cleanedAt = now
+	for _, clients := range []map[string]*clientLimiter{l.ips, l.keys} {
+		for id, c := range clients {
+			if now.Sub(c.lastSeen) > limiterIdleTimeout {
+				delete(clients, id)
+			}
+		}
+	}
+}
+
+// clientIP 返回请求的来源IP, 不信任X-Forwarded-For等可以伪造的头.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// retryAfterSeconds 把等待时间向上取整为Retry-After头使用的秒数.
+func retryAfterSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
+
+// respondRateLimited 返回429, 并在Retry-After头中给出建议的等待时间.
+func respondRateLimited(w http.ResponseWriter, err error) {
+	var limited *RateLimitError
+	if errors.As(err, &limited) {
+		w.Header().Set("Retry-After", retryAfterSeconds(limited.RetryAfter))
+	}
+	http.Error(w, err.Error(), http.StatusTooManyRequests)
+}
+
+// readBody 读取请求体, 超过MaxBodySize时返回*http.MaxBytesError.
+func (s *Server) readBody(w http.ResponseWriter, r *http.Request) ([]byte, error) {
+	body := r.Body
+	if s.MaxBodySize > 0 {
+		body = http.MaxBytesReader(w, r.Body, s.MaxBodySize)
+	}
+	defer body.Close()
+	return ioutil.ReadAll(body)
+}
+
+// respondBodyError 返回读取请求体时的错误, 请求体太大时返回413.
+func respondBodyError(w http.ResponseWriter, err error) {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		return
+	}
+	http.Error(w, err.Error(), http.StatusBadRequest)
+}
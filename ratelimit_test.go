@@ -0,0 +1,94 @@
+package blockchain
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRateLimiter(t *testing.T) {
+	l := NewRateLimiter(RateLimitOptions{PerIP: 1, IPBurst: 2, PerKey: 1})
+
+	for i := 0; i < 2; i++ {
+		if err := l.AllowIP("10.0.0.1"); err != nil {
+			t.Fatalf("expect request %d to be allowed: %v", i, err)
+		}
+	}
+	err := l.AllowIP("10.0.0.1")
+	var limited *RateLimitError
+	if !errors.As(err, &limited) || !errors.Is(err, ErrRateLimited) || limited.RetryAfter <= 0 {
+		t.Fatalf("expect rate limit error but got %v", err)
+	}
+	if err = l.AllowIP("10.0.0.2"); err != nil {
+		t.Fatalf("expect other clients to be allowed: %v", err)
+	}
+
+	if err = l.AllowKey("key"); err != nil {
+		t.Fatal(err)
+	}
+	if err = l.AllowKey("key"); err == nil {
+		t.Fatal("expect key to be limited")
+	}
+
+	var nilLimiter *RateLimiter
+	if err = nilLimiter.AllowIP("10.0.0.1"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestServerRateLimit(t *testing.T) {
+	bc := newTestBlockchain(t, 0)
+	s := &Server{
+		Blockchain:  bc,
+		RateLimiter: NewRateLimiter(RateLimitOptions{PerIP: 0.01, IPBurst: 1}),
+	}
+	ts := httptest.NewServer(s.configRouter())
+	defer ts.Close()
+
+	post := func(path, body string) *http.Response {
+		resp, err := http.Post(ts.URL+path, "application/octet-stream", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp
+	}
+
+	if resp := post("/blocks", "hello"); resp.StatusCode != http.StatusOK {
+		t.Fatalf("expect 200 but got %d", resp.StatusCode)
+	}
+	resp := post("/blocks", "hello")
+	if resp.StatusCode != http.StatusTooManyRequests || resp.Header.Get("Retry-After") == "" {
+		t.Fatalf("expect 429 with Retry-After but got %d", resp.StatusCode)
+	}
+
+	// JSON-RPC的submitData使用同样的限流
+	body, _ := json.Marshal(map[string]interface{}{
+		"jsonrpc": "2.0", "id": 1, "method": "submitData", "params": []string{"hi"},
+	})
+	rpcResp, err := http.Post(ts.URL+"/rpc", "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rpcResp.Body.Close()
+	if rpcResp.StatusCode != http.StatusTooManyRequests || rpcResp.Header.Get("Retry-After") == "" {
+		t.Fatalf("expect 429 with Retry-After for rpc but got %d", rpcResp.StatusCode)
+	}
+	var result RPCResponse
+	if err = json.NewDecoder(rpcResp.Body).Decode(&result); err != nil {
+		t.Fatal(err)
+	}
+	if result.Error == nil || result.Error.Code != RPCRateLimited {
+		t.Fatalf("expect rate limited rpc error but got %+v", result.Error)
+	}
+
+	s.RateLimiter = nil
+	s.MaxBodySize = 16
+	if resp := post("/blocks", strings.Repeat("x", 17)); resp.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Fatalf("expect 413 but got %d", resp.StatusCode)
+	}
+}
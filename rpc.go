@@ -0,0 +1,320 @@
+package blockchain
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// rpcPath 是JSON-RPC服务的路径.
+const rpcPath = "/rpc"
+
+// JSON-RPC 2.0 的错误码.
+const (
+	RPCParseError     = -32700
+	RPCInvalidRequest = -32600
+	RPCMethodNotFound = -32601
+	RPCInvalidParams  = -32602
+	RPCInternalError  = -32603
+
+	// 以下是应用自定义的错误码
+	RPCNotFound     = -32001
+	RPCPruned       = -32002
+	RPCInvalidBlock = -32003
+	RPCUnauthorized = -32004
+	RPCForbidden    = -32005
+	RPCStopped      = -32006
+	RPCRateLimited  = -32007
+)
+
+// RPCRequest 是一个JSON-RPC 2.0请求.
+type RPCRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	// 没有ID的请求是通知, 不需要回复
+	ID json.RawMessage `json:"id,omitempty"`
+}
+
+// RPCResponse 是一个JSON-RPC 2.0响应.
+type RPCResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *RPCError       `json:"error,omitempty"`
+	ID      json.RawMessage `json:"id"`
+}
+
+// RPCError 是JSON-RPC 2.0的错误对象.
+type RPCError struct {
+	Code    int         `json:"code"`
+	Message string      `json:"message"`
+	Data    interface{} `json:"data,omitempty"`
+}
+
+func (e *RPCError) Error() string {
+	return fmt.Sprintf("rpc error %d: %s", e.Code, e.Message)
+}
+
+// rpcMethod 处理一个方法调用, 返回的错误如果不是*RPCError会被转换.
+type rpcMethod func(s *Server, r *http.Request, params json.RawMessage) (interface{}, error)
+
+var rpcMethods = map[string]rpcMethod{
+	"getBlockByHeight": rpcGetBlockByHeight,
+	"getBlockByHash":   rpcGetBlockByHash,
+	"getTip":           rpcGetTip,
+	"submitData":       rpcSubmitData,
+	"getDifficulty":    rpcGetDifficulty,
+}
+
+var nullID = json.RawMessage("null")
+
+// handleRPC 处理JSON-RPC 2.0请求, 支持批量请求.
+func (s *Server) handleRPC(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	body, err := s.readBody(w, r)
+	if err != nil {
+		respondBodyError(w, err)
+		return
+	}
+
+	body = bytes.TrimSpace(body)
+	if len(body) == 0 || body[0] != '[' {
+		resp := s.callRPC(r, body)
+		if resp == nil {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		respondJSON(w, r, rpcStatus(w, resp), resp)
+		return
+	}
+
+	var batch []json.RawMessage
+	if err := json.Unmarshal(body, &batch); err != nil {
+		respondJSON(w, r, http.StatusOK, rpcErrorResponse(nullID, &RPCError{Code: RPCParseError, Message: err.Error()}))
+		return
+	}
+	if len(batch) == 0 {
+		respondJSON(w, r, http.StatusOK, rpcErrorResponse(nullID, &RPCError{Code: RPCInvalidRequest, Message: "empty batch"}))
+		return
+	}
+
+	var responses = make([]*RPCResponse, 0, len(batch))
+	for _, req := range batch {
+		if resp := s.callRPC(r, req); resp != nil {
+			responses = append(responses, resp)
+		}
+	}
+	if len(responses) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	respondJSON(w, r, rpcStatus(w, responses...), responses)
+}
+
+// rpcStatus 返回JSON-RPC响应的http状态码. 所有的调用都被限流时, 和REST接口一样返回429,
+// 并在Retry-After头中返回建议等待的秒数, 否则返回200.
+func rpcStatus(w http.ResponseWriter, responses ...*RPCResponse) int {
+	var retryAfter float64
+	for _, resp := range responses {
+		if resp.Error == nil || resp.Error.Code != RPCRateLimited {
+			return http.StatusOK
+		}
+		if seconds, ok := resp.Error.Data.(float64); ok && seconds > retryAfter {
+			retryAfter = seconds
+		}
+	}
+	w.Header().Set("Retry-After", strconv.FormatFloat(retryAfter, 'f', 0, 64))
+	return http.StatusTooManyRequests
+}
+
+// callRPC 执行一个请求, 通知请求返回nil.
+func (s *Server) callRPC(r *http.Request, data []byte) *RPCResponse {
+	var req RPCRequest
+	if err := json.Unmarshal(data, &req); err != nil {
+		if _, ok := err.(*json.SyntaxError); ok {
+			return rpcErrorResponse(nullID, &RPCError{Code: RPCParseError, Message: err.Error()})
+		}
+		return rpcErrorResponse(nullID, &RPCError{Code: RPCInvalidRequest, Message: err.Error()})
+	}
+
+	id := req.ID
+	if id == nil {
+		id = nullID
+	}
+	if req.JSONRPC != "2.0" || req.Method == "" {
+		return rpcErrorResponse(id, &RPCError{Code: RPCInvalidRequest, Message: "invalid request"})
+	}
+
+	method, ok := rpcMethods[req.Method]
+	if !ok {
+		if req.ID == nil {
+			return nil
+		}
+		return rpcErrorResponse(id, &RPCError{Code: RPCMethodNotFound, Message: "method not found: " + req.Method})
+	}
+
+	result, err := method(s, r, req.Params)
+	if req.ID == nil {
+		return nil
+	}
+	if err != nil {
+		return rpcErrorResponse(id, toRPCError(err))
+	}
+	data, err = json.Marshal(result)
+	if err != nil {
+		return rpcErrorResponse(id, toRPCError(err))
+	}
+	return &RPCResponse{JSONRPC: "2.0", Result: data, ID: id}
+}
+
+func rpcErrorResponse(id json.RawMessage, err *RPCError) *RPCResponse {
+	return &RPCResponse{JSONRPC: "2.0", Error: err, ID: id}
+}
+
+// toRPCError 把区块链的错误映射为JSON-RPC的错误码.
+func toRPCError(err error) *RPCError {
+	var rpcErr *RPCError
+	var limited *RateLimitError
+	switch {
+	case errors.As(err, &rpcErr):
+		return rpcErr
+	case errors.As(err, &limited):
+		// data是建议等待的秒数
+		return &RPCError{Code: RPCRateLimited, Message: err.Error(), Data: math.Ceil(limited.RetryAfter.Seconds())}
+	case errors.Is(err, ErrNotFound):
+		return &RPCError{Code: RPCNotFound, Message: err.Error()}
+	case errors.Is(err, ErrPruned):
+		return &RPCError{Code: RPCPruned, Message: err.Error()}
+	case errors.Is(err, ErrInvalidBlock):
+		return &RPCError{Code: RPCInvalidBlock, Message: err.Error()}
+	case errors.Is(err, ErrUnauthenticated):
+		return &RPCError{Code: RPCUnauthorized, Message: err.Error()}
+	case errors.Is(err, ErrForbidden):
+		return &RPCError{Code: RPCForbidden, Message: err.Error()}
+	case errors.Is(err, ErrStopped):
+		return &RPCError{Code: RPCStopped, Message: err.Error()}
+	default:
+		return &RPCError{Code: RPCInternalError, Message: err.Error()}
+	}
+}
+
+// unmarshalParams 解析参数, 参数可以是按位置的数组, 也可以是按names命名的对象.
+func unmarshalParams(params json.RawMessage, names []string, ptrs ...interface{}) error {
+	params = bytes.TrimSpace(params)
+	if len(params) == 0 || bytes.Equal(params, nullID) {
+		return nil
+	}
+
+	var values = make([]json.RawMessage, len(names))
+	switch params[0] {
+	case '[':
+		var positional []json.RawMessage
+		if err := json.Unmarshal(params, &positional); err != nil {
+			return &RPCError{Code: RPCInvalidParams, Message: err.Error()}
+		}
+		if len(positional) > len(names) {
+			return &RPCError{Code: RPCInvalidParams, Message: "too many params"}
+		}
+		copy(values, positional)
+	case '{':
+		var named map[string]json.RawMessage
+		if err := json.Unmarshal(params, &named); err != nil {
+			return &RPCError{Code: RPCInvalidParams, Message: err.Error()}
+		}
+		for i, name := range names {
+			values[i] = named[name]
+		}
+	default:
+		return &RPCError{Code: RPCInvalidParams, Message: "params must be an array or an object"}
+	}
+
+	for i, value := range values {
+		if value == nil {
+			continue
+		}
+		if err := json.Unmarshal(value, ptrs[i]); err != nil {
+			return &RPCError{Code: RPCInvalidParams, Message: fmt.Sprintf("invalid param %s: %v", names[i], err)}
+		}
+	}
+	return nil
+}
+
+func rpcGetBlockByHeight(s *Server, r *http.Request, params json.RawMessage) (interface{}, error) {
+	var height *uint64
+	if err := unmarshalParams(params, []string{"height"}, &height); err != nil {
+		return nil, err
+	}
+	if height == nil {
+		return nil, &RPCError{Code: RPCInvalidParams, Message: "missing param height"}
+	}
+	return s.Blockchain.GetBlock(*height)
+}
+
+func rpcGetBlockByHash(s *Server, r *http.Request, params json.RawMessage) (interface{}, error) {
+	var hash string
+	if err := unmarshalParams(params, []string{"hash"}, &hash); err != nil {
+		return nil, err
+	}
+	if hash == "" {
+		return nil, &RPCError{Code: RPCInvalidParams, Message: "missing param hash"}
+	}
+	return s.Blockchain.GetBlockByHash(hash)
+}
+
+func rpcGetTip(s *Server, r *http.Request, params json.RawMessage) (interface{}, error) {
+	return s.Blockchain.Tip()
+}
+
+func rpcGetDifficulty(s *Server, r *http.Request, params json.RawMessage) (interface{}, error) {
+	s.Blockchain.RLock()
+	defer s.Blockchain.RUnlock()
+	return s.Blockchain.Difficulty, nil
+}
+
+// rpcSubmitData 把数据写入一个新的区块.
+// encoding可以是raw(默认)、hex或者base64.
+// 服务器要求签名时, publicKey和signature是十六进制编码的公钥和签名,
+// timestamp和nonce防止请求被重放, 签名的消息参见WriteMessage.
+func rpcSubmitData(s *Server, r *http.Request, params json.RawMessage) (interface{}, error) {
+	var data, encoding string
+	var c Credentials
+	names := []string{"data", "encoding", "publicKey", "signature", "timestamp", "nonce"}
+	if err := unmarshalParams(params, names, &data, &encoding, &c.PublicKey, &c.Signature, &c.Timestamp, &c.Nonce); err != nil {
+		return nil, err
+	}
+
+	if err := s.RateLimiter.AllowIP(clientIP(r)); err != nil {
+		return nil, err
+	}
+
+	var payload []byte
+	var err error
+	switch encoding {
+	case "", "raw":
+		payload = []byte(data)
+	case "hex":
+		payload, err = hex.DecodeString(data)
+	case "base64":
+		payload, err = base64.StdEncoding.DecodeString(data)
+	default:
+		return nil, &RPCError{Code: RPCInvalidParams, Message: "unknown encoding: " + encoding}
+	}
+	if err != nil {
+		return nil, &RPCError{Code: RPCInvalidParams, Message: err.Error()}
+	}
+	if err = s.authorize(r, c, payload); err != nil {
+		return nil, err
+	}
+	if err = s.limitKey(c.PublicKey); err != nil {
+		return nil, err
+	}
+
+	return s.Blockchain.MineBlock(payload)
+}
@@ -0,0 +1,70 @@
+package blockchain
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func callTestRPC(t *testing.T, s *Server, body string) []byte {
+	req := httptest.NewRequest(http.MethodPost, "/rpc", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	s.configRouter().ServeHTTP(w, req)
+	return w.Body.Bytes()
+}
+
+func TestRPC(t *testing.T) {
+	bc := newTestBlockchain(t, 2)
+	s := &Server{Blockchain: bc}
+
+	var resp RPCResponse
+	body := callTestRPC(t, s, `{"jsonrpc":"2.0","method":"getBlockByHeight","params":[1],"id":1}`)
+	if err := json.Unmarshal(body, &resp); err != nil || resp.Error != nil {
+		t.Fatalf("unexpected response: %s", body)
+	}
+	var block Block
+	json.Unmarshal(resp.Result, &block)
+	if block.Hash != bc.Blocks[1].Hash {
+		t.Fatalf("expect block 1 but got %s", resp.Result)
+	}
+
+	body = callTestRPC(t, s, `[
+		{"jsonrpc":"2.0","method":"getBlockByHash","params":{"hash":"`+bc.Blocks[2].Hash+`"},"id":"a"},
+		{"jsonrpc":"2.0","method":"getBlockByHeight","params":[100],"id":"b"},
+		{"jsonrpc":"2.0","method":"noSuchMethod","id":"c"},
+		{"jsonrpc":"2.0","method":"submitData","params":["hello"]},
+		{"jsonrpc":"2.0","method":"getDifficulty","id":"d"}
+	]`)
+	var batch []RPCResponse
+	if err := json.Unmarshal(body, &batch); err != nil {
+		t.Fatal(err)
+	}
+	if len(batch) != 4 {
+		t.Fatalf("expect 4 responses but got %s", body)
+	}
+	if batch[0].Error != nil {
+		t.Fatalf("getBlockByHash failed: %v", batch[0].Error)
+	}
+	if batch[1].Error == nil || batch[1].Error.Code != RPCNotFound {
+		t.Fatalf("expect not found error but got %s", body)
+	}
+	if batch[2].Error == nil || batch[2].Error.Code != RPCMethodNotFound {
+		t.Fatalf("expect method not found error but got %s", body)
+	}
+	if string(batch[3].Result) != "1" {
+		t.Fatalf("expect difficulty 1 but got %s", batch[3].Result)
+	}
+
+	// 通知也会执行
+	if len(bc.Blocks) != 4 || string(bc.Blocks[3].Data) != "hello" {
+		t.Fatalf("submitData notification is not executed")
+	}
+
+	body = callTestRPC(t, s, `{"jsonrpc":"2.0","method":`)
+	json.Unmarshal(body, &resp)
+	if resp.Error == nil || resp.Error.Code != RPCParseError {
+		t.Fatalf("expect parse error but got %s", body)
+	}
+}
@@ -0,0 +1,62 @@
+package blockchain
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+
+	"github.com/smallnest/blockchain/wallet"
+	"github.com/smallnest/blockchain/wallet/schnorr"
+	"github.com/smallnest/blockchain/wallet/signer"
+)
+
+// SchnorrSignatureSize 是BIP-340签名的长度.
+const SchnorrSignatureSize = schnorr.SignatureSize
+
+// SignSchnorr 使用BIP-340的Schnorr算法对数据data进行签名, 返回64字节的签名.
+func SignSchnorr(privateKey string, data []byte) ([]byte, error) {
+	priKey, err := hex.DecodeString(privateKey)
+	if err != nil {
+		return nil, err
+	}
+	if !wallet.ValidPrivateKey(priKey) {
+		return nil, signer.ErrInvalidPrivateKey
+	}
+
+	// BIP-340推荐使用随机的辅助数据生成nonce, 避免侧信道攻击
+	var key, aux [32]byte
+	copy(key[:], priKey)
+	if _, err = rand.Read(aux[:]); err != nil {
+		return nil, err
+	}
+	return schnorr.Sign(dataHash(data), key, aux)
+}
+
+// VerifySchnorr 校验BIP-340的Schnorr签名, publicKey可以是压缩、未压缩或者32字节x-only的公钥.
+func VerifySchnorr(publicKey string, signed []byte, data []byte) bool {
+	pubKey, err := hex.DecodeString(publicKey)
+	if err != nil {
+		return false
+	}
+	return schnorr.Verify(dataHash(data), signed, pubKey)
+}
+
+// SignedData 是一条带签名的数据.
+type SignedData struct {
+	PublicKey string
+	Signature []byte
+	Data      []byte
+}
+
+// BatchVerifySchnorr 批量校验Schnorr签名, 所有签名都有效时返回true.
+// 返回false时不能确定是哪个签名无效, 需要逐个调用VerifySchnorr.
+func BatchVerifySchnorr(batch []SignedData) bool {
+	items := make([]schnorr.Item, len(batch))
+	for i, item := range batch {
+		pubKey, err := hex.DecodeString(item.PublicKey)
+		if err != nil {
+			return false
+		}
+		items[i] = schnorr.Item{Hash: dataHash(item.Data), Signature: item.Signature, PublicKey: pubKey}
+	}
+	return schnorr.BatchVerify(items)
+}
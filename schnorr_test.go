@@ -0,0 +1,189 @@
+package blockchain
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/smallnest/blockchain/wallet"
+)
+
+func TestSignSchnorr(t *testing.T) {
+	privateKey, _, publicKey, _, _ := wallet.GenerateKeys()
+	compressed, _, _ := wallet.GetCompressedPublicKey(privateKey)
+	data := []byte("飞鸽传输")
+
+	signed, err := SignSchnorr(privateKey, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(signed) != SchnorrSignatureSize {
+		t.Fatalf("expect %d bytes but got %d", SchnorrSignatureSize, len(signed))
+	}
+	for _, key := range []string{publicKey, compressed, compressed[2:]} {
+		if !VerifySchnorr(key, signed, data) {
+			t.Errorf("signature should be valid for public key %s", key)
+		}
+	}
+	if VerifySchnorr(publicKey, signed, []byte("other")) {
+		t.Error("signature of other data should be invalid")
+	}
+	if Verify(publicKey, signed, data) {
+		t.Error("schnorr signature should not pass ECDSA verification")
+	}
+	if _, err = SignSchnorr("00", data); err == nil {
+		t.Error("expect error for an invalid private key")
+	}
+}
+
+func TestSignTagged(t *testing.T) {
+	privateKey, _, publicKey, _, _ := wallet.GenerateKeys()
+	data := []byte("飞鸽传输")
+
+	for _, sigType := range []SigType{SigECDSA, SigSchnorr} {
+		signed, err := SignTagged(sigType, privateKey, data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if SigType(signed[0]) != sigType {
+			t.Errorf("%s: expect tag %d but got %d", sigType, sigType, signed[0])
+		}
+		if !VerifyTagged(publicKey, signed, data) {
+			t.Errorf("%s: signature should be valid", sigType)
+		}
+		if VerifyTagged(publicKey, signed, []byte("other")) {
+			t.Errorf("%s: signature of other data should be invalid", sigType)
+		}
+
+		// Authorizer接受两种签名
+		a := NewAuthorizer([]string{publicKey})
+		c, err := SignCredentials(sigType, privateKey, data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = a.Check(*c, data); err != nil {
+			t.Errorf("%s: %v", sigType, err)
+		}
+	}
+
+	// 没有标记的DER签名按照ECDSA校验
+	signed, _ := Sign(privateKey, data)
+	if !VerifyTagged(publicKey, signed, data) {
+		t.Error("untagged ECDSA signature should be valid")
+	}
+
+	if _, err := SignTagged(SigType(9), privateKey, data); !errors.Is(err, ErrUnknownSigType) {
+		t.Errorf("expect ErrUnknownSigType but got %v", err)
+	}
+	if VerifyTagged(publicKey, nil, data) || VerifyTagged(publicKey, []byte{9, 1, 2}, data) {
+		t.Error("unknown signatures should be invalid")
+	}
+	if sigType, err := ParseSigType("schnorr"); err != nil || sigType != SigSchnorr {
+		t.Errorf("ParseSigType(schnorr) = %v, %v", sigType, err)
+	}
+}
+
+func newSchnorrBatch(t testing.TB, n int) []SignedData {
+	batch := make([]SignedData, n)
+	for i := range batch {
+		privateKey, _, publicKey, _, _ := wallet.GenerateKeys()
+		data := []byte(fmt.Sprintf("block %d", i))
+		signed, err := SignSchnorr(privateKey, data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		batch[i] = SignedData{PublicKey: publicKey, Signature: signed, Data: data}
+	}
+	return batch
+}
+
+func TestBatchVerifySchnorr(t *testing.T) {
+	if !BatchVerifySchnorr(nil) {
+		t.Error("empty batch should be valid")
+	}
+
+	batch := newSchnorrBatch(t, 100)
+	if !BatchVerifySchnorr(batch[:1]) {
+		t.Error("batch of one signature should be valid")
+	}
+	if !BatchVerifySchnorr(batch) {
+		t.Fatal("batch should be valid")
+	}
+
+	_, _, otherKey, _, _ := wallet.GenerateKeys()
+	tests := map[string]func(item *SignedData){
+		"data":   func(item *SignedData) { item.Data = []byte("other") },
+		"key":    func(item *SignedData) { item.PublicKey = otherKey },
+		"r":      func(item *SignedData) { item.Signature[0] ^= 1 },
+		"s":      func(item *SignedData) { item.Signature[63] ^= 1 },
+		"length": func(item *SignedData) { item.Signature = item.Signature[:63] },
+		"s overflow": func(item *SignedData) {
+			copy(item.Signature[32:], btcec.S256().N.Bytes())
+		},
+	}
+	for name, corrupt := range tests {
+		for _, index := range []int{0, 57, 99} {
+			copied := append([]SignedData(nil), batch...)
+			item := copied[index]
+			item.Signature = append([]byte(nil), item.Signature...)
+			corrupt(&item)
+			copied[index] = item
+			if BatchVerifySchnorr(copied) {
+				t.Errorf("batch with a corrupted %s of item %d should be invalid", name, index)
+			}
+		}
+	}
+}
+
+// 对比逐个校验ECDSA、Schnorr签名以及批量校验Schnorr签名的吞吐量, 每次操作校验benchBatchSize个签名.
+const benchBatchSize = 128
+
+func BenchmarkVerifyECDSA(b *testing.B) {
+	batch := make([]SignedData, benchBatchSize)
+	for i := range batch {
+		privateKey, _, publicKey, _, _ := wallet.GenerateKeys()
+		data := []byte(fmt.Sprintf("block %d", i))
+		signed, err := Sign(privateKey, data)
+		if err != nil {
+			b.Fatal(err)
+		}
+		batch[i] = SignedData{PublicKey: publicKey, Signature: signed, Data: data}
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, item := range batch {
+			if !Verify(item.PublicKey, item.Signature, item.Data) {
+				b.Fatal("invalid signature")
+			}
+		}
+	}
+	b.ReportMetric(float64(b.N*benchBatchSize)/b.Elapsed().Seconds(), "sigs/s")
+}
+
+func BenchmarkVerifySchnorr(b *testing.B) {
+	batch := newSchnorrBatch(b, benchBatchSize)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, item := range batch {
+			if !VerifySchnorr(item.PublicKey, item.Signature, item.Data) {
+				b.Fatal("invalid signature")
+			}
+		}
+	}
+	b.ReportMetric(float64(b.N*benchBatchSize)/b.Elapsed().Seconds(), "sigs/s")
+}
+
+func BenchmarkBatchVerifySchnorr(b *testing.B) {
+	batch := newSchnorrBatch(b, benchBatchSize)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if !BatchVerifySchnorr(batch) {
+			b.Fatal("invalid batch")
+		}
+	}
+	b.ReportMetric(float64(b.N*benchBatchSize)/b.Elapsed().Seconds(), "sigs/s")
+}
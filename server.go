@@ -1,15 +1,23 @@
 package blockchain
 
 import (
+	"context"
+	"crypto/tls"
 	"encoding/json"
-	"io/ioutil"
+	"errors"
+	"fmt"
 	"net/http"
+	"path/filepath"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/smallnest/blockchain/wallet"
+	"github.com/smallnest/log"
 
 	"github.com/julienschmidt/httprouter"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 // Server 提供区块链rpc服务，比如增加区块，查看区块等.
@@ -19,41 +27,181 @@ type Server struct {
 	Addr       string
 	server     *http.Server // rpc server
 	Blockchain *Blockchain
+	// 快照存放的目录, 为空则不提供快照服务
+	SnapshotDir string
+	// 允许写入区块的公钥, 为nil则不校验签名
+	Authorizer *Authorizer
+	// 不为nil时使用TLS, 参见NewTLSConfig
+	TLSConfig *tls.Config
+	// 写入区块的限流, 为nil则不限流
+	RateLimiter *RateLimiter
+	// 写入请求的请求体最大长度, 为0则不限制
+	MaxBodySize int64
+
+	mu   sync.Mutex
+	quit chan struct{} // 关闭时通知推送等长连接退出
 }
 
-// NewServer 创建一个新的blockchain服务器.
-func NewServer(privateKey string, addr string, bc *Blockchain) *Server {
-	publicKey, _ := wallet.GetPublicKey(privateKey)
-	return &Server{
-		privateKey: privateKey,
-		publicKey:  publicKey,
-		Addr:       addr,
-		Blockchain: bc,
+// NewServer 创建一个新的blockchain服务器, 私钥无效时返回错误.
+func NewServer(privateKey string, addr string, bc *Blockchain) (*Server, error) {
+	// 没有私钥时服务器不对区块签名
+	var publicKey string
+	if privateKey != "" {
+		var err error
+		if publicKey, _, err = wallet.GetPublicKey(privateKey); err != nil {
+			return nil, err
+		}
 	}
+	return &Server{
+		privateKey:  privateKey,
+		publicKey:   publicKey,
+		Addr:        addr,
+		Blockchain:  bc,
+		MaxBodySize: DefaultMaxBodySize,
+	}, nil
 }
 
-// Serve 开启http rpc server.
+// Serve 开启http rpc server, 调用Shutdown后返回http.ErrServerClosed.
 func (s *Server) Serve() error {
-	r := s.configRouter()
 	ss := &http.Server{
 		Addr:           s.Addr,
-		Handler:        r,
+		Handler:        s.Handler(),
 		ReadTimeout:    10 * time.Second,
 		WriteTimeout:   10 * time.Second,
 		MaxHeaderBytes: 1 << 20,
+		TLSConfig:      s.TLSConfig,
+	}
+
+	// 和Shutdown在同一个临界区中检查和设置server, 要么这里看到已经关闭,
+	// 要么Shutdown看到ss并关闭它, 之后ListenAndServe直接返回ErrServerClosed
+	s.mu.Lock()
+	select {
+	case <-s.doneLocked():
+		s.mu.Unlock()
+		return http.ErrServerClosed
+	default:
 	}
 	s.server = ss
+	s.mu.Unlock()
+
+	if s.TLSConfig != nil {
+		// 证书由TLSConfig.GetCertificate提供
+		return ss.ListenAndServeTLS("", "")
+	}
+	return ss.ListenAndServe()
+}
+
+// Shutdown 优雅地关闭服务器: 不再接受新的连接, 断开推送区块的长连接,
+// 然后等待正在处理的请求完成, 直到ctx结束.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	quit := s.doneLocked()
+	select {
+	case <-quit:
+	default:
+		close(quit)
+	}
+	ss := s.server
+	s.mu.Unlock()
+
+	if ss == nil {
+		return nil
+	}
+	return ss.Shutdown(ctx)
+}
 
-	return s.server.ListenAndServe()
+// done 返回服务器开始关闭时关闭的channel.
+func (s *Server) done() chan struct{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.doneLocked()
+}
+
+// doneLocked 和done一样, 调用者需要持有s.mu.
+func (s *Server) doneLocked() chan struct{} {
+	if s.quit == nil {
+		s.quit = make(chan struct{})
+	}
+	return s.quit
+}
+
+// shuttingDown 判断是否已经开始关闭.
+func (s *Server) shuttingDown() bool {
+	select {
+	case <-s.done():
+		return true
+	default:
+		return false
+	}
+}
+
+// Handler 返回处理rpc请求的http.Handler, 可以挂载到其它的http服务中.
+// 请求会先按照OpenAPI文档校验.
+func (s *Server) Handler() http.Handler {
+	return s.validateRequests(s.configRouter())
+}
+
+// route 是http rpc服务的一个路由.
+type route struct {
+	method string
+	path   string
+	handle httprouter.Handle
+}
+
+// routes 返回所有的路由, 每个路由都需要在OpenAPI文档中说明.
+func (s *Server) routes() []route {
+	metrics := promhttp.Handler()
+	return []route{
+		{http.MethodGet, "/blocks", instrument("/blocks", s.handleGetBlockchain)},
+		{http.MethodPost, "/blocks", instrument("/blocks", s.handleWriteBlock)},
+		{http.MethodGet, "/blocks/stream", instrument("/blocks/stream", s.handleStreamBlocks)},
+		{http.MethodGet, "/block/:height", instrument("/block/:height", s.handleGetBlock)},
+		{http.MethodPost, "/admin/snapshot", instrument("/admin/snapshot", s.admin(s.handleSnapshot))},
+		{http.MethodPost, rpcPath, instrument(rpcPath, s.handleRPC)},
+		{http.MethodPost, "/verify", instrument("/verify", s.handleVerifyMessage)},
+		{http.MethodGet, "/healthz", s.handleHealthz},
+		{http.MethodGet, "/readyz", s.handleReadyz},
+		{http.MethodGet, "/metrics", func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+			metrics.ServeHTTP(w, r)
+		}},
+		{http.MethodGet, "/openapi.json", s.handleOpenAPI},
+	}
 }
 
 func (s *Server) configRouter() http.Handler {
 	r := httprouter.New()
-	r.GET("/blocks", s.handleGetBlockchain)
-	r.POST("/blocks", s.handleWriteBlock)
+	for _, rt := range s.routes() {
+		r.Handle(rt.method, rt.path, rt.handle)
+	}
 	return r
 }
 
+// handleHealthz 存活检查, 进程能处理请求就返回200.
+func (s *Server) handleHealthz(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	w.Write([]byte("ok"))
+}
+
+// handleReadyz 就绪检查, 正在关闭、已经停止挖矿或者还没有创世区块时返回503.
+func (s *Server) handleReadyz(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	if s.shuttingDown() {
+		http.Error(w, "shutting down", http.StatusServiceUnavailable)
+		return
+	}
+	if s.Blockchain.Stopped() {
+		http.Error(w, ErrStopped.Error(), http.StatusServiceUnavailable)
+		return
+	}
+
+	s.Blockchain.RLock()
+	empty := len(s.Blockchain.Blocks) == 0
+	s.Blockchain.RUnlock()
+	if empty {
+		http.Error(w, "no genesis block", http.StatusServiceUnavailable)
+		return
+	}
+	w.Write([]byte("ok"))
+}
+
 func (s *Server) handleGetBlockchain(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
 	startHeight := r.FormValue("start")
 	start := 0
@@ -66,7 +214,20 @@ func (s *Server) handleGetBlockchain(w http.ResponseWriter, r *http.Request, par
 		}
 	}
 
+	s.Blockchain.RLock()
+	if start < 0 || start > len(s.Blockchain.Blocks) {
+		s.Blockchain.RUnlock()
+		http.Error(w, "start is out of range", http.StatusBadRequest)
+		return
+	}
+	// 和GET /block/:height一样, 数据已经被裁剪的区块返回410
+	if prunedHeight := s.Blockchain.prunedHeight; uint64(start) < prunedHeight {
+		s.Blockchain.RUnlock()
+		http.Error(w, fmt.Sprintf("%v below height %d", ErrPruned, prunedHeight), http.StatusGone)
+		return
+	}
 	bytes, err := json.MarshalIndent(s.Blockchain.Blocks[start:], "", "  ")
+	s.Blockchain.RUnlock()
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -75,27 +236,206 @@ func (s *Server) handleGetBlockchain(w http.ResponseWriter, r *http.Request, par
 	w.Write(bytes)
 }
 
-func (s *Server) handleWriteBlock(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
-	data, err := ioutil.ReadAll(r.Body)
+func (s *Server) handleGetBlock(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	height, err := strconv.ParseUint(params.ByName("height"), 10, 64)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	defer r.Body.Close()
 
-	s.Blockchain.Lock()
-	defer s.Blockchain.Unlock()
+	block, err := s.Blockchain.GetBlock(height)
+	switch err {
+	case nil:
+		respondJSON(w, r, http.StatusOK, block)
+	case ErrNotFound:
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case ErrPruned:
+		http.Error(w, err.Error(), http.StatusGone)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func (s *Server) handleWriteBlock(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	// 在读取数据和校验签名之前先按IP限流
+	if err := s.RateLimiter.AllowIP(clientIP(r)); err != nil {
+		respondRateLimited(w, err)
+		return
+	}
 
-	prevBlock := s.Blockchain.Blocks[len(s.Blockchain.Blocks)-1]
-	newBlock := s.Blockchain.generateBlock(prevBlock, data)
+	data, err := s.readBody(w, r)
+	if err != nil {
+		respondBodyError(w, err)
+		return
+	}
 
-	if validateBlock(newBlock, prevBlock) {
-		s.Blockchain.AddBlock(newBlock)
-		respondJSON(w, r, http.StatusOK, newBlock)
+	credentials := headerCredentials(r.Header)
+	if err = s.authorize(r, credentials, data); err != nil {
+		respondAuthError(w, err)
+		return
+	}
+	if err = s.limitKey(credentials.PublicKey); err != nil {
+		respondRateLimited(w, err)
 		return
 	}
 
-	http.Error(w, "invalid new block", http.StatusInternalServerError)
+	newBlock, err := s.Blockchain.MineBlock(data)
+	if err == ErrStopped {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	respondJSON(w, r, http.StatusOK, newBlock)
+}
+
+// respondAuthError 签名不正确时返回401, 没有权限时返回403.
+func respondAuthError(w http.ResponseWriter, err error) {
+	if errors.Is(err, ErrUnauthenticated) {
+		w.Header().Set("WWW-Authenticate", `Signature headers="`+
+			strings.Join([]string{HeaderPublicKey, HeaderSignature, HeaderTimestamp, HeaderNonce}, " ")+`"`)
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	http.Error(w, err.Error(), http.StatusForbidden)
+}
+
+// headerCredentials 从http头中读取写入请求的签名, 时间戳不是整数时按0处理, 校验时会被拒绝.
+func headerCredentials(header http.Header) Credentials {
+	timestamp, _ := strconv.ParseInt(header.Get(HeaderTimestamp), 10, 64)
+	return Credentials{
+		PublicKey: header.Get(HeaderPublicKey),
+		Signature: header.Get(HeaderSignature),
+		Timestamp: timestamp,
+		Nonce:     header.Get(HeaderNonce),
+	}
+}
+
+// authorize 校验写入请求的签名, 拒绝的请求会记录到审计日志中.
+func (s *Server) authorize(r *http.Request, c Credentials, data []byte) error {
+	if s.Authorizer == nil {
+		return nil
+	}
+
+	err := s.Authorizer.Check(c, data)
+	if err != nil {
+		commonName, fingerprint := ClientIdentity(r)
+		log.Warnf("audit: rejected write from %s (client cert %q %s), public key %q: %v",
+			r.RemoteAddr, commonName, fingerprint, c.PublicKey, err)
+	}
+	return err
+}
+
+// admin 保护管理接口: 请求需要出示服务器校验过的客户端证书,
+// 或者由有写入权限的公钥签名, 签名的数据是AdminMessage(r.Method, r.RequestURI).
+// 使用原始的RequestURI, 因为校验OpenAPI文档时会在URL中补上参数的默认值.
+func (s *Server) admin(handle httprouter.Handle) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+		if !s.trustedClient(r) {
+			err := ErrUnauthenticated
+			if s.Authorizer != nil {
+				err = s.authorize(r, headerCredentials(r.Header), AdminMessage(r.Method, r.RequestURI))
+			}
+			if err != nil {
+				respondAuthError(w, err)
+				return
+			}
+		}
+		handle(w, r, params)
+	}
+}
+
+// AdminMessage 返回管理接口的请求签名的数据, 比如"POST /admin/snapshot?name=a".
+func AdminMessage(method, requestURI string) []byte {
+	return []byte(method + " " + requestURI)
+}
+
+// trustedClient 判断请求出示了服务器校验过的客户端证书.
+func (s *Server) trustedClient(r *http.Request) bool {
+	if ClientCertificate(r) == nil {
+		return false
+	}
+	// 标准库校验过证书链, 或者NewTLSConfig配置了ClientCAFile, 在握手时已经校验过
+	return len(r.TLS.VerifiedChains) > 0 || s.TLSConfig != nil && s.TLSConfig.VerifyConnection != nil
+}
+
+// limitKey 按签名的公钥限流, 只有校验了签名时公钥才是可信的.
+func (s *Server) limitKey(publicKey string) error {
+	if s.Authorizer == nil {
+		return nil
+	}
+	return s.RateLimiter.AllowKey(strings.ToLower(publicKey))
+}
+
+// handleSnapshot 为正在运行的节点生成一致的数据快照, 只有通过admin认证的请求才能调用.
+// 参数name指定快照的文件名, format为tar(默认)或者dir.
+func (s *Server) handleSnapshot(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	if s.SnapshotDir == "" {
+		http.Error(w, "snapshot is disabled", http.StatusNotImplemented)
+		return
+	}
+	snapshotter, ok := s.Blockchain.Store.(Snapshotter)
+	if !ok {
+		http.Error(w, ErrSnapshotUnsupported.Error(), http.StatusNotImplemented)
+		return
+	}
+
+	name := r.FormValue("name")
+	if name == "" {
+		name = fmt.Sprintf("snapshot-%s", time.Now().Format("20060102-150405"))
+	}
+	if strings.ContainsAny(name, `/\`) || name == "." || name == ".." {
+		http.Error(w, "invalid snapshot name", http.StatusBadRequest)
+		return
+	}
+
+	switch r.FormValue("format") {
+	case "", "tar":
+		if !strings.HasSuffix(name, ".tar.gz") && !strings.HasSuffix(name, ".tgz") {
+			name += ".tar.gz"
+		}
+	case "dir":
+	default:
+		http.Error(w, "unknown snapshot format", http.StatusBadRequest)
+		return
+	}
+
+	// 快照时可能有新的区块写入, 高度以快照中的数据为准
+	path := filepath.Join(s.SnapshotDir, name)
+	height, err := snapshotter.Snapshot(path)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	respondJSON(w, r, http.StatusOK, map[string]interface{}{
+		"path":   path,
+		"height": height,
+	})
+}
+
+// handleVerifyMessage 校验SignMessage生成的消息签名, 地址或者签名的格式不正确时返回400.
+func (s *Server) handleVerifyMessage(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	body, err := s.readBody(w, r)
+	if err != nil {
+		respondBodyError(w, err)
+		return
+	}
+
+	var req VerifyMessageRequest
+	if err = json.Unmarshal(body, &req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	result, err := VerifyMessage(req.Address, req.Signature, req.Message)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	respondJSON(w, r, http.StatusOK, result)
 }
 
 func respondJSON(w http.ResponseWriter, r *http.Request, code int, payload interface{}) {
@@ -0,0 +1,156 @@
+package blockchain
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/smallnest/blockchain/wallet"
+)
+
+func TestServerShutdown(t *testing.T) {
+	bc := newTestBlockchain(t, 1)
+	s := &Server{Blockchain: bc}
+	ts := httptest.NewServer(s.configRouter())
+	defer ts.Close()
+
+	status := func(path string) int {
+		resp, err := http.Get(ts.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	if status("/healthz") != http.StatusOK || status("/readyz") != http.StatusOK {
+		t.Fatal("expect server to be live and ready")
+	}
+
+	// 开始关闭后推送的长连接会断开
+	resp, err := http.Get(ts.URL + "/blocks/stream")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if err = s.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		buf := make([]byte, 64)
+		for {
+			if _, err := resp.Body.Read(buf); err != nil {
+				return
+			}
+		}
+	}()
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("stream is not closed on shutdown")
+	}
+
+	if status("/healthz") != http.StatusOK || status("/readyz") != http.StatusServiceUnavailable {
+		t.Fatal("expect server to be live but not ready")
+	}
+	if err = s.Serve(); err != http.ErrServerClosed {
+		t.Fatalf("expect ErrServerClosed but got %v", err)
+	}
+}
+
+func TestGetPrunedBlocks(t *testing.T) {
+	bc := newTestBlockchain(t, 0)
+	bc.PruneDepth = 2
+	for i := 0; i < 4; i++ {
+		if err := bc.AddBlock(bc.generateBlock(bc.Blocks[len(bc.Blocks)-1], []byte("data"))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	handler := (&Server{Blockchain: bc}).configRouter()
+
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+	for _, path := range []string{"/blocks", "/blocks?start=2", "/block/2"} {
+		if w := get(path); w.Code != http.StatusGone {
+			t.Errorf("%s: expect 410 but got %d", path, w.Code)
+		}
+	}
+
+	var blocks []*Block
+	w := get("/blocks?start=3")
+	if err := json.Unmarshal(w.Body.Bytes(), &blocks); err != nil || len(blocks) != 2 || string(blocks[0].Data) != "data" {
+		t.Fatalf("expect 2 blocks with data but got %s", w.Body.String())
+	}
+}
+
+// Serve和Shutdown同时调用时, Serve总是返回ErrServerClosed而不是继续监听.
+func TestServeShutdownRace(t *testing.T) {
+	bc := newTestBlockchain(t, 0)
+	for i := 0; i < 20; i++ {
+		s := &Server{Addr: "127.0.0.1:0", Blockchain: bc}
+		served := make(chan error, 1)
+		go func() { served <- s.Serve() }()
+		if err := s.Shutdown(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case err := <-served:
+			if err != http.ErrServerClosed {
+				t.Fatalf("expect ErrServerClosed but got %v", err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("Serve is still running after Shutdown")
+		}
+	}
+}
+
+func TestStopMining(t *testing.T) {
+	bc := newTestBlockchain(t, 1)
+	bc.Stop()
+	if _, err := bc.MineBlock([]byte("data")); err != ErrStopped {
+		t.Fatalf("expect ErrStopped but got %v", err)
+	}
+	if bc.generateBlock(bc.Blocks[1], []byte("data")) != nil {
+		t.Fatal("expect generateBlock to give up after stop")
+	}
+}
+
+func TestVerifyMessageHandler(t *testing.T) {
+	privateKey, _, publicKey, address, _ := wallet.GenerateKeys()
+	signature, err := SignMessage(privateKey, "hello", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &Server{Blockchain: newTestBlockchain(t, 0)}
+	handler := s.Handler()
+
+	verify := func(message string) VerifyMessageResult {
+		body, _ := json.Marshal(VerifyMessageRequest{Address: address, Message: message, Signature: signature})
+		req := httptest.NewRequest(http.MethodPost, "/verify", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("expect status 200 but got %d: %s", w.Code, w.Body.String())
+		}
+		var result VerifyMessageResult
+		if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+			t.Fatal(err)
+		}
+		return result
+	}
+	if result := verify("hello"); !result.Valid || result.PublicKey != publicKey {
+		t.Errorf("signature should be valid: %+v", result)
+	}
+	if result := verify("other"); result.Valid || result.PublicKey != "" {
+		t.Errorf("signature of another message should be invalid: %+v", result)
+	}
+}
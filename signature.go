@@ -0,0 +1,118 @@
+package blockchain
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/smallnest/blockchain/wallet"
+)
+
+// SigType 是签名的算法, 带标记的签名以这个字节开头, 区块和交易可以携带任意一种签名.
+type SigType byte
+
+const (
+	// SigECDSA 是DER编码的ECDSA签名.
+	SigECDSA SigType = 0x01
+	// SigSchnorr 是BIP-340的Schnorr签名.
+	SigSchnorr SigType = 0x02
+)
+
+// derSequence 是DER编码的ECDSA签名的第一个字节, 没有标记的签名按照ECDSA处理.
+const derSequence = 0x30
+
+// ErrUnknownSigType 未知的签名算法.
+var ErrUnknownSigType = errors.New("unknown signature type")
+
+func (t SigType) String() string {
+	switch t {
+	case SigECDSA:
+		return "ecdsa"
+	case SigSchnorr:
+		return "schnorr"
+	default:
+		return fmt.Sprintf("SigType(%d)", byte(t))
+	}
+}
+
+// ParseSigType 根据名字ecdsa或者schnorr得到签名算法.
+func ParseSigType(name string) (SigType, error) {
+	switch name {
+	case "ecdsa":
+		return SigECDSA, nil
+	case "schnorr":
+		return SigSchnorr, nil
+	default:
+		return 0, fmt.Errorf("%w: %s", ErrUnknownSigType, name)
+	}
+}
+
+// SignTagged 使用指定的算法对数据data签名, 返回的签名以算法的标记开头.
+func SignTagged(sigType SigType, privateKey string, data []byte) ([]byte, error) {
+	var (
+		signed []byte
+		err    error
+	)
+	switch sigType {
+	case SigECDSA:
+		signed, err = Sign(privateKey, data)
+	case SigSchnorr:
+		signed, err = SignSchnorr(privateKey, data)
+	default:
+		return nil, fmt.Errorf("%w: %d", ErrUnknownSigType, byte(sigType))
+	}
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte{byte(sigType)}, signed...), nil
+}
+
+// VerifyTagged 按照签名开头的标记选择算法校验签名, 没有标记的DER签名按照ECDSA校验.
+func VerifyTagged(publicKey string, signed []byte, data []byte) bool {
+	if len(signed) == 0 {
+		return false
+	}
+	switch signed[0] {
+	case derSequence:
+		return Verify(publicKey, signed, data)
+	case byte(SigECDSA):
+		return Verify(publicKey, signed[1:], data)
+	case byte(SigSchnorr):
+		return VerifySchnorr(publicKey, signed[1:], data)
+	default:
+		return false
+	}
+}
+
+// Signed 判断区块是否带有公钥或者签名.
+func (b *Block) Signed() bool {
+	return b.PublicKey != "" || len(b.Signature) > 0
+}
+
+// SigType 返回区块签名的算法, 没有签名时返回0.
+func (b *Block) SigType() SigType {
+	if len(b.Signature) == 0 {
+		return 0
+	}
+	if b.Signature[0] == derSequence {
+		return SigECDSA
+	}
+	return SigType(b.Signature[0])
+}
+
+// Sign 使用私钥按照指定的算法对区块的哈希签名, 设置区块的公钥和签名.
+func (b *Block) Sign(sigType SigType, privateKey string) error {
+	signed, err := SignTagged(sigType, privateKey, []byte(b.Hash))
+	if err != nil {
+		return err
+	}
+	if b.PublicKey, _, err = wallet.GetCompressedPublicKey(privateKey); err != nil {
+		return err
+	}
+	b.Signature = signed
+	return nil
+}
+
+// VerifySignature 校验区块的签名, 没有签名的区块返回false.
+func (b *Block) VerifySignature() bool {
+	return b.PublicKey != "" && VerifyTagged(b.PublicKey, b.Signature, []byte(b.Hash))
+}
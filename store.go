@@ -7,6 +7,16 @@ import (
 
 var (
 	ErrNotFound = errors.New("not found")
+	// ErrSnapshotUnsupported Store不支持快照.
+	ErrSnapshotUnsupported = errors.New("snapshot is not supported by the store")
+	// ErrPruned 区块的数据已经被裁剪.
+	ErrPruned = errors.New("block body pruned")
+	// ErrStopped 区块链已经停止挖矿.
+	ErrStopped = errors.New("blockchain is stopped")
+	// ErrPruneUnsupported Store不支持裁剪.
+	ErrPruneUnsupported = errors.New("pruning is not supported by the store")
+	// ErrNetworkMismatch 数据目录中的创世块属于另一个网络.
+	ErrNetworkMismatch = errors.New("genesis block belongs to another network")
 )
 
 // Store 定义了存储的通用接口。
@@ -18,6 +28,21 @@ type Store interface {
 	Close() error
 }
 
+// Snapshotter 由支持在线备份的Store实现.
+type Snapshotter interface {
+	// Snapshot 把某一时刻一致的数据快照写入path, 返回快照中最高的区块的高度.
+	// path以.tar.gz或者.tgz结尾时生成压缩包, 否则生成一个新的数据目录.
+	Snapshot(path string) (height uint64, err error)
+}
+
+// Pruner 由支持裁剪区块数据的Store实现.
+type Pruner interface {
+	// Prune 丢弃高度小于height的区块的数据, 只保留区块头.
+	Prune(height uint64) error
+	// PrunedHeight 返回已经裁剪到的高度, 高度小于它的区块都没有数据.
+	PrunedHeight() (uint64, error)
+}
+
 func Int2Bytes(height uint64) []byte {
 	var data = make([]byte, 8)
 	binary.BigEndian.PutUint64(data, height)
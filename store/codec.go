@@ -0,0 +1,345 @@
+package store
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+	"github.com/smallnest/blockchain"
+)
+
+var (
+	_ blockchain.Store  = &CodecStore{}
+	_ blockchain.Pruner = &CodecStore{}
+)
+
+var (
+	// ErrEncrypted 数据已加密，但是没有提供密钥.
+	ErrEncrypted = errors.New("block data is encrypted but no key is supplied")
+	// ErrCorrupted 编码后的数据无法还原.
+	ErrCorrupted = errors.New("corrupted block data")
+)
+
+// codecMagic 是经过CodecStore编码的数据的前缀.
+// 不带这个前缀的数据被认为是原始数据，这样旧的数据目录依然可以读取.
+var codecMagic = []byte{0xbc, 0xdc}
+
+// codecKey 是标记数据目录由CodecStore创建的元数据, 这样的数据目录中所有的数据都经过编码.
+// 没有这个标记的旧数据目录只能根据codecMagic判断, 恰好以codecMagic开头但是无法解码的原始数据按原样返回.
+// 使用bc migrate重写旧的数据目录之后就不再有歧义.
+const codecKey = "codec"
+
+// MetaStore 由可以保存元数据的Store实现, CodecStore用它区分编码的数据和原始数据.
+type MetaStore interface {
+	// GetMeta 读取名字为key的元数据, 不存在时返回blockchain.ErrNotFound.
+	GetMeta(key string) ([]byte, error)
+	// PutMeta 写入名字为key的元数据.
+	PutMeta(key string, value []byte) error
+}
+
+const (
+	flagCompressionMask = 0x0f
+	flagEncrypted       = 0x10
+)
+
+// Compression 区块数据的压缩算法.
+type Compression byte
+
+const (
+	// CompressionNone 不压缩.
+	CompressionNone Compression = iota
+	// CompressionSnappy 使用snappy压缩.
+	CompressionSnappy
+	// CompressionZstd 使用zstd压缩.
+	CompressionZstd
+)
+
+func (c Compression) String() string {
+	switch c {
+	case CompressionNone:
+		return "none"
+	case CompressionSnappy:
+		return "snappy"
+	case CompressionZstd:
+		return "zstd"
+	default:
+		return fmt.Sprintf("unknown(%d)", byte(c))
+	}
+}
+
+// ParseCompression 根据名称解析压缩算法, 空字符串代表不压缩.
+func ParseCompression(name string) (Compression, error) {
+	switch strings.ToLower(name) {
+	case "", "none":
+		return CompressionNone, nil
+	case "snappy":
+		return CompressionSnappy, nil
+	case "zstd":
+		return CompressionZstd, nil
+	default:
+		return CompressionNone, fmt.Errorf("unknown compression: %s", name)
+	}
+}
+
+// CodecOptions 是CodecStore的配置.
+type CodecOptions struct {
+	// 压缩算法
+	Compression Compression
+	// AES密钥, 长度为16、24或者32字节. 为空则不加密
+	Key []byte
+}
+
+// CodecStore 包装了任意的Store, 写入时对区块的Data进行压缩和加密, 读取时透明地还原.
+type CodecStore struct {
+	blockchain.Store
+
+	compression Compression
+	aead        cipher.AEAD
+	encoder     *zstd.Encoder
+	decoder     *zstd.Decoder
+
+	// marked为true时所有的数据都经过编码, 否则根据codecMagic判断
+	marked bool
+}
+
+// NewCodecStore 创建一个CodecStore.
+func NewCodecStore(s blockchain.Store, opts CodecOptions) (*CodecStore, error) {
+	if opts.Compression > CompressionZstd {
+		return nil, fmt.Errorf("unknown compression: %s", opts.Compression)
+	}
+
+	cs := &CodecStore{
+		Store:       s,
+		compression: opts.Compression,
+	}
+
+	if len(opts.Key) > 0 {
+		block, err := aes.NewCipher(opts.Key)
+		if err != nil {
+			return nil, err
+		}
+		cs.aead, err = cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if meta, ok := s.(MetaStore); ok {
+		var err error
+		if cs.marked, err = markCodec(s, meta); err != nil {
+			return nil, err
+		}
+	}
+
+	var err error
+	cs.encoder, err = zstd.NewWriter(nil)
+	if err != nil {
+		return nil, err
+	}
+	cs.decoder, err = zstd.NewReader(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return cs, nil
+}
+
+// DeriveKey 根据节点的私钥派生出存储使用的AES-256密钥.
+func DeriveKey(privateKey string) ([]byte, error) {
+	priKey, err := hex.DecodeString(privateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	h := sha256.New()
+	h.Write([]byte("blockchain store key"))
+	h.Write(priKey)
+	return h.Sum(nil), nil
+}
+
+// Get 查找指定的区块并还原数据.
+func (s *CodecStore) Get(height uint64) (*blockchain.Block, error) {
+	block, err := s.Store.Get(height)
+	if err != nil {
+		return nil, err
+	}
+
+	block.Data, err = s.decode(height, block.Data)
+	return block, err
+}
+
+// Add 编码区块数据之后再写入.
+func (s *CodecStore) Add(height uint64, block *blockchain.Block) error {
+	data, err := s.encode(height, block.Data)
+	if err != nil {
+		return err
+	}
+
+	// 不能修改调用者的区块, 它可能还被区块链引用
+	encoded := *block
+	encoded.Data = data
+	return s.Store.Add(height, &encoded)
+}
+
+// GetBatch 得到一批数据并还原.
+func (s *CodecStore) GetBatch(height uint64, count int) ([]*blockchain.Block, error) {
+	blocks, err := s.Store.GetBatch(height, count)
+	if err != nil {
+		return blocks, err
+	}
+
+	for _, block := range blocks {
+		block.Data, err = s.decode(block.Height, block.Data)
+		if err != nil {
+			return blocks, err
+		}
+	}
+	return blocks, nil
+}
+
+// Close 关闭底层的Store.
+func (s *CodecStore) Close() error {
+	s.encoder.Close()
+	s.decoder.Close()
+	return s.Store.Close()
+}
+
+// Prune 裁剪底层的Store. 被裁剪的区块没有数据, 读取时原样返回.
+func (s *CodecStore) Prune(height uint64) error {
+	pruner, ok := s.Store.(blockchain.Pruner)
+	if !ok {
+		return blockchain.ErrPruneUnsupported
+	}
+	return pruner.Prune(height)
+}
+
+// PrunedHeight 返回底层的Store已经裁剪到的高度.
+func (s *CodecStore) PrunedHeight() (uint64, error) {
+	pruner, ok := s.Store.(blockchain.Pruner)
+	if !ok {
+		return 0, nil
+	}
+	return pruner.PrunedHeight()
+}
+
+// markCodec 判断数据目录是否由CodecStore创建, 新的数据目录会被标记.
+func markCodec(s blockchain.Store, meta MetaStore) (bool, error) {
+	data, err := meta.GetMeta(codecKey)
+	if err == nil {
+		return len(data) == 1 && data[0] == 1, nil
+	}
+	if err != blockchain.ErrNotFound {
+		return false, err
+	}
+
+	// 已经有区块的旧数据目录不能标记, 其中可能有原始数据
+	exist, err := s.Exist(0)
+	if err != nil || exist {
+		return false, err
+	}
+	return true, meta.PutMeta(codecKey, []byte{1})
+}
+
+func (s *CodecStore) encode(height uint64, data []byte) ([]byte, error) {
+	flags := byte(s.compression)
+
+	switch s.compression {
+	case CompressionSnappy:
+		data = snappy.Encode(nil, data)
+	case CompressionZstd:
+		data = s.encoder.EncodeAll(data, nil)
+	}
+
+	var buf = make([]byte, 0, len(codecMagic)+1+len(data))
+	buf = append(buf, codecMagic...)
+
+	if s.aead == nil {
+		buf = append(buf, flags)
+		return append(buf, data...), nil
+	}
+
+	flags |= flagEncrypted
+	buf = append(buf, flags)
+
+	nonce := make([]byte, s.aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	buf = append(buf, nonce...)
+
+	// 把高度作为附加数据, 防止密文被挪到别的区块上
+	return s.aead.Seal(buf, nonce, data, blockchain.Int2Bytes(height)), nil
+}
+
+func (s *CodecStore) decode(height uint64, data []byte) ([]byte, error) {
+	// 被裁剪的区块没有数据
+	if len(data) == 0 {
+		return data, nil
+	}
+
+	if s.marked {
+		if !hasCodecMagic(data) {
+			return nil, ErrCorrupted
+		}
+		return s.decodeEnvelope(height, data)
+	}
+
+	// 旧的数据目录只能根据前缀判断.
+	// 恰好以codecMagic开头的原始数据无法解码时作为原始数据返回, 但是解密失败依然是错误
+	if !hasCodecMagic(data) {
+		return data, nil
+	}
+	decoded, err := s.decodeEnvelope(height, data)
+	if err == ErrCorrupted || (err != nil && data[len(codecMagic)]&flagEncrypted == 0) {
+		return data, nil
+	}
+	return decoded, err
+}
+
+func hasCodecMagic(data []byte) bool {
+	return len(data) > len(codecMagic) && string(data[:len(codecMagic)]) == string(codecMagic)
+}
+
+func (s *CodecStore) decodeEnvelope(height uint64, data []byte) ([]byte, error) {
+	flags := data[len(codecMagic)]
+	data = data[len(codecMagic)+1:]
+	if flags&^(flagCompressionMask|flagEncrypted) != 0 {
+		return nil, ErrCorrupted
+	}
+
+	if flags&flagEncrypted != 0 {
+		if s.aead == nil {
+			return nil, ErrEncrypted
+		}
+
+		nonceSize := s.aead.NonceSize()
+		if len(data) < nonceSize {
+			return nil, ErrCorrupted
+		}
+
+		var err error
+		data, err = s.aead.Open(nil, data[:nonceSize], data[nonceSize:], blockchain.Int2Bytes(height))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	switch Compression(flags & flagCompressionMask) {
+	case CompressionNone:
+		return data, nil
+	case CompressionSnappy:
+		return snappy.Decode(nil, data)
+	case CompressionZstd:
+		return s.decoder.DecodeAll(data, nil)
+	default:
+		return nil, ErrCorrupted
+	}
+}
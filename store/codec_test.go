@@ -0,0 +1,162 @@
+package store
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/smallnest/blockchain"
+)
+
+func newCodecStore(t *testing.T, dir string, opts CodecOptions) (*CodecStore, *LevelDBStore) {
+	t.Helper()
+	db, err := NewLevelDBStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cs, err := NewCodecStore(db, opts)
+	if err != nil {
+		db.Close()
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { cs.Close() })
+	return cs, db
+}
+
+func TestCodecRoundTrip(t *testing.T) {
+	key := bytes.Repeat([]byte{7}, 32)
+	data := []byte(strings.Repeat("飞鸽传输", 100))
+
+	for _, c := range []Compression{CompressionNone, CompressionSnappy, CompressionZstd} {
+		for _, encrypted := range []bool{false, true} {
+			opts := CodecOptions{Compression: c}
+			if encrypted {
+				opts.Key = key
+			}
+			cs, db := newCodecStore(t, t.TempDir(), opts)
+
+			for h := uint64(0); h < 3; h++ {
+				if err := cs.Add(h, &blockchain.Block{Height: h, Data: data}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			block, err := cs.Get(1)
+			if err != nil || !bytes.Equal(block.Data, data) {
+				t.Fatalf("%s, encrypted=%v: failed to read block: %v", c, encrypted, err)
+			}
+			blocks, err := cs.GetBatch(0, 10)
+			if err != nil || len(blocks) != 3 || !bytes.Equal(blocks[2].Data, data) {
+				t.Fatalf("%s, encrypted=%v: failed to read blocks: %v", c, encrypted, err)
+			}
+
+			raw, _ := db.Get(1)
+			if encrypted && bytes.Contains(raw.Data, []byte("飞鸽传输")) {
+				t.Errorf("%s: stored data is not encrypted", c)
+			}
+			if c != CompressionNone && len(raw.Data) >= len(data) {
+				t.Errorf("%s, encrypted=%v: stored data is not compressed", c, encrypted)
+			}
+		}
+	}
+}
+
+func TestCodecWrongKey(t *testing.T) {
+	dir := t.TempDir()
+	cs, _ := newCodecStore(t, dir, CodecOptions{Key: bytes.Repeat([]byte{1}, 32)})
+	if err := cs.Add(0, &blockchain.Block{Data: []byte("secret")}); err != nil {
+		t.Fatal(err)
+	}
+	cs.Close()
+
+	cs, _ = newCodecStore(t, dir, CodecOptions{Key: bytes.Repeat([]byte{2}, 32)})
+	if _, err := cs.Get(0); err == nil {
+		t.Error("expect error for a wrong key")
+	}
+	cs.Close()
+
+	cs, _ = newCodecStore(t, dir, CodecOptions{})
+	if _, err := cs.Get(0); err != ErrEncrypted {
+		t.Errorf("expect ErrEncrypted but got %v", err)
+	}
+}
+
+// 加密的数据绑定了区块的高度, 挪到其它高度之后无法解密.
+func TestCodecHeightAAD(t *testing.T) {
+	cs, db := newCodecStore(t, t.TempDir(), CodecOptions{Key: bytes.Repeat([]byte{1}, 32)})
+	if err := cs.Add(1, &blockchain.Block{Height: 1, Data: []byte("block 1")}); err != nil {
+		t.Fatal(err)
+	}
+
+	raw, err := db.Get(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = db.Add(2, raw); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = cs.Get(2); err == nil {
+		t.Error("expect error for data moved to another height")
+	}
+}
+
+// 旧的数据目录中恰好以codecMagic开头的原始数据按原样返回.
+func TestCodecLegacyData(t *testing.T) {
+	dir := t.TempDir()
+	legacy := [][]byte{
+		append(append([]byte{}, codecMagic...), 0x07, 'a'), // 未知的flags
+		append(append([]byte{}, codecMagic...), 0x01, 'a'), // 不是snappy数据
+		[]byte("plain"),
+	}
+
+	db, err := NewLevelDBStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, data := range legacy {
+		if err = db.Add(uint64(i), &blockchain.Block{Height: uint64(i), Data: data}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	db.Close()
+
+	cs, _ := newCodecStore(t, dir, CodecOptions{Compression: CompressionZstd})
+	if cs.marked {
+		t.Fatal("store with existing blocks should not be marked")
+	}
+	for i, data := range legacy {
+		block, err := cs.Get(uint64(i))
+		if err != nil || !bytes.Equal(block.Data, data) {
+			t.Errorf("legacy block %d: expect %x but got %v, %v", i, data, block, err)
+		}
+	}
+
+	// 新的数据依然可以读取
+	if err = cs.Add(3, &blockchain.Block{Height: 3, Data: []byte("new")}); err != nil {
+		t.Fatal(err)
+	}
+	if block, err := cs.Get(3); err != nil || string(block.Data) != "new" {
+		t.Errorf("failed to read new block: %v", err)
+	}
+}
+
+// 新的数据目录被标记之后, 所有的数据都必须经过编码.
+func TestCodecMarked(t *testing.T) {
+	dir := t.TempDir()
+	cs, db := newCodecStore(t, dir, CodecOptions{})
+	if !cs.marked {
+		t.Fatal("new store should be marked")
+	}
+	if err := db.Add(0, &blockchain.Block{Data: []byte("raw")}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cs.Get(0); err != ErrCorrupted {
+		t.Errorf("expect ErrCorrupted but got %v", err)
+	}
+	cs.Close()
+
+	// 标记保存在数据目录中
+	cs, _ = newCodecStore(t, dir, CodecOptions{})
+	if !cs.marked {
+		t.Error("reopened store should be marked")
+	}
+}
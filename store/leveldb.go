@@ -1,6 +1,9 @@
 package store
 
 import (
+	"encoding/binary"
+	"fmt"
+
 	"github.com/smallnest/blockchain"
 	"github.com/syndtr/goleveldb/leveldb"
 	"github.com/syndtr/goleveldb/leveldb/errors"
@@ -8,11 +11,32 @@ import (
 	"github.com/syndtr/goleveldb/leveldb/opt"
 )
 
-var _ blockchain.Store = &LevelDBStore{}
+var (
+	_ blockchain.Store  = &LevelDBStore{}
+	_ blockchain.Pruner = &LevelDBStore{}
+)
+
+// 区块的key都是8个字节, 元数据的key长度不同, 遍历区块时会被跳过.
+var (
+	// prunedHeightKey 记录裁剪到的高度.
+	prunedHeightKey = []byte("meta:pruned")
+	// formatKey 记录区块的编码版本, 没有这个key的数据目录使用版本0.
+	formatKey = []byte("meta:format")
+)
+
+// metaPrefix 是元数据的key的前缀.
+const metaPrefix = "meta:"
+
+// ErrStoreOutdated 数据目录使用的是旧的编码版本, 只能读取, 需要迁移后才能写入.
+var ErrStoreOutdated = fmt.Errorf("store format is outdated, please migrate it to version %d", blockchain.BlockFormatVersion)
+
+// pruneBatchSize 裁剪时每批写入的区块数.
+const pruneBatchSize = 1000
 
 // LevelDBStore 基于leveldb实现的Store
 type LevelDBStore struct {
-	db *leveldb.DB
+	db     *leveldb.DB
+	format byte
 }
 
 func convertLevelDBError(err error) error {
@@ -40,9 +64,63 @@ func NewLevelDBStore(dataFile string) (*LevelDBStore, error) {
 	store := &LevelDBStore{
 		db: db,
 	}
+	if err = store.loadFormat(); err != nil {
+		db.Close()
+		return nil, err
+	}
 	return store, nil
 }
 
+// loadFormat 读取数据目录的编码版本, 新的数据目录使用最新的版本.
+func (s *LevelDBStore) loadFormat() error {
+	data, err := s.db.Get(formatKey, nil)
+	if err == nil {
+		if len(data) != 1 || data[0] > blockchain.BlockFormatVersion {
+			return fmt.Errorf("%w: %v", blockchain.ErrUnknownVersion, data)
+		}
+		s.format = data[0]
+		return nil
+	}
+	if err != errors.ErrNotFound {
+		return err
+	}
+
+	iter := s.db.NewIterator(nil, nil)
+	defer iter.Release()
+	for iter.Next() {
+		if len(iter.Key()) == 8 {
+			// 已经有区块, 是旧版本的数据目录
+			s.format = 0
+			return iter.Error()
+		}
+	}
+	if err = iter.Error(); err != nil {
+		return err
+	}
+
+	s.format = blockchain.BlockFormatVersion
+	return s.db.Put(formatKey, []byte{s.format}, nil)
+}
+
+// FormatVersion 返回数据目录使用的编码版本.
+func (s *LevelDBStore) FormatVersion() byte {
+	return s.format
+}
+
+func (s *LevelDBStore) decode(data []byte) (*blockchain.Block, error) {
+	if s.format == 0 {
+		return blockchain.DecodeBlockVersion(0, data)
+	}
+	return blockchain.DecodeBlock(data)
+}
+
+func (s *LevelDBStore) encode(block *blockchain.Block) ([]byte, error) {
+	if s.format != blockchain.BlockFormatVersion {
+		return nil, ErrStoreOutdated
+	}
+	return blockchain.EncodeBlock(block)
+}
+
 // Get 查找指定的区块链
 func (s *LevelDBStore) Get(height uint64) (*blockchain.Block, error) {
 	key := blockchain.Int2Bytes(height)
@@ -51,15 +129,13 @@ func (s *LevelDBStore) Get(height uint64) (*blockchain.Block, error) {
 		return nil, convertLevelDBError(err)
 	}
 
-	var block = &blockchain.Block{}
-	_, err = block.Unmarshal(data)
-	return block, err
+	return s.decode(data)
 }
 
 // Add 增加一个区块.
 func (s *LevelDBStore) Add(height uint64, block *blockchain.Block) error {
 	key := blockchain.Int2Bytes(height)
-	data, err := block.Marshal(nil)
+	data, err := s.encode(block)
 	if err != nil {
 		return err
 	}
@@ -75,11 +151,11 @@ func (s *LevelDBStore) GetBatch(height uint64, count int) ([]*blockchain.Block,
 	iter := s.db.NewIterator(nil, nil)
 	defer iter.Release()
 
-	var err error
-	for ok := iter.Seek(key); ok; ok = iter.Next() {
-		value := iter.Value()
-		var block = &blockchain.Block{}
-		_, err = block.Unmarshal(value)
+	for ok := iter.Seek(key); ok && len(blocks) < count; ok = iter.Next() {
+		if len(iter.Key()) != len(key) {
+			continue
+		}
+		block, err := s.decode(iter.Value())
 		if err != nil {
 			return blocks, err
 		}
@@ -95,6 +171,72 @@ func (s *LevelDBStore) Exist(height uint64) (bool, error) {
 	return s.db.Has(key, nil)
 }
 
+// Prune 丢弃高度小于height的区块的数据, 只保留区块头.
+func (s *LevelDBStore) Prune(height uint64) error {
+	start, err := s.PrunedHeight()
+	if err != nil {
+		return err
+	}
+
+	batch := new(leveldb.Batch)
+	for h := start; h < height; h++ {
+		key := blockchain.Int2Bytes(h)
+		data, err := s.db.Get(key, nil)
+		if err == errors.ErrNotFound {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		block, err := s.decode(data)
+		if err != nil {
+			return err
+		}
+		block.Data = nil
+		if data, err = s.encode(block); err != nil {
+			return err
+		}
+		batch.Put(key, data)
+
+		if batch.Len() >= pruneBatchSize {
+			batch.Put(prunedHeightKey, blockchain.Int2Bytes(h+1))
+			if err = s.db.Write(batch, nil); err != nil {
+				return err
+			}
+			batch.Reset()
+		}
+	}
+
+	if height > start {
+		batch.Put(prunedHeightKey, blockchain.Int2Bytes(height))
+	}
+	return s.db.Write(batch, nil)
+}
+
+// PrunedHeight 返回已经裁剪到的高度.
+func (s *LevelDBStore) PrunedHeight() (uint64, error) {
+	data, err := s.db.Get(prunedHeightKey, nil)
+	if err == errors.ErrNotFound {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint64(data), nil
+}
+
+// GetMeta 读取名字为key的元数据, 不存在时返回blockchain.ErrNotFound.
+func (s *LevelDBStore) GetMeta(key string) ([]byte, error) {
+	data, err := s.db.Get([]byte(metaPrefix+key), nil)
+	return data, convertLevelDBError(err)
+}
+
+// PutMeta 写入名字为key的元数据.
+func (s *LevelDBStore) PutMeta(key string, value []byte) error {
+	return s.db.Put([]byte(metaPrefix+key), value, nil)
+}
+
 // Close 关闭db.
 func (s *LevelDBStore) Close() error {
 	return s.db.Close()
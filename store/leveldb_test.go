@@ -0,0 +1,87 @@
+package store
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/smallnest/blockchain"
+	"github.com/syndtr/goleveldb/leveldb"
+)
+
+func TestLegacyFormat(t *testing.T) {
+	dir := t.TempDir()
+
+	// 写入没有版本号的旧格式数据
+	db, err := leveldb.OpenFile(dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	legacy := &blockchain.Block{Height: 0, Hash: "genesis", Data: []byte("legacy")}
+	data, _ := legacy.Marshal(nil)
+	db.Put(blockchain.Int2Bytes(0), data, nil)
+	db.Close()
+
+	s, err := NewLevelDBStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.FormatVersion() != 0 {
+		t.Fatalf("expect format version 0 but got %d", s.FormatVersion())
+	}
+	block, err := s.Get(0)
+	if err != nil || string(block.Data) != "legacy" {
+		t.Fatalf("failed to read legacy block: %v", err)
+	}
+	if err = s.Add(1, &blockchain.Block{Height: 1}); err != ErrStoreOutdated {
+		t.Fatalf("expect ErrStoreOutdated but got %v", err)
+	}
+	s.Close()
+
+	// 新的数据目录使用最新的版本
+	s, err = NewLevelDBStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if s.FormatVersion() != blockchain.BlockFormatVersion {
+		t.Fatalf("expect format version %d but got %d", blockchain.BlockFormatVersion, s.FormatVersion())
+	}
+	if err = s.Add(0, legacy); err != nil {
+		t.Fatal(err)
+	}
+	blocks, err := s.GetBatch(0, 10)
+	if err != nil || len(blocks) != 1 || blocks[0].Hash != "genesis" {
+		t.Fatalf("failed to read blocks: %v", err)
+	}
+}
+
+// 损坏的数据返回错误而不是panic.
+func TestCorruptBlock(t *testing.T) {
+	s, err := NewLevelDBStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	if err = s.Add(0, &blockchain.Block{Hash: "genesis", Data: []byte("data")}); err != nil {
+		t.Fatal(err)
+	}
+	data, err := s.db.Get(blockchain.Int2Bytes(0), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, corrupt := range [][]byte{data[:len(data)/2], {blockchain.BlockFormatVersion, 0x02}} {
+		if err = s.db.Put(blockchain.Int2Bytes(1), corrupt, nil); err != nil {
+			t.Fatal(err)
+		}
+		if _, err = s.Get(1); !errors.Is(err, blockchain.ErrMalformedBlock) {
+			t.Errorf("Get: expect ErrMalformedBlock but got %v", err)
+		}
+		if _, err = s.GetBatch(0, 10); !errors.Is(err, blockchain.ErrMalformedBlock) {
+			t.Errorf("GetBatch: expect ErrMalformedBlock but got %v", err)
+		}
+		if err = s.Prune(2); !errors.Is(err, blockchain.ErrMalformedBlock) {
+			t.Errorf("Prune: expect ErrMalformedBlock but got %v", err)
+		}
+	}
+}
@@ -0,0 +1,272 @@
+package store
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/smallnest/blockchain"
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/filter"
+	"github.com/syndtr/goleveldb/leveldb/opt"
+)
+
+var (
+	_ blockchain.Snapshotter = &LevelDBStore{}
+	_ blockchain.Snapshotter = &CodecStore{}
+)
+
+// ErrInvalidSnapshot 快照压缩包中有带路径的文件, 可能是被篡改的.
+var ErrInvalidSnapshot = errors.New("invalid snapshot")
+
+// snapshotBatchSize 复制快照时每批写入的记录数.
+const snapshotBatchSize = 1000
+
+// IsTarball 判断路径是否是快照压缩包.
+func IsTarball(path string) bool {
+	return strings.HasSuffix(path, ".tar.gz") || strings.HasSuffix(path, ".tgz")
+}
+
+// Snapshot 利用leveldb的快照把某一时刻一致的数据写入path, 写入时不影响正常的读写.
+// 返回快照中最高的区块的高度.
+func (s *LevelDBStore) Snapshot(path string) (uint64, error) {
+	if _, err := os.Stat(path); err == nil {
+		return 0, fmt.Errorf("snapshot %s already exists", path)
+	}
+
+	if !IsTarball(path) {
+		return s.snapshotToDir(path)
+	}
+
+	tmpDir, err := os.MkdirTemp(filepath.Dir(path), ".snapshot-")
+	if err != nil {
+		return 0, err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	dir := filepath.Join(tmpDir, "data")
+	height, err := s.snapshotToDir(dir)
+	if err != nil {
+		return 0, err
+	}
+
+	// 先写临时文件, 保证path要么不存在要么是完整的快照
+	tmpFile := filepath.Join(tmpDir, "snapshot.tar.gz")
+	if err = tarDir(tmpFile, dir); err != nil {
+		return 0, err
+	}
+	return height, os.Rename(tmpFile, path)
+}
+
+// snapshotToDir 把快照写入新的数据目录dir, 返回快照中最高的区块的高度.
+func (s *LevelDBStore) snapshotToDir(dir string) (height uint64, err error) {
+	snap, err := s.db.GetSnapshot()
+	if err != nil {
+		return 0, err
+	}
+	defer snap.Release()
+
+	o := &opt.Options{
+		Filter:       filter.NewBloomFilter(10),
+		ErrorIfExist: true,
+	}
+	db, err := leveldb.OpenFile(dir, o)
+	if err != nil {
+		return 0, err
+	}
+
+	iter := snap.NewIterator(nil, nil)
+	defer iter.Release()
+
+	batch := new(leveldb.Batch)
+	for iter.Next() {
+		// 区块的key是8字节的高度, 其它的是元数据
+		if key := iter.Key(); len(key) == 8 && binary.BigEndian.Uint64(key) > height {
+			height = binary.BigEndian.Uint64(key)
+		}
+		batch.Put(iter.Key(), iter.Value())
+		if batch.Len() >= snapshotBatchSize {
+			if err = db.Write(batch, nil); err != nil {
+				db.Close()
+				return 0, err
+			}
+			batch.Reset()
+		}
+	}
+	if err = iter.Error(); err != nil {
+		db.Close()
+		return 0, err
+	}
+	if err = db.Write(batch, nil); err != nil {
+		db.Close()
+		return 0, err
+	}
+	return height, db.Close()
+}
+
+// Snapshot 对底层的Store做快照, 数据保持编码后的形式.
+func (s *CodecStore) Snapshot(path string) (uint64, error) {
+	snapshotter, ok := s.Store.(blockchain.Snapshotter)
+	if !ok {
+		return 0, blockchain.ErrSnapshotUnsupported
+	}
+	return snapshotter.Snapshot(path)
+}
+
+// Restore 用快照恢复出数据目录dataDir, dataDir必须不存在. 恢复失败时删除不完整的dataDir.
+func Restore(snapshot, dataDir string) error {
+	if _, err := os.Stat(dataDir); err == nil {
+		return fmt.Errorf("data directory %s already exists", dataDir)
+	}
+
+	var err error
+	if IsTarball(snapshot) {
+		err = untar(snapshot, dataDir)
+	} else {
+		err = copyDir(snapshot, dataDir)
+	}
+	if err != nil {
+		os.RemoveAll(dataDir)
+	}
+	return err
+}
+
+// tarDir 把目录dir中的文件打包成file.
+func tarDir(file, dir string) error {
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	gw := gzip.NewWriter(f)
+	tw := tar.NewWriter(gw)
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		if err = addTarFile(tw, filepath.Join(dir, entry.Name())); err != nil {
+			return err
+		}
+	}
+
+	if err = tw.Close(); err != nil {
+		return err
+	}
+	if err = gw.Close(); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+func addTarFile(tw *tar.Writer, file string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	hdr, err := tar.FileInfoHeader(fi, "")
+	if err != nil {
+		return err
+	}
+	if err = tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	_, err = io.Copy(tw, f)
+	return err
+}
+
+func untar(file, dir string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	gr, err := gzip.NewReader(f)
+	if err != nil {
+		return err
+	}
+	defer gr.Close()
+
+	if err = os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	tr := tar.NewReader(gr)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		// 快照中只有平铺的文件, 拒绝带路径的文件防止写到dir之外
+		name := hdr.Name
+		if name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+			return fmt.Errorf("%w: unexpected file %q", ErrInvalidSnapshot, hdr.Name)
+		}
+		if err = writeFile(filepath.Join(dir, name), tr); err != nil {
+			return err
+		}
+	}
+}
+
+func copyDir(src, dst string) error {
+	entries, err := os.ReadDir(src)
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(dst, 0755); err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		f, err := os.Open(filepath.Join(src, entry.Name()))
+		if err != nil {
+			return err
+		}
+		err = writeFile(filepath.Join(dst, entry.Name()), f)
+		f.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeFile(file string, r io.Reader) error {
+	f, err := os.OpenFile(file, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
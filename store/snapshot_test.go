@@ -0,0 +1,87 @@
+package store
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/smallnest/blockchain"
+)
+
+func TestSnapshotRestore(t *testing.T) {
+	s, err := NewLevelDBStore(filepath.Join(t.TempDir(), "data"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	for i := uint64(0); i < 3; i++ {
+		if err = s.Add(i, &blockchain.Block{Height: i, Data: []byte("snapshot")}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	dir := t.TempDir()
+	for _, name := range []string{"snapshot", "snapshot.tar.gz"} {
+		snapshot := filepath.Join(dir, name)
+		height, err := s.Snapshot(snapshot)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if height != 2 {
+			t.Errorf("%s: expect height 2 but got %d", name, height)
+		}
+		if _, err = s.Snapshot(snapshot); err == nil {
+			t.Errorf("%s: expect an error for an existing snapshot", name)
+		}
+
+		dataDir := filepath.Join(dir, name+".restored")
+		if err = Restore(snapshot, dataDir); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if err = Restore(snapshot, dataDir); err == nil {
+			t.Errorf("%s: expect an error for an existing data directory", name)
+		}
+
+		restored, err := NewLevelDBStore(dataDir)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		blocks, err := restored.GetBatch(0, 10)
+		restored.Close()
+		if err != nil || len(blocks) != 3 || string(blocks[2].Data) != "snapshot" {
+			t.Fatalf("%s: expect 3 restored blocks but got %d: %v", name, len(blocks), err)
+		}
+	}
+}
+
+func TestRestoreRejectsPath(t *testing.T) {
+	dir := t.TempDir()
+	snapshot := filepath.Join(dir, "evil.tar.gz")
+
+	f, err := os.Create(snapshot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gw := gzip.NewWriter(f)
+	tw := tar.NewWriter(gw)
+	content := []byte("evil")
+	tw.WriteHeader(&tar.Header{Name: "../evil", Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg})
+	tw.Write(content)
+	tw.Close()
+	gw.Close()
+	f.Close()
+
+	dataDir := filepath.Join(dir, "data")
+	if err = Restore(snapshot, dataDir); !errors.Is(err, ErrInvalidSnapshot) {
+		t.Fatalf("expect ErrInvalidSnapshot but got %v", err)
+	}
+	if _, err = os.Stat(filepath.Join(dir, "evil")); !os.IsNotExist(err) {
+		t.Error("file outside the data directory should not be written")
+	}
+	if _, err = os.Stat(dataDir); !os.IsNotExist(err) {
+		t.Error("incomplete data directory should be removed")
+	}
+}
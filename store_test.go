@@ -0,0 +1,83 @@
+package blockchain
+
+import "testing"
+
+// memStore 是测试用的内存Store.
+type memStore struct {
+	blocks       map[uint64]*Block
+	prunedHeight uint64
+}
+
+func newMemStore() *memStore {
+	return &memStore{blocks: make(map[uint64]*Block)}
+}
+
+func (s *memStore) Get(height uint64) (*Block, error) {
+	block, ok := s.blocks[height]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return block, nil
+}
+
+func (s *memStore) Add(height uint64, block *Block) error {
+	s.blocks[height] = block
+	return nil
+}
+
+func (s *memStore) GetBatch(height uint64, count int) ([]*Block, error) {
+	var blocks []*Block
+	for i := height; i < height+uint64(count); i++ {
+		block, ok := s.blocks[i]
+		if !ok {
+			break
+		}
+		blocks = append(blocks, block)
+	}
+	return blocks, nil
+}
+
+func (s *memStore) Exist(height uint64) (bool, error) {
+	_, ok := s.blocks[height]
+	return ok, nil
+}
+
+func (s *memStore) Close() error {
+	return nil
+}
+
+func (s *memStore) Prune(height uint64) error {
+	for i := s.prunedHeight; i < height; i++ {
+		if block, ok := s.blocks[i]; ok {
+			pruned := *block
+			pruned.Data = nil
+			s.blocks[i] = &pruned
+		}
+	}
+	if height > s.prunedHeight {
+		s.prunedHeight = height
+	}
+	return nil
+}
+
+func (s *memStore) PrunedHeight() (uint64, error) {
+	return s.prunedHeight, nil
+}
+
+func newTestBlockchain(t *testing.T, n int) *Blockchain {
+	bc := &Blockchain{
+		Store:      newMemStore(),
+		Difficulty: 1,
+		PrefixZero: "0",
+	}
+	if err := bc.GenerateGenesisBlock(); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < n; i++ {
+		prevBlock := bc.Blocks[len(bc.Blocks)-1]
+		if err := bc.AddBlock(bc.generateBlock(prevBlock, []byte{byte(i)})); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return bc
+}
@@ -0,0 +1,189 @@
+package blockchain
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/julienschmidt/httprouter"
+)
+
+const (
+	// streamPingInterval 推送流的心跳间隔.
+	streamPingInterval = 30 * time.Second
+	// streamWriteWait 推送一条消息的超时时间.
+	streamWriteWait = 10 * time.Second
+)
+
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+}
+
+// streamStart 解析推送的起始高度.
+// 优先使用参数from, 其次是SSE断线重连时带上的Last-Event-ID, 都没有则只推送新的区块.
+func (s *Server) streamStart(r *http.Request) (uint64, error) {
+	if from := r.FormValue("from"); from != "" {
+		return strconv.ParseUint(from, 10, 64)
+	}
+	if lastID := r.Header.Get("Last-Event-ID"); lastID != "" {
+		height, err := strconv.ParseUint(lastID, 10, 64)
+		if err != nil {
+			return 0, err
+		}
+		return height + 1, nil
+	}
+
+	s.Blockchain.RLock()
+	defer s.Blockchain.RUnlock()
+	return uint64(len(s.Blockchain.Blocks)), nil
+}
+
+// handleStreamBlocks 推送新增的区块.
+// 请求是websocket握手时使用websocket, 否则使用Server-Sent Events.
+func (s *Server) handleStreamBlocks(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	from, err := s.streamStart(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if websocket.IsWebSocketUpgrade(r) {
+		s.streamWebSocket(w, r, from)
+		return
+	}
+	s.streamSSE(w, r, from)
+}
+
+func (s *Server) streamSSE(w http.ResponseWriter, r *http.Request, from uint64) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	// 推送是长连接, 不能受http.Server的WriteTimeout限制
+	rc := http.NewResponseController(w)
+	rc.SetWriteDeadline(time.Time{})
+
+	quit := s.done()
+	backlog, blocks, cancel := s.Blockchain.Subscribe(from)
+	defer cancel()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	writeEvent := func(block *Block) error {
+		data, err := json.Marshal(block)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w, "id: %d\nevent: block\ndata: %s\n\n", block.Height, data)
+		return err
+	}
+
+	for _, block := range backlog {
+		if err := writeEvent(block); err != nil {
+			return
+		}
+	}
+	flusher.Flush()
+
+	ticker := time.NewTicker(streamPingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case block, ok := <-blocks:
+			if !ok {
+				// 处理太慢被断开, 客户端可以使用Last-Event-ID继续
+				return
+			}
+			if err := writeEvent(block); err != nil {
+				return
+			}
+		case <-ticker.C:
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return
+			}
+		case <-r.Context().Done():
+			return
+		case <-quit:
+			return
+		}
+		flusher.Flush()
+	}
+}
+
+func (s *Server) streamWebSocket(w http.ResponseWriter, r *http.Request, from uint64) {
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// Upgrade已经返回了错误信息
+		return
+	}
+	defer conn.Close()
+
+	quit := s.done()
+	backlog, blocks, cancel := s.Blockchain.Subscribe(from)
+	defer cancel()
+
+	// 读取客户端的消息以便处理pong和关闭
+	closed := make(chan struct{})
+	conn.SetReadDeadline(time.Now().Add(2 * streamPingInterval))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(2 * streamPingInterval))
+	})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	writeBlock := func(block *Block) error {
+		conn.SetWriteDeadline(time.Now().Add(streamWriteWait))
+		return conn.WriteJSON(block)
+	}
+
+	for _, block := range backlog {
+		if err := writeBlock(block); err != nil {
+			return
+		}
+	}
+
+	ticker := time.NewTicker(streamPingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case block, ok := <-blocks:
+			if !ok {
+				conn.WriteControl(websocket.CloseMessage,
+					websocket.FormatCloseMessage(websocket.CloseTryAgainLater, "subscriber is too slow"),
+					time.Now().Add(streamWriteWait))
+				return
+			}
+			if err := writeBlock(block); err != nil {
+				return
+			}
+		case <-ticker.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(streamWriteWait)); err != nil {
+				return
+			}
+		case <-closed:
+			return
+		case <-quit:
+			conn.WriteControl(websocket.CloseMessage,
+				websocket.FormatCloseMessage(websocket.CloseGoingAway, "server is shutting down"),
+				time.Now().Add(streamWriteWait))
+			return
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package blockchain
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestStreamBlocks(t *testing.T) {
+	bc := newTestBlockchain(t, 2)
+	s := &Server{Blockchain: bc}
+	ts := httptest.NewServer(s.configRouter())
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/blocks/stream?from=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	ws, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http")+"/blocks/stream?from=2", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ws.Close()
+
+	bc.Lock()
+	bc.AddBlock(bc.generateBlock(bc.Blocks[2], []byte("new")))
+	bc.Unlock()
+
+	// SSE: 先收到已有的区块1和2, 再收到新的区块3
+	reader := bufio.NewReader(resp.Body)
+	var ids []string
+	for len(ids) < 3 {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.HasPrefix(line, "id: ") {
+			ids = append(ids, strings.TrimSpace(line[4:]))
+		}
+	}
+	if strings.Join(ids, ",") != "1,2,3" {
+		t.Fatalf("unexpected event ids: %v", ids)
+	}
+
+	for _, height := range []uint64{2, 3} {
+		var block Block
+		if err := ws.ReadJSON(&block); err != nil {
+			t.Fatal(err)
+		}
+		if block.Height != height {
+			t.Fatalf("expect block %d but got %d", height, block.Height)
+		}
+	}
+}
@@ -0,0 +1,180 @@
+package blockchain
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/smallnest/log"
+)
+
+// certCheckInterval 检查证书文件是否更新的最小间隔.
+const certCheckInterval = time.Second
+
+// TLSOptions 是rpc服务器的TLS配置.
+type TLSOptions struct {
+	// 服务器的证书和私钥
+	CertFile string
+	KeyFile  string
+	// 签发客户端证书的CA, 设置后要求客户端出示由它签发的证书(mutual TLS)
+	ClientCAFile string
+}
+
+// NewTLSConfig 根据配置创建tls.Config.
+// 证书和CA文件更新后, 新的连接会自动使用新的文件, 不需要重启节点.
+func NewTLSConfig(opts TLSOptions) (*tls.Config, error) {
+	if opts.CertFile == "" || opts.KeyFile == "" {
+		return nil, errors.New("both certificate and key are required")
+	}
+
+	r := &certReloader{opts: opts}
+	if err := r.reload(); err != nil {
+		return nil, err
+	}
+
+	config := &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: r.getCertificate,
+	}
+	if opts.ClientCAFile != "" {
+		// 自己校验客户端证书, 这样每次握手都使用最新的CA
+		config.ClientAuth = tls.RequireAnyClientCert
+		config.VerifyConnection = r.verifyClient
+	}
+	return config, nil
+}
+
+// certReloader 在文件修改后重新加载证书.
+type certReloader struct {
+	opts TLSOptions
+
+	mu        sync.RWMutex
+	cert      *tls.Certificate
+	clientCAs *x509.CertPool
+	modTimes  map[string]time.Time
+	checkedAt time.Time
+}
+
+func (r *certReloader) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.maybeReload()
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.cert, nil
+}
+
+// verifyClient 使用当前的CA校验客户端证书.
+func (r *certReloader) verifyClient(cs tls.ConnectionState) error {
+	if len(cs.PeerCertificates) == 0 {
+		return errors.New("client certificate is required")
+	}
+
+	r.mu.RLock()
+	clientCAs := r.clientCAs
+	r.mu.RUnlock()
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range cs.PeerCertificates[1:] {
+		intermediates.AddCert(cert)
+	}
+	_, err := cs.PeerCertificates[0].Verify(x509.VerifyOptions{
+		Roots:         clientCAs,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+	return err
+}
+
+// maybeReload 文件有修改时重新加载, 加载失败时继续使用旧的证书.
+func (r *certReloader) maybeReload() {
+	r.mu.Lock()
+	if time.Since(r.checkedAt) < certCheckInterval {
+		r.mu.Unlock()
+		return
+	}
+	r.checkedAt = time.Now()
+	changed := false
+	for file, modTime := range r.modTimes {
+		fi, err := os.Stat(file)
+		if err == nil && !fi.ModTime().Equal(modTime) {
+			changed = true
+			break
+		}
+	}
+	r.mu.Unlock()
+
+	if !changed {
+		return
+	}
+	if err := r.reload(); err != nil {
+		log.Errorf("failed to reload TLS certificates, keep using the old ones: %v", err)
+		return
+	}
+	log.Info("TLS certificates reloaded")
+}
+
+func (r *certReloader) reload() error {
+	files := []string{r.opts.CertFile, r.opts.KeyFile}
+	if r.opts.ClientCAFile != "" {
+		files = append(files, r.opts.ClientCAFile)
+	}
+	modTimes := make(map[string]time.Time, len(files))
+	for _, file := range files {
+		fi, err := os.Stat(file)
+		if err != nil {
+			return err
+		}
+		modTimes[file] = fi.ModTime()
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.opts.CertFile, r.opts.KeyFile)
+	if err != nil {
+		return err
+	}
+
+	var clientCAs *x509.CertPool
+	if r.opts.ClientCAFile != "" {
+		pem, err := ioutil.ReadFile(r.opts.ClientCAFile)
+		if err != nil {
+			return err
+		}
+		clientCAs = x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("no certificate found in %s", r.opts.ClientCAFile)
+		}
+	}
+
+	r.mu.Lock()
+	r.cert = &cert
+	r.clientCAs = clientCAs
+	r.modTimes = modTimes
+	r.mu.Unlock()
+	return nil
+}
+
+// ClientCertificate 返回客户端出示的证书, 没有使用mutual TLS时返回nil.
+// 只有配置了ClientCAFile时服务器才会要求客户端证书, 并且在握手时已经校验过.
+func ClientCertificate(r *http.Request) *x509.Certificate {
+	if r.TLS == nil || len(r.TLS.PeerCertificates) == 0 {
+		return nil
+	}
+	return r.TLS.PeerCertificates[0]
+}
+
+// ClientIdentity 返回客户端证书的Common Name和SHA-256指纹, 没有客户端证书时返回空字符串.
+func ClientIdentity(r *http.Request) (commonName, fingerprint string) {
+	cert := ClientCertificate(r)
+	if cert == nil {
+		return "", ""
+	}
+	sum := sha256.Sum256(cert.Raw)
+	return cert.Subject.CommonName, hex.EncodeToString(sum[:])
+}
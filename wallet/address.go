@@ -0,0 +1,155 @@
+package wallet
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/smallnest/blockchain/wallet/base58check"
+	"github.com/smallnest/blockchain/wallet/bech32"
+	"golang.org/x/crypto/ripemd160"
+)
+
+// compressedWIFSuffix 是WIF中私钥之后的压缩标志.
+const compressedWIFSuffix = 0x01
+
+var (
+	// ErrInvalidPublicKey 不是合法的公钥.
+	ErrInvalidPublicKey = errors.New("invalid public key")
+	// ErrUncompressedKey 隔离见证地址只能使用压缩公钥.
+	ErrUncompressedKey = errors.New("segwit address requires a compressed public key")
+	// ErrUnknownAddress 无法识别的地址.
+	ErrUnknownAddress = errors.New("unknown address format")
+	// ErrInvalidAddress 地址的长度不正确.
+	ErrInvalidAddress = errors.New("invalid address")
+	// ErrInvalidWIF 不是合法的WIF私钥.
+	ErrInvalidWIF = errors.New("invalid wif")
+)
+
+// AddressType 是地址的类型.
+type AddressType int
+
+const (
+	// AddressP2PKH 是base58编码的公钥哈希地址.
+	AddressP2PKH AddressType = iota + 1
+	// AddressP2SH 是base58编码的脚本哈希地址.
+	AddressP2SH
+	// AddressP2WPKH 是版本0的隔离见证公钥哈希地址.
+	AddressP2WPKH
+	// AddressP2WSH 是版本0的隔离见证脚本哈希地址.
+	AddressP2WSH
+	// AddressP2TR 是版本1的taproot地址.
+	AddressP2TR
+	// AddressWitnessUnknown 是其它版本的隔离见证地址.
+	AddressWitnessUnknown
+)
+
+func (t AddressType) String() string {
+	switch t {
+	case AddressP2PKH:
+		return "p2pkh"
+	case AddressP2SH:
+		return "p2sh"
+	case AddressP2WPKH:
+		return "p2wpkh"
+	case AddressP2WSH:
+		return "p2wsh"
+	case AddressP2TR:
+		return "p2tr"
+	case AddressWitnessUnknown:
+		return "witness_unknown"
+	default:
+		return "unknown"
+	}
+}
+
+// Address 是解析后的地址.
+type Address struct {
+	Type AddressType
+	// Version 对于base58地址是版本字节, 对于隔离见证地址是见证版本.
+	Version byte
+	// Hash 是公钥哈希、脚本哈希或者见证程序.
+	Hash []byte
+}
+
+// ParseAddress 解析当前网络的地址并识别它的类型, 支持base58的P2PKH、P2SH地址以及Bech32/Bech32m的隔离见证地址.
+func ParseAddress(address string) (*Address, error) {
+	if strings.HasPrefix(strings.ToLower(address), netParams.Bech32HRP+"1") {
+		return parseSegwitAddress(address)
+	}
+
+	version, hash, err := DecodeAddress(address)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnknownAddress, err)
+	}
+
+	addr := &Address{Version: version, Hash: hash}
+	switch version {
+	case netParams.PubKeyHashAddrID:
+		addr.Type = AddressP2PKH
+	case netParams.ScriptHashAddrID:
+		addr.Type = AddressP2SH
+	default:
+		return nil, fmt.Errorf("%w: unknown version %#x", ErrUnknownAddress, version)
+	}
+	return addr, nil
+}
+
+// DecodeAddress 解码base58的P2PKH或者P2SH地址, 返回版本字节和20字节的hash160.
+func DecodeAddress(address string) (version byte, payload []byte, err error) {
+	version, payload, err = base58check.Decode(address)
+	if err != nil {
+		return 0, nil, err
+	}
+	if len(payload) != ripemd160.Size {
+		return 0, nil, fmt.Errorf("%w: hash length %d", ErrInvalidAddress, len(payload))
+	}
+	return version, payload, nil
+}
+
+// DecodeWIF 解码WIF, 返回十六进制的私钥以及公钥是否应该使用压缩格式.
+func DecodeWIF(wif string) (privateKey string, compressed bool, err error) {
+	version, payload, err := base58check.Decode(wif)
+	if err != nil {
+		return "", false, err
+	}
+	if version != netParams.PrivateKeyID {
+		return "", false, fmt.Errorf("%w: unknown version %#x", ErrInvalidWIF, version)
+	}
+
+	switch {
+	case len(payload) == 32:
+	case len(payload) == 33 && payload[32] == compressedWIFSuffix:
+		payload, compressed = payload[:32], true
+	default:
+		return "", false, fmt.Errorf("%w: payload length %d", ErrInvalidWIF, len(payload))
+	}
+	if !ValidPrivateKey(payload) {
+		return "", false, fmt.Errorf("%w: private key out of range", ErrInvalidWIF)
+	}
+	return hex.EncodeToString(payload), compressed, nil
+}
+
+func parseSegwitAddress(address string) (*Address, error) {
+	hrp, version, program, err := bech32.DecodeSegwitAddress(address)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnknownAddress, err)
+	}
+	if hrp != netParams.Bech32HRP {
+		return nil, fmt.Errorf("%w: unknown human-readable part %q", ErrUnknownAddress, hrp)
+	}
+
+	addr := &Address{Version: version, Hash: program}
+	switch {
+	case version == 0 && len(program) == 20:
+		addr.Type = AddressP2WPKH
+	case version == 0 && len(program) == 32:
+		addr.Type = AddressP2WSH
+	case version == 1 && len(program) == 32:
+		addr.Type = AddressP2TR
+	default:
+		addr.Type = AddressWitnessUnknown
+	}
+	return addr, nil
+}
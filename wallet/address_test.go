@@ -0,0 +1,163 @@
+package wallet
+
+import (
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	"github.com/smallnest/blockchain/wallet/base58check"
+)
+
+// 私钥1对应的公钥就是secp256k1的基点G.
+const onePrivateKey = "0000000000000000000000000000000000000000000000000000000000000001"
+
+func TestCompressedKey(t *testing.T) {
+	publicKey, p2pkh, err := GetCompressedPublicKey(onePrivateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if publicKey != "0279be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798" {
+		t.Errorf("unexpected compressed public key %s", publicKey)
+	}
+	if p2pkh != "1BgGZ9tcN4rm9KBzDn7KprQz87SZ26SAMH" {
+		t.Errorf("unexpected compressed p2pkh %s", p2pkh)
+	}
+	if wif, err := PrivateKey2CompressedWif(onePrivateKey); err != nil || wif != "KwDiBf89QgGbjEhKnhXJuH7LrciVrZi3qYjgd9M7rFU73sVHnoWn" {
+		t.Errorf("unexpected compressed wif %s, %v", wif, err)
+	}
+	if wif, err := PrivateKey2Wif(onePrivateKey); err != nil || wif != "5HpHagT65TZzG1PH3CSu63k8DbpvD8s5ip4nEB3kEsreAnchuDf" {
+		t.Errorf("unexpected wif %s, %v", wif, err)
+	}
+
+	uncompressed, _, _ := GetPublicKey(onePrivateKey)
+	compressed, err := CompressPublicKey(uncompressed)
+	if err != nil || compressed != publicKey {
+		t.Errorf("CompressPublicKey = %s, %v", compressed, err)
+	}
+	if decompressed, err := decompressPublicKey(mustDecodeHex(t, publicKey)); err != nil || hex.EncodeToString(decompressed) != uncompressed {
+		t.Error("decompressed key does not match the uncompressed key")
+	}
+}
+
+func TestPublicKey2P2WPKH(t *testing.T) {
+	publicKey, _, _ := GetCompressedPublicKey(onePrivateKey)
+	address, err := PublicKey2P2WPKH(publicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if address != "bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4" {
+		t.Errorf("unexpected p2wpkh address %s", address)
+	}
+
+	uncompressed, _, _ := GetPublicKey(onePrivateKey)
+	if _, err = PublicKey2P2WPKH(uncompressed); !errors.Is(err, ErrUncompressedKey) {
+		t.Errorf("expect ErrUncompressedKey, got %v", err)
+	}
+}
+
+func TestParseAddress(t *testing.T) {
+	tests := []struct {
+		address string
+		typ     AddressType
+		version byte
+		hashLen int
+	}{
+		{"1EHNa6Q4Jz2uvNExL497mE43ikXhwF6kZm", AddressP2PKH, 0x00, 20},
+		{"1BgGZ9tcN4rm9KBzDn7KprQz87SZ26SAMH", AddressP2PKH, 0x00, 20},
+		{"3J98t1WpEZ73CNmQviecrnyiWrnqRhWNLy", AddressP2SH, 0x05, 20},
+		{"bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4", AddressP2WPKH, 0, 20},
+		{"BC1QW508D6QEJXTDG4Y5R3ZARVARY0C5XW7KV8F3T4", AddressP2WPKH, 0, 20},
+		{"bc1qrp33g0q5c5txsp9arysrx4k6zdkfs4nce4xj0gdcccefvpysxf3qccfmv3", AddressP2WSH, 0, 32},
+		{"bc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vqzk5jj0", AddressP2TR, 1, 32},
+		{"bc1pw508d6qejxtdg4y5r3zarvary0c5xw7kw508d6qejxtdg4y5r3zarvary0c5xw7kt5nd6y", AddressWitnessUnknown, 1, 40},
+	}
+	for _, tt := range tests {
+		addr, err := ParseAddress(tt.address)
+		if err != nil {
+			t.Errorf("%s: %v", tt.address, err)
+			continue
+		}
+		if addr.Type != tt.typ || addr.Version != tt.version || len(addr.Hash) != tt.hashLen {
+			t.Errorf("%s: got %s version %d hash length %d", tt.address, addr.Type, addr.Version, len(addr.Hash))
+		}
+	}
+
+	invalid := []string{
+		"",
+		"1EHNa6Q4Jz2uvNExL497mE43ikXhwF6kZn", // 校验和错误
+		"bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t5",                                 // 校验和错误
+		"tb1qw508d6qejxtdg4y5r3zarvary0c5xw7kxpjzsx",                                 // 其它网络
+		"bc1zw508d6qejxtdg4y5r3zarvary0c5xw7kw508d6qejxtdg4y5r3zarvary0c5xw7kt5nd6y", // 版本2使用了Bech32
+	}
+	for _, address := range invalid {
+		if _, err := ParseAddress(address); !errors.Is(err, ErrUnknownAddress) {
+			t.Errorf("%q: expect ErrUnknownAddress, got %v", address, err)
+		}
+	}
+}
+
+func mustDecodeHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestDecodeWIF(t *testing.T) {
+	tests := []struct {
+		wif        string
+		compressed bool
+	}{
+		{"5HpHagT65TZzG1PH3CSu63k8DbpvD8s5ip4nEB3kEsreAnchuDf", false},
+		{"KwDiBf89QgGbjEhKnhXJuH7LrciVrZi3qYjgd9M7rFU73sVHnoWn", true},
+	}
+	for _, tt := range tests {
+		privateKey, compressed, err := DecodeWIF(tt.wif)
+		if err != nil {
+			t.Errorf("%s: %v", tt.wif, err)
+			continue
+		}
+		if privateKey != onePrivateKey || compressed != tt.compressed {
+			t.Errorf("%s: got %s compressed %t", tt.wif, privateKey, compressed)
+		}
+	}
+
+	privateKey, wif, _, _, _ := GenerateKeys()
+	if decoded, compressed, err := DecodeWIF(wif); err != nil || decoded != privateKey || compressed {
+		t.Errorf("DecodeWIF(%s) = %s, %t, %v", wif, decoded, compressed, err)
+	}
+
+	invalid := []string{
+		"",
+		"0OIl",
+		"5HpHagT65TZzG1PH3CSu63k8DbpvD8s5ip4nEB3kEsreAnchuDg",             // 校验和错误
+		"1EHNa6Q4Jz2uvNExL497mE43ikXhwF6kZm",                              // 地址不是WIF
+		base58check.CheckEncode(netParams.PrivateKeyID, make([]byte, 32)), // 私钥为0
+	}
+	for _, wif := range invalid {
+		if _, _, err := DecodeWIF(wif); err == nil {
+			t.Errorf("%q: expect an error", wif)
+		}
+	}
+}
+
+func TestDecodeAddress(t *testing.T) {
+	_, p2pkh, _ := GetPublicKey(onePrivateKey)
+	version, hash, err := DecodeAddress(p2pkh)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != netParams.PubKeyHashAddrID || hex.EncodeToString(hash) != "91b24bf9f5288532960ac687abb035127b1d28a5" {
+		t.Errorf("got version %#x hash %x", version, hash)
+	}
+
+	if _, _, err = DecodeAddress("1EHNa6Q4Jz2uvNExL497mE43ikXhwF6kZn"); !errors.Is(err, base58check.ErrChecksum) {
+		t.Errorf("expect ErrChecksum, got %v", err)
+	}
+	wif, _ := PrivateKey2Wif(onePrivateKey)
+	if _, _, err = DecodeAddress(wif); !errors.Is(err, ErrInvalidAddress) {
+		t.Errorf("expect ErrInvalidAddress, got %v", err)
+	}
+}
@@ -1,92 +1,88 @@
+// Package base58check 实现比特币使用的Base58Check编码: 版本字节+数据+4字节的校验和.
 package base58check
 
 import (
 	"bytes"
 	"crypto/sha256"
 	"encoding/hex"
-	"log"
+	"errors"
+	"fmt"
 	"math/big"
 	"strings"
 
-	"github.com/smallnest/bitcoin/wallet/base58check/base58"
+	"github.com/smallnest/blockchain/wallet/base58check/base58"
 )
 
-func Encode(prefix string, byteData []byte) string {
+var (
+	// ErrChecksum 校验和不正确.
+	ErrChecksum = errors.New("base58check: invalid checksum")
+	// ErrInvalidFormat 不是合法的base58check编码.
+	ErrInvalidFormat = errors.New("base58check: invalid format")
+)
+
+// Encode 使用十六进制的版本前缀编码数据, 前缀必须是一个字节.
+func Encode(prefix string, byteData []byte) (string, error) {
 	prefixBytes, err := hex.DecodeString(prefix)
 	if err != nil {
-		log.Fatal(err)
+		return "", fmt.Errorf("base58check: invalid prefix %q: %v", prefix, err)
 	}
+	if len(prefixBytes) != 1 {
+		return "", fmt.Errorf("base58check: prefix %q is not a single byte", prefix)
+	}
+	return CheckEncode(prefixBytes[0], byteData), nil
+}
 
-	length := len(byteData) + 1
-	encoded := make([]byte, length)
-	encoded[0] = prefixBytes[0]
-	copy(encoded[1:], byteData)
-
-	//Perform SHA-256 twice
-	shaHash := sha256.New()
-	shaHash.Write(encoded)
-	var hash = shaHash.Sum(nil)
-
-	shaHash2 := sha256.New()
-	shaHash2.Write(hash)
-	hash2 := shaHash2.Sum(nil)
-
-	//First 4 bytes if this double-sha'd byte array is the checksum
-	checksum := hash2[0:4]
+// CheckEncode 使用版本字节version编码数据.
+func CheckEncode(version byte, byteData []byte) string {
+	encoded := make([]byte, 0, len(byteData)+5)
+	encoded = append(encoded, version)
+	encoded = append(encoded, byteData...)
 
-	//Append this checksum to the input bytes
-	encodedChecksum := append(encoded, checksum...)
+	//First 4 bytes of the double-sha'd byte array is the checksum
+	encoded = append(encoded, checksum(encoded)...)
 
 	//base58 alone is not enough. We need to first count each of the zero bytes
-	//which are at the beginning of the encodedCheckSum
+	//which are at the beginning of the encoded bytes
 	zeroBytes := 0
-	for i := 0; i < len(encodedChecksum); i++ {
-		if encodedChecksum[i] == 0 {
-			zeroBytes++
-		} else {
-			break
-		}
+	for zeroBytes < len(encoded) && encoded[zeroBytes] == 0 {
+		zeroBytes++
 	}
 
-	//Convert this checksum'd version to a big Int
-	bigIntEncodedChecksum := big.NewInt(0)
-	bigIntEncodedChecksum.SetBytes(encodedChecksum)
-
 	//Encode the big int checksum'd version into a Base58Checked string
-	base58EncodedChecksum := string(base58.EncodeBig(nil, bigIntEncodedChecksum))
+	base58EncodedChecksum := string(base58.EncodeBig(nil, new(big.Int).SetBytes(encoded)))
 
 	//Now for each zero byte we counted above we need to prepend a 1 to our
 	//base58 encoded string. The rational behind this is that base58 removes 0's (0x00).
 	//So bitcoin demands we add leading 0s back on as 1s.
-	var buffer bytes.Buffer
-	for i := 0; i < zeroBytes; i++ {
-		buffer.WriteString("1")
-	}
-
-	leadingZeros := strings.Repeat("1", zeroBytes)
-	return leadingZeros + base58EncodedChecksum
+	return strings.Repeat("1", zeroBytes) + base58EncodedChecksum
 }
 
-func Decode(value string) []byte {
+// Decode 解码字符串并校验校验和, 返回版本字节和数据.
+func Decode(value string) (version byte, payload []byte, err error) {
 	zeroBytes := 0
-	for i := 0; i < len(value); i++ {
-		if value[i] == 49 {
-			zeroBytes++
-		} else {
-			break
-		}
+	for zeroBytes < len(value) && value[zeroBytes] == '1' {
+		zeroBytes++
 	}
 
-	publicKeyInt, err := base58.DecodeToBig([]byte(value))
+	n, err := base58.DecodeToBig([]byte(value))
 	if err != nil {
-		log.Fatal(err)
+		return 0, nil, fmt.Errorf("%w: %v", ErrInvalidFormat, err)
+	}
+	decoded := append(make([]byte, zeroBytes), n.Bytes()...)
+	if len(decoded) < 5 {
+		return 0, nil, ErrInvalidFormat
 	}
 
-	encodedChecksum := publicKeyInt.Bytes()
-	encoded := encodedChecksum[0 : len(encodedChecksum)-4]
-
-	var buffer = bytes.NewBuffer(bytes.Repeat([]byte{0}, zeroBytes))
-	buffer.Write(encoded)
+	data, sum := decoded[:len(decoded)-4], decoded[len(decoded)-4:]
+	if !bytes.Equal(checksum(data), sum) {
+		return 0, nil, ErrChecksum
+	}
+	return data[0], data[1:], nil
+}
 
-	return buffer.Bytes()[1:len(buffer.Bytes())]
+// checksum 计算两次SHA-256, 取前4个字节.
+func checksum(data []byte) []byte {
+	hash := sha256.Sum256(data)
+	hash = sha256.Sum256(hash[:])
+	return hash[:4]
 }
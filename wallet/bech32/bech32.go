@@ -0,0 +1,245 @@
+// Package bech32 实现BIP-173的Bech32和BIP-350的Bech32m编码, 以及隔离见证地址的编码和解码.
+package bech32
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const charset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+
+// Encoding 是校验和的算法.
+type Encoding int
+
+const (
+	// Bech32 用于版本0的隔离见证地址.
+	Bech32 Encoding = iota + 1
+	// Bech32m 用于版本1及以上的隔离见证地址.
+	Bech32m
+)
+
+const (
+	bech32Const  = 1
+	bech32mConst = 0x2bc830a3
+
+	// maxLength 是BIP-173规定的最大长度.
+	maxLength = 90
+)
+
+var (
+	// ErrInvalidChecksum 校验和不正确.
+	ErrInvalidChecksum = errors.New("bech32: invalid checksum")
+	// ErrMixedCase 同时包含大写和小写字母.
+	ErrMixedCase = errors.New("bech32: mixed case")
+	// ErrInvalidLength 长度不合法.
+	ErrInvalidLength = errors.New("bech32: invalid length")
+	// ErrInvalidProgram 隔离见证程序不合法.
+	ErrInvalidProgram = errors.New("bech32: invalid witness program")
+)
+
+var charsetRev [128]int8
+
+func init() {
+	for i := range charsetRev {
+		charsetRev[i] = -1
+	}
+	for i, c := range charset {
+		charsetRev[c] = int8(i)
+	}
+}
+
+func polymod(values []byte) uint32 {
+	gen := [5]uint32{0x3b6a57b2, 0x26508e6d, 0x1ea119fa, 0x3d4233dd, 0x2a1462b3}
+	chk := uint32(1)
+	for _, v := range values {
+		b := chk >> 25
+		chk = (chk&0x1ffffff)<<5 ^ uint32(v)
+		for i := 0; i < 5; i++ {
+			if (b>>uint(i))&1 == 1 {
+				chk ^= gen[i]
+			}
+		}
+	}
+	return chk
+}
+
+func hrpExpand(hrp string) []byte {
+	ret := make([]byte, 0, len(hrp)*2+1)
+	for i := 0; i < len(hrp); i++ {
+		ret = append(ret, hrp[i]>>5)
+	}
+	ret = append(ret, 0)
+	for i := 0; i < len(hrp); i++ {
+		ret = append(ret, hrp[i]&31)
+	}
+	return ret
+}
+
+func checksumConst(enc Encoding) uint32 {
+	if enc == Bech32m {
+		return bech32mConst
+	}
+	return bech32Const
+}
+
+func createChecksum(hrp string, data []byte, enc Encoding) []byte {
+	values := append(hrpExpand(hrp), data...)
+	values = append(values, 0, 0, 0, 0, 0, 0)
+	mod := polymod(values) ^ checksumConst(enc)
+	ret := make([]byte, 6)
+	for i := range ret {
+		ret[i] = byte(mod>>uint(5*(5-i))) & 31
+	}
+	return ret
+}
+
+// Encode 把5比特一组的数据data编码为字符串.
+func Encode(hrp string, data []byte, enc Encoding) (string, error) {
+	if len(hrp)+len(data)+7 > maxLength {
+		return "", ErrInvalidLength
+	}
+	if len(hrp) == 0 {
+		return "", fmt.Errorf("bech32: empty human-readable part")
+	}
+	for i := 0; i < len(hrp); i++ {
+		if hrp[i] < 33 || hrp[i] > 126 {
+			return "", fmt.Errorf("bech32: invalid character in human-readable part")
+		}
+	}
+	hrp = strings.ToLower(hrp)
+
+	values := make([]byte, 0, len(data)+6)
+	values = append(values, data...)
+	values = append(values, createChecksum(hrp, data, enc)...)
+
+	var sb strings.Builder
+	sb.WriteString(hrp)
+	sb.WriteByte('1')
+	for _, d := range values {
+		if d >= 32 {
+			return "", fmt.Errorf("bech32: invalid data value %d", d)
+		}
+		sb.WriteByte(charset[d])
+	}
+	return sb.String(), nil
+}
+
+// Decode 解码字符串, 返回human-readable部分、5比特一组的数据和校验和的算法.
+func Decode(s string) (hrp string, data []byte, enc Encoding, err error) {
+	if len(s) > maxLength {
+		return "", nil, 0, ErrInvalidLength
+	}
+	if strings.ToLower(s) != s && strings.ToUpper(s) != s {
+		return "", nil, 0, ErrMixedCase
+	}
+	s = strings.ToLower(s)
+
+	pos := strings.LastIndexByte(s, '1')
+	if pos < 1 || pos+7 > len(s) {
+		return "", nil, 0, ErrInvalidLength
+	}
+	hrp = s[:pos]
+	for i := 0; i < len(hrp); i++ {
+		if hrp[i] < 33 || hrp[i] > 126 {
+			return "", nil, 0, fmt.Errorf("bech32: invalid character in human-readable part")
+		}
+	}
+	data = make([]byte, 0, len(s)-pos-1)
+	for i := pos + 1; i < len(s); i++ {
+		c := s[i]
+		if c >= 128 || charsetRev[c] == -1 {
+			return "", nil, 0, fmt.Errorf("bech32: invalid character %q", c)
+		}
+		data = append(data, byte(charsetRev[c]))
+	}
+
+	switch polymod(append(hrpExpand(hrp), data...)) {
+	case bech32Const:
+		enc = Bech32
+	case bech32mConst:
+		enc = Bech32m
+	default:
+		return "", nil, 0, ErrInvalidChecksum
+	}
+	return hrp, data[:len(data)-6], enc, nil
+}
+
+// ConvertBits 把每组fromBits比特的数据转换为每组toBits比特.
+// pad为true时不足的部分补0, 否则剩余的比特必须都是0.
+func ConvertBits(data []byte, fromBits, toBits uint, pad bool) ([]byte, error) {
+	var acc, bits uint
+	maxv := uint(1)<<toBits - 1
+	ret := make([]byte, 0, len(data)*int(fromBits)/int(toBits)+1)
+	for _, value := range data {
+		if uint(value)>>fromBits != 0 {
+			return nil, fmt.Errorf("bech32: invalid data value %d", value)
+		}
+		acc = acc<<fromBits | uint(value)
+		bits += fromBits
+		for bits >= toBits {
+			bits -= toBits
+			ret = append(ret, byte(acc>>bits&maxv))
+		}
+	}
+	if pad {
+		if bits > 0 {
+			ret = append(ret, byte(acc<<(toBits-bits)&maxv))
+		}
+	} else if bits >= fromBits || acc<<(toBits-bits)&maxv != 0 {
+		return nil, errors.New("bech32: invalid padding")
+	}
+	return ret, nil
+}
+
+// EncodeSegwitAddress 编码隔离见证地址, 版本0使用Bech32, 其它版本使用Bech32m.
+func EncodeSegwitAddress(hrp string, version byte, program []byte) (string, error) {
+	if err := checkProgram(version, program); err != nil {
+		return "", err
+	}
+	data, err := ConvertBits(program, 8, 5, true)
+	if err != nil {
+		return "", err
+	}
+	enc := Bech32m
+	if version == 0 {
+		enc = Bech32
+	}
+	return Encode(hrp, append([]byte{version}, data...), enc)
+}
+
+// DecodeSegwitAddress 解码隔离见证地址, 返回human-readable部分、见证版本和见证程序.
+func DecodeSegwitAddress(address string) (hrp string, version byte, program []byte, err error) {
+	hrp, data, enc, err := Decode(address)
+	if err != nil {
+		return "", 0, nil, err
+	}
+	if len(data) < 1 {
+		return "", 0, nil, ErrInvalidProgram
+	}
+	version = data[0]
+	if (version == 0 && enc != Bech32) || (version != 0 && enc != Bech32m) {
+		return "", 0, nil, ErrInvalidChecksum
+	}
+	program, err = ConvertBits(data[1:], 5, 8, false)
+	if err != nil {
+		return "", 0, nil, err
+	}
+	if err = checkProgram(version, program); err != nil {
+		return "", 0, nil, err
+	}
+	return hrp, version, program, nil
+}
+
+func checkProgram(version byte, program []byte) error {
+	if version > 16 {
+		return ErrInvalidProgram
+	}
+	if len(program) < 2 || len(program) > 40 {
+		return ErrInvalidProgram
+	}
+	if version == 0 && len(program) != 20 && len(program) != 32 {
+		return ErrInvalidProgram
+	}
+	return nil
+}
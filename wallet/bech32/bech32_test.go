@@ -0,0 +1,56 @@
+package bech32
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+// BIP-173和BIP-350中的测试向量.
+func TestSegwitAddress(t *testing.T) {
+	valid := []struct {
+		address string
+		version byte
+		program string
+	}{
+		{"BC1QW508D6QEJXTDG4Y5R3ZARVARY0C5XW7KV8F3T4", 0, "751e76e8199196d454941c45d1b3a323f1433bd6"},
+		{"tb1qrp33g0q5c5txsp9arysrx4k6zdkfs4nce4xj0gdcccefvpysxf3q0sl5k7", 0, "1863143c14c5166804bd19203356da136c985678cd4d27a1b8c6329604903262"},
+		{"bc1pw508d6qejxtdg4y5r3zarvary0c5xw7kw508d6qejxtdg4y5r3zarvary0c5xw7kt5nd6y", 1, "751e76e8199196d454941c45d1b3a323f1433bd6751e76e8199196d454941c45d1b3a323f1433bd6"},
+		{"BC1SW50QGDZ25J", 16, "751e"},
+		{"bc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vqzk5jj0", 1, "79be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798"},
+	}
+	for _, v := range valid {
+		hrp, version, program, err := DecodeSegwitAddress(v.address)
+		if err != nil {
+			t.Errorf("%s: %v", v.address, err)
+			continue
+		}
+		if version != v.version || hex.EncodeToString(program) != v.program {
+			t.Errorf("%s: unexpected version %d or program %x", v.address, version, program)
+		}
+		encoded, err := EncodeSegwitAddress(hrp, version, program)
+		if err != nil || encoded != strings.ToLower(v.address) {
+			t.Errorf("%s: encoded to %s: %v", v.address, encoded, err)
+		}
+	}
+
+	invalid := []string{
+		// 版本0使用了Bech32m
+		"bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kemeawh",
+		// 版本1使用了Bech32
+		"bc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vqh2y7hd",
+		// 校验和错误
+		"bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t5",
+		// 大小写混合
+		"tb1qrp33g0q5c5txsp9arysrx4k6zdkfs4nce4xj0gdcccefvpysxf3q0sL5k7",
+		// 版本0的程序长度不对
+		"BC1QR508D6QEJXTDG4Y5R3ZARVARYV98GJ9P",
+		// 补位不是0
+		"bc1zw508d6qejxtdg4y5r3zarvaryvqyzf3du",
+	}
+	for _, address := range invalid {
+		if _, _, _, err := DecodeSegwitAddress(address); err == nil {
+			t.Errorf("%s: expect error", address)
+		}
+	}
+}
@@ -4,27 +4,104 @@ import (
 	"encoding/hex"
 
 	"github.com/smallnest/blockchain/wallet/base58check"
+	"github.com/smallnest/blockchain/wallet/bech32"
+	"github.com/smallnest/blockchain/wallet/signer"
 )
 
 // GetPublicKey 根据私钥得到公钥和p2pkh地址.
-func GetPublicKey(privateKey string) (publicKey, p2pkh string) {
-	priKey, _ := hex.DecodeString(privateKey)
-	pubKey, ripeHashedBytes := generatePublicKey(priKey)
-	publicKey = hex.EncodeToString(pubKey)
-	publicKeyP2PKH := base58check.Encode(publicKeyPrefix, ripeHashedBytes)
-
-	return publicKey, publicKeyP2PKH
+func GetPublicKey(privateKey string) (publicKey, p2pkh string, err error) {
+	return getPublicKey(privateKey, false)
 }
 
 // PrivateKey2Wif 根据私钥得到wif.
-func PrivateKey2Wif(privateKey string) (wif string) {
-	priKey, _ := hex.DecodeString(privateKey)
-	privateKeyWif := base58check.Encode(privateKeyPrefix, priKey)
-	return privateKeyWif
+func PrivateKey2Wif(privateKey string) (wif string, err error) {
+	priKey, err := decodePrivateKey(privateKey)
+	if err != nil {
+		return "", err
+	}
+	return base58check.CheckEncode(netParams.PrivateKeyID, priKey), nil
 }
 
 // PublicKey2P2PKH 根据公钥生成p2pkh地址.
-func PublicKey2P2PKH(publicKey string) (p2pkh string) {
-	pubKey, _ := hex.DecodeString(publicKey)
-	return base58check.Encode(publicKeyPrefix, pubKey)
+func PublicKey2P2PKH(publicKey string) (p2pkh string, err error) {
+	pubKey, err := hex.DecodeString(publicKey)
+	if err != nil {
+		return "", err
+	}
+	return base58check.CheckEncode(netParams.PubKeyHashAddrID, hash160(pubKey)), nil
+}
+
+// GetCompressedPublicKey 根据私钥得到33字节的压缩公钥和对应的p2pkh地址.
+func GetCompressedPublicKey(privateKey string) (publicKey, p2pkh string, err error) {
+	return getPublicKey(privateKey, true)
+}
+
+func getPublicKey(privateKey string, compressed bool) (publicKey, p2pkh string, err error) {
+	priKey, err := decodePrivateKey(privateKey)
+	if err != nil {
+		return "", "", err
+	}
+	pubKey, ripeHashedBytes, err := generatePublicKey(priKey, compressed)
+	if err != nil {
+		return "", "", err
+	}
+	publicKey = hex.EncodeToString(pubKey)
+	publicKeyP2PKH := base58check.CheckEncode(netParams.PubKeyHashAddrID, ripeHashedBytes)
+
+	return publicKey, publicKeyP2PKH, nil
+}
+
+// PrivateKey2CompressedWif 根据私钥得到带压缩标志的wif, 表示对应的公钥是压缩格式.
+func PrivateKey2CompressedWif(privateKey string) (wif string, err error) {
+	priKey, err := decodePrivateKey(privateKey)
+	if err != nil {
+		return "", err
+	}
+	return base58check.CheckEncode(netParams.PrivateKeyID, append(priKey, compressedWIFSuffix)), nil
+}
+
+// decodePrivateKey 解码十六进制的私钥, 私钥无效时返回signer.ErrInvalidPrivateKey.
+func decodePrivateKey(privateKey string) ([]byte, error) {
+	priKey, err := hex.DecodeString(privateKey)
+	if err != nil || !ValidPrivateKey(priKey) {
+		return nil, signer.ErrInvalidPrivateKey
+	}
+	return priKey, nil
+}
+
+// CompressPublicKey 把十六进制的未压缩公钥转换为压缩公钥, 已经压缩的公钥原样返回.
+func CompressPublicKey(publicKey string) (string, error) {
+	pubKey, err := hex.DecodeString(publicKey)
+	if err != nil {
+		return "", err
+	}
+	switch {
+	case len(pubKey) == 33 && (pubKey[0] == 0x02 || pubKey[0] == 0x03):
+		return publicKey, nil
+	case len(pubKey) == 65 && pubKey[0] == 0x04:
+		compressed := make([]byte, 33)
+		compressed[0] = 0x02 | pubKey[64]&1
+		copy(compressed[1:], pubKey[1:33])
+		return hex.EncodeToString(compressed), nil
+	default:
+		return "", ErrInvalidPublicKey
+	}
+}
+
+// PublicKey2P2WPKH 根据压缩公钥生成版本0的隔离见证地址(P2WPKH), 未压缩的公钥不能用于隔离见证.
+func PublicKey2P2WPKH(publicKey string) (string, error) {
+	pubKey, err := hex.DecodeString(publicKey)
+	if err != nil {
+		return "", err
+	}
+	if len(pubKey) != 33 || (pubKey[0] != 0x02 && pubKey[0] != 0x03) {
+		return "", ErrUncompressedKey
+	}
+	return bech32.EncodeSegwitAddress(netParams.Bech32HRP, 0, hash160(pubKey))
+}
+
+// Script2P2SH 根据赎回脚本生成P2SH地址, 地址中是脚本的hash160.
+func Script2P2SH(redeemScript []byte) (string, error) {
+	prefix := hex.EncodeToString([]byte{netParams.ScriptHashAddrID})
+	return base58check.Encode(prefix, hash160(redeemScript))
 }
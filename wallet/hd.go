@@ -0,0 +1,267 @@
+package wallet
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/smallnest/blockchain/wallet/base58check"
+	bip32 "github.com/tyler-smith/go-bip32"
+	bip39 "github.com/tyler-smith/go-bip39"
+)
+
+// HardenedOffset 是第一个强化(hardened)子密钥的序号.
+const HardenedOffset = bip32.FirstHardenedChild
+
+// BIP44Purpose 是BIP-44路径中的purpose, 币种由网络参数决定.
+const BIP44Purpose = 44
+
+var (
+	// ErrInvalidPath 派生路径的格式不正确.
+	ErrInvalidPath = errors.New("invalid derivation path")
+	// ErrHardenedFromPublic 不能从扩展公钥派生强化的子密钥.
+	ErrHardenedFromPublic = errors.New("cannot derive a hardened child from a public key")
+	// ErrPublicKeyOnly 扩展公钥没有私钥.
+	ErrPublicKeyOnly = errors.New("extended key has no private key")
+	// ErrInvalidExtendedKey 不是合法的xprv或者xpub.
+	ErrInvalidExtendedKey = errors.New("invalid extended key")
+)
+
+// secp256k1曲线的参数p和b, 用于把压缩的公钥还原.
+var (
+	curveP, _ = new(big.Int).SetString("FFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFEFFFFFC2F", 16)
+	curveB    = big.NewInt(7)
+)
+
+// HDKey 是BIP-32扩展密钥, 可以是扩展私钥(xprv)或者扩展公钥(xpub).
+type HDKey struct {
+	key *bip32.Key
+}
+
+// NewMasterKey 根据种子生成主密钥.
+func NewMasterKey(seed []byte) (*HDKey, error) {
+	key, err := bip32.NewMasterKey(seed)
+	if err != nil {
+		return nil, err
+	}
+	return &HDKey{key: key}, nil
+}
+
+// NewMasterKeyFromMnemonic 根据BIP-39助记词和密码生成主密钥.
+func NewMasterKeyFromMnemonic(mnemonic, secretPassphrase string) (*HDKey, error) {
+	seed, err := bip39.NewSeedWithErrorChecking(mnemonic, secretPassphrase)
+	if err != nil {
+		return nil, err
+	}
+	return NewMasterKey(seed)
+}
+
+// ParseExtendedKey 解析当前网络的扩展私钥或者扩展公钥, 主网是xprv和xpub, 测试网是tprv和tpub.
+// 私钥不在[1, n-1]之内或者公钥不在曲线上时返回ErrInvalidExtendedKey.
+func ParseExtendedKey(s string) (*HDKey, error) {
+	key, err := bip32.B58Deserialize(s)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidExtendedKey, err)
+	}
+	switch {
+	case key.IsPrivate && bytes.Equal(key.Version, netParams.HDPrivateKeyID[:]):
+		if !ValidPrivateKey(key.Key) {
+			return nil, fmt.Errorf("%w: private key out of range", ErrInvalidExtendedKey)
+		}
+	case !key.IsPrivate && bytes.Equal(key.Version, netParams.HDPublicKeyID[:]):
+		if _, err = decompressPublicKey(key.Key); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("%w: unknown version %x", ErrInvalidExtendedKey, key.Version)
+	}
+	return &HDKey{key: key}, nil
+}
+
+// ParsePath 解析形如m/44'/0'/0'/0/1的派生路径, 强化的序号用'或者h标记.
+// 开头的m可以省略, 此时路径相对于当前的密钥.
+func ParsePath(path string) ([]uint32, error) {
+	path = strings.TrimSpace(path)
+	if path == "m" || path == "" {
+		return nil, nil
+	}
+	path = strings.TrimPrefix(path, "m/")
+
+	parts := strings.Split(path, "/")
+	indexes := make([]uint32, len(parts))
+	for i, part := range parts {
+		hardened := strings.HasSuffix(part, "'") || strings.HasSuffix(part, "h") || strings.HasSuffix(part, "H")
+		if hardened {
+			part = part[:len(part)-1]
+		}
+		index, err := strconv.ParseUint(part, 10, 32)
+		if err != nil || uint32(index) >= HardenedOffset {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidPath, path)
+		}
+		indexes[i] = uint32(index)
+		if hardened {
+			indexes[i] += HardenedOffset
+		}
+	}
+	return indexes, nil
+}
+
+// BIP44Path 返回BIP-44的路径m/44'/coin'/account'/change/index, 主网的coin为0, 测试网为1.
+// change为0表示收款地址, 为1表示找零地址.
+func BIP44Path(account, change, index uint32) string {
+	return fmt.Sprintf("m/%d'/%d'/%d'/%d/%d", BIP44Purpose, netParams.HDCoinType, account, change, index)
+}
+
+// BIP44AccountPath 返回账户的路径m/44'/coin'/account', 它的扩展公钥可以派生所有的地址.
+func BIP44AccountPath(account uint32) string {
+	return fmt.Sprintf("m/%d'/%d'/%d'", BIP44Purpose, netParams.HDCoinType, account)
+}
+
+// Child 派生序号为index的子密钥.
+func (k *HDKey) Child(index uint32) (*HDKey, error) {
+	child, err := k.key.NewChildKey(index)
+	if err == bip32.ErrHardnedChildPublicKey {
+		return nil, ErrHardenedFromPublic
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &HDKey{key: child}, nil
+}
+
+// Derive 按照路径派生子密钥.
+func (k *HDKey) Derive(path string) (*HDKey, error) {
+	indexes, err := ParsePath(path)
+	if err != nil {
+		return nil, err
+	}
+	key := k
+	for _, index := range indexes {
+		if key, err = key.Child(index); err != nil {
+			return nil, err
+		}
+	}
+	return key, nil
+}
+
+// IsPrivate 判断是否是扩展私钥.
+func (k *HDKey) IsPrivate() bool {
+	return k.key.IsPrivate
+}
+
+// Depth 返回密钥在派生树中的深度, 主密钥为0.
+func (k *HDKey) Depth() uint8 {
+	return k.key.Depth
+}
+
+// Neuter 返回对应的扩展公钥.
+func (k *HDKey) Neuter() *HDKey {
+	return &HDKey{key: k.key.PublicKey()}
+}
+
+// String 返回当前网络的扩展私钥或者扩展公钥编码, 主网是xprv和xpub, 测试网是tprv和tpub.
+func (k *HDKey) String() string {
+	key := *k.key
+	if key.IsPrivate {
+		key.Version = netParams.HDPrivateKeyID[:]
+	} else {
+		key.Version = netParams.HDPublicKeyID[:]
+	}
+	return key.B58Serialize()
+}
+
+// PrivateKey 返回十六进制的私钥.
+func (k *HDKey) PrivateKey() (string, error) {
+	if !k.key.IsPrivate {
+		return "", ErrPublicKeyOnly
+	}
+	return hex.EncodeToString(k.key.Key), nil
+}
+
+// WIF 返回私钥的WIF编码.
+func (k *HDKey) WIF() (string, error) {
+	if !k.key.IsPrivate {
+		return "", ErrPublicKeyOnly
+	}
+	return base58check.CheckEncode(netParams.PrivateKeyID, k.key.Key), nil
+}
+
+// PublicKey 返回十六进制的未压缩公钥, 和GenerateKeys生成的公钥格式相同.
+func (k *HDKey) PublicKey() (string, error) {
+	pubKey, err := k.publicKeyBytes()
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(pubKey), nil
+}
+
+// Address 返回P2PKH地址.
+func (k *HDKey) Address() (string, error) {
+	pubKey, err := k.publicKeyBytes()
+	if err != nil {
+		return "", err
+	}
+	return base58check.CheckEncode(netParams.PubKeyHashAddrID, hash160(pubKey)), nil
+}
+
+// Addresses 派生从start开始的count个地址, 通常在账户的扩展公钥上调用.
+// change为0时得到收款地址, 为1时得到找零地址.
+func (k *HDKey) Addresses(change, start, count uint32) ([]string, error) {
+	branch, err := k.Child(change)
+	if err != nil {
+		return nil, err
+	}
+	addresses := make([]string, 0, count)
+	for i := uint32(0); i < count; i++ {
+		child, err := branch.Child(start + i)
+		if err != nil {
+			return nil, err
+		}
+		address, err := child.Address()
+		if err != nil {
+			return nil, err
+		}
+		addresses = append(addresses, address)
+	}
+	return addresses, nil
+}
+
+func (k *HDKey) publicKeyBytes() ([]byte, error) {
+	if k.key.IsPrivate {
+		pubKey, _, err := generatePublicKey(k.key.Key, false)
+		return pubKey, err
+	}
+	return decompressPublicKey(k.key.Key)
+}
+
+// decompressPublicKey 把33字节的压缩公钥还原为65字节的未压缩公钥.
+// x不小于p或者不在曲线上时返回ErrInvalidExtendedKey.
+func decompressPublicKey(key []byte) ([]byte, error) {
+	if len(key) != 33 || (key[0] != 0x02 && key[0] != 0x03) {
+		return nil, fmt.Errorf("%w: malformed public key", ErrInvalidExtendedKey)
+	}
+	x := new(big.Int).SetBytes(key[1:])
+	if x.Cmp(curveP) >= 0 {
+		return nil, fmt.Errorf("%w: public key out of range", ErrInvalidExtendedKey)
+	}
+
+	// y^2 = x^3 + 7
+	y := new(big.Int).Exp(x, big.NewInt(3), curveP)
+	y.Add(y, curveB)
+	if y.ModSqrt(y, curveP) == nil {
+		return nil, fmt.Errorf("%w: public key is not on the curve", ErrInvalidExtendedKey)
+	}
+	if y.Bit(0) != uint(key[0]&1) {
+		y.Sub(curveP, y)
+	}
+
+	pubKey := make([]byte, 65)
+	pubKey[0] = 0x04
+	x.FillBytes(pubKey[1:33])
+	y.FillBytes(pubKey[33:])
+	return pubKey, nil
+}
@@ -0,0 +1,163 @@
+package wallet
+
+import (
+	"encoding/hex"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/smallnest/blockchain/chaincfg"
+	bip32 "github.com/tyler-smith/go-bip32"
+)
+
+// BIP-32的测试向量1.
+func TestDeriveVector(t *testing.T) {
+	seed, _ := hex.DecodeString("000102030405060708090a0b0c0d0e0f")
+	master, err := NewMasterKey(seed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if master.String() != "xprv9s21ZrQH143K3QTDL4LXw2F7HEK3wJUD2nW2nRk4stbPy6cq3jPPqjiChkVvvNKmPGJxWUtg6LnF5kejMRNNU3TGtRBeJgk33yuGBxrMPHi" {
+		t.Fatalf("unexpected master key %s", master)
+	}
+
+	key, err := master.Derive("m/0'/1/2'/2/1000000000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key.String() != "xprvA41z7zogVVwxVSgdKUHDy1SKmdb533PjDz7J6N6mV6uS3ze1ai8FHa8kmHScGpWmj4WggLyQjgPie1rFSruoUihUZREPSL39UNdE3BBDu76" {
+		t.Fatalf("unexpected xprv %s", key)
+	}
+	if key.Neuter().String() != "xpub6H1LXWLaKsWFhvm6RVpEL9P4KfRZSW7abD2ttkWP3SSQvnyA8FSVqNTEcYFgJS2UaFcxupHiYkro49S8yGasTvXEYBVPamhGW6cFJodrTHy" {
+		t.Fatalf("unexpected xpub %s", key.Neuter())
+	}
+}
+
+func TestDeriveFromXpub(t *testing.T) {
+	mnemonic, _, _, _, _, _ := GenerateBIP39("passphrase")
+	master, err := NewMasterKeyFromMnemonic(mnemonic, "passphrase")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	account, err := master.Derive(BIP44AccountPath(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	xpub, err := ParseExtendedKey(account.Neuter().String())
+	if err != nil || xpub.IsPrivate() {
+		t.Fatalf("failed to parse xpub: %v", err)
+	}
+	if _, err = xpub.PrivateKey(); err != ErrPublicKeyOnly {
+		t.Fatalf("expect ErrPublicKeyOnly but got %v", err)
+	}
+	if _, err = xpub.Child(HardenedOffset); err != ErrHardenedFromPublic {
+		t.Fatalf("expect ErrHardenedFromPublic but got %v", err)
+	}
+
+	addresses, err := xpub.Addresses(0, 0, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, address := range addresses {
+		key, err := master.Derive(BIP44Path(0, 0, uint32(i)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		privateKey, _ := key.PrivateKey()
+		publicKey, p2pkh, _ := GetPublicKey(privateKey)
+		keyAddress, _ := key.Address()
+		keyPublicKey, _ := key.PublicKey()
+		if address != p2pkh || keyAddress != p2pkh || keyPublicKey != publicKey {
+			t.Fatalf("address %d derived from xpub %s does not match %s", i, address, p2pkh)
+		}
+	}
+}
+
+func TestParsePath(t *testing.T) {
+	indexes, err := ParsePath("m/44'/0h/1/2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []uint32{44 + HardenedOffset, HardenedOffset, 1, 2}
+	for i := range expected {
+		if indexes[i] != expected[i] {
+			t.Fatalf("unexpected indexes %v", indexes)
+		}
+	}
+
+	for _, path := range []string{"m/x", "m/1//2", "m/2147483648", "n/1"} {
+		if _, err = ParsePath(path); err == nil {
+			t.Errorf("expect error for %s", path)
+		}
+	}
+}
+
+func TestParseExtendedKeyInvalid(t *testing.T) {
+	master, err := NewMasterKey(make([]byte, 16))
+	if err != nil {
+		t.Fatal(err)
+	}
+	serialize := func(version []byte, key []byte, private bool) string {
+		k := *master.key
+		k.Version, k.Key, k.IsPrivate = version, key, private
+		return k.B58Serialize()
+	}
+
+	// 找到一个不在曲线上的x, 即x^3+7不是模p的二次剩余
+	x := big.NewInt(1)
+	for ; ; x.Add(x, big.NewInt(1)) {
+		y := new(big.Int).Exp(x, big.NewInt(3), curveP)
+		if new(big.Int).ModSqrt(y.Add(y, curveB), curveP) == nil {
+			break
+		}
+	}
+	offCurve := append([]byte{0x02}, x.FillBytes(make([]byte, 32))...)
+	tooLarge := append([]byte{0x02}, curveP.Bytes()...)
+
+	xprv, xpub := netParams.HDPrivateKeyID[:], netParams.HDPublicKeyID[:]
+	for name, s := range map[string]string{
+		"zero private key":   serialize(xprv, make([]byte, 32), true),
+		"private key n":      serialize(xprv, curveN.Bytes(), true),
+		"off curve":          serialize(xpub, offCurve, false),
+		"x not less than p":  serialize(xpub, tooLarge, false),
+		"bad prefix":         serialize(xpub, append([]byte{0x04}, offCurve[1:]...), false),
+		"public key as xprv": serialize(xprv, master.Neuter().key.Key, false),
+	} {
+		if _, err := ParseExtendedKey(s); !errors.Is(err, ErrInvalidExtendedKey) {
+			t.Errorf("%s: expect ErrInvalidExtendedKey, got %v", name, err)
+		}
+	}
+	if _, err := ParseExtendedKey(serialize(bip32.PrivateWalletVersion, master.key.Key, true)); err != nil {
+		t.Errorf("failed to parse a valid xprv: %v", err)
+	}
+}
+
+func TestTestNetExtendedKey(t *testing.T) {
+	master, err := NewMasterKey(make([]byte, 16))
+	if err != nil {
+		t.Fatal(err)
+	}
+	xprv := master.String()
+
+	SetNetParams(&chaincfg.TestNet)
+	defer SetNetParams(&chaincfg.MainNet)
+
+	tprv, tpub := master.String(), master.Neuter().String()
+	if !strings.HasPrefix(tprv, "tprv") || !strings.HasPrefix(tpub, "tpub") {
+		t.Fatalf("unexpected testnet keys %s %s", tprv, tpub)
+	}
+	for _, s := range []string{tprv, tpub} {
+		key, err := ParseExtendedKey(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if key.String() != s {
+			t.Errorf("round trip of %s returns %s", s, key)
+		}
+	}
+	if _, err = ParseExtendedKey(xprv); !errors.Is(err, ErrInvalidExtendedKey) {
+		t.Errorf("expect ErrInvalidExtendedKey for a mainnet key on testnet, got %v", err)
+	}
+}
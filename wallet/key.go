@@ -1,108 +1,131 @@
 package wallet
 
 import (
+	"crypto/rand"
 	"crypto/sha256"
 	"encoding/hex"
-	"log"
-	"math"
-	"math/rand"
-	"time"
+	"fmt"
+	"io"
+	"math/big"
 
 	"github.com/smallnest/blockchain/wallet/base58check"
-	secp256k1 "github.com/toxeus/go-secp256k1"
+	"github.com/smallnest/blockchain/wallet/signer"
 	bip32 "github.com/tyler-smith/go-bip32"
 	bip39 "github.com/tyler-smith/go-bip39"
 	"golang.org/x/crypto/ripemd160"
 )
 
-var (
-	privateKeyPrefix = "80"
-	publicKeyPrefix  = "00"
-)
-
 // GenerateKeys 产生私钥、WIF地址、公钥、P2PKH地址.
-func GenerateKeys() (privateKey, wif string, publicKey, p2pkh string) {
-	priKey := generatePrivateKey()
-	privateKey = hex.EncodeToString(priKey)
-	privateKeyWif := base58check.Encode(privateKeyPrefix, priKey)
-
-	pubKey, ripeHashedBytes := generatePublicKey(priKey)
-	publicKey = hex.EncodeToString(pubKey)
-	publicKeyP2PKH := base58check.Encode(publicKeyPrefix, ripeHashedBytes)
-
-	return privateKey, privateKeyWif, publicKey, publicKeyP2PKH
+func GenerateKeys() (privateKey, wif string, publicKey, p2pkh string, err error) {
+	priKey, err := generatePrivateKey()
+	if err != nil {
+		return "", "", "", "", err
+	}
+	return keysOf(priKey)
 }
 
 // GenerateBIP39 根据BIP-39规范生成助记词、私钥、WIF地址、公钥、P2PKH地址.
-func GenerateBIP39(secretPassphrase string) (mnemonic string, privateKey, wif string, publicKey, p2pkh string) {
-	entropy, _ := bip39.NewEntropy(256)
-	mnemonic, _ = bip39.NewMnemonic(entropy)
-	seed := bip39.NewSeed(mnemonic, secretPassphrase)
-
-	masterKey, _ := bip32.NewMasterKey(seed)
-
-	priKey := masterKey.Key
-	privateKey = hex.EncodeToString(priKey)
-	privateKeyWif := base58check.Encode(privateKeyPrefix, priKey)
-
-	pubKey, ripeHashedBytes := generatePublicKey(priKey)
-	publicKey = hex.EncodeToString(pubKey)
-	publicKeyP2PKH := base58check.Encode(publicKeyPrefix, ripeHashedBytes)
-
-	return mnemonic, privateKey, privateKeyWif, publicKey, publicKeyP2PKH
+func GenerateBIP39(secretPassphrase string) (mnemonic string, privateKey, wif string, publicKey, p2pkh string, err error) {
+	entropy, err := bip39.NewEntropy(256)
+	if err != nil {
+		return "", "", "", "", "", err
+	}
+	if mnemonic, err = bip39.NewMnemonic(entropy); err != nil {
+		return "", "", "", "", "", err
+	}
+	privateKey, wif, publicKey, p2pkh, err = RecoverBIP39(mnemonic, secretPassphrase)
+	return mnemonic, privateKey, wif, publicKey, p2pkh, err
 }
 
 // RecoverBIP39 根据助记词和密码恢复私钥、WIF地址、公钥、P2PKH地址.
-func RecoverBIP39(mnemonic, secretPassphrase string) (privateKey, wif string, publicKey, p2pkh string) {
+func RecoverBIP39(mnemonic, secretPassphrase string) (privateKey, wif string, publicKey, p2pkh string, err error) {
 	seed := bip39.NewSeed(mnemonic, secretPassphrase)
-	masterKey, _ := bip32.NewMasterKey(seed)
+	masterKey, err := bip32.NewMasterKey(seed)
+	if err != nil {
+		return "", "", "", "", err
+	}
+	return keysOf(masterKey.Key)
+}
 
-	priKey := masterKey.Key
+// keysOf 根据私钥得到私钥、WIF地址、未压缩的公钥和对应的P2PKH地址.
+func keysOf(priKey []byte) (privateKey, wif string, publicKey, p2pkh string, err error) {
+	pubKey, ripeHashedBytes, err := generatePublicKey(priKey, false)
+	if err != nil {
+		return "", "", "", "", err
+	}
 	privateKey = hex.EncodeToString(priKey)
-	privateKeyWif := base58check.Encode(privateKeyPrefix, priKey)
-
-	pubKey, ripeHashedBytes := generatePublicKey(priKey)
+	privateKeyWif := base58check.CheckEncode(netParams.PrivateKeyID, priKey)
 	publicKey = hex.EncodeToString(pubKey)
-	publicKeyP2PKH := base58check.Encode(publicKeyPrefix, ripeHashedBytes)
+	publicKeyP2PKH := base58check.CheckEncode(netParams.PubKeyHashAddrID, ripeHashedBytes)
 
-	return privateKey, privateKeyWif, publicKey, publicKeyP2PKH
+	return privateKey, privateKeyWif, publicKey, publicKeyP2PKH, nil
 }
 
-func generatePublicKey(privateKeyBytes []byte) (publicKeyBytes, ripeHashedBytes []byte) {
+// generatePublicKey 根据私钥生成公钥以及公钥的hash160, compressed为true时生成33字节的压缩公钥.
+// 私钥无效时返回signer.ErrInvalidPrivateKey.
+func generatePublicKey(privateKeyBytes []byte, compressed bool) (publicKeyBytes, ripeHashedBytes []byte, err error) {
+	if !ValidPrivateKey(privateKeyBytes) {
+		return nil, nil, signer.ErrInvalidPrivateKey
+	}
 	var privateKeyBytes32 [32]byte
 	copy(privateKeyBytes32[:], privateKeyBytes)
 
-	secp256k1.Start()
-	publicKeyBytes, success := secp256k1.Pubkey_create(privateKeyBytes32, false)
-	if !success {
-		log.Fatal("Failed to create public key.")
+	publicKeyBytes, err = signer.Default().PublicKey(privateKeyBytes32, compressed)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	secp256k1.Stop()
+	ripeHashedBytes = hash160(publicKeyBytes)
+
+	return publicKeyBytes, ripeHashedBytes, nil
+}
 
-	//Next we get a sha256 hash of the public key generated
-	//via ECDSA, and then get a ripemd160 hash of the sha256 hash.
+// hash160 计算公钥的sha256哈希之后再计算ripemd160哈希.
+func hash160(publicKeyBytes []byte) []byte {
 	shaHash := sha256.New()
 	shaHash.Write(publicKeyBytes)
 	shadPublicKeyBytes := shaHash.Sum(nil)
 
 	ripeHash := ripemd160.New()
 	ripeHash.Write(shadPublicKeyBytes)
-	ripeHashedBytes = ripeHash.Sum(nil)
+	return ripeHash.Sum(nil)
+}
 
-	return publicKeyBytes, ripeHashedBytes
+// curveN 是secp256k1曲线的阶, 私钥和签名的随机数必须在[1, n-1]之内.
+var curveN, _ = new(big.Int).SetString("FFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFEBAAEDCE6AF48A03BBFD25E8CD0364141", 16)
+
+// RandomScalar 使用crypto/rand生成[1, n-1]之内均匀分布的32字节随机数, n是secp256k1曲线的阶.
+// 可以用作私钥或者签名的随机数.
+func RandomScalar() ([32]byte, error) {
+	return randomScalar(rand.Reader)
 }
 
-func generatePrivateKey() []byte {
-	bytes := make([]byte, 32)
-	for i := 0; i < 32; i++ {
-		//This is not "cryptographically random"
-		bytes[i] = byte(randInt(0, math.MaxUint8))
+func randomScalar(r io.Reader) ([32]byte, error) {
+	var b [32]byte
+	// 超出范围的概率约为2^-128, 直接丢弃重新生成, 保证均匀分布
+	for {
+		if _, err := io.ReadFull(r, b[:]); err != nil {
+			return b, err
+		}
+		if ValidPrivateKey(b[:]) {
+			return b, nil
+		}
 	}
-	return bytes
 }
 
-func randInt(min int, max int) uint8 {
-	rand.Seed(time.Now().UTC().UnixNano())
-	return uint8(min + rand.Intn(max-min))
+// ValidPrivateKey 判断key是否是合法的私钥, 即在[1, n-1]之内.
+func ValidPrivateKey(key []byte) bool {
+	if len(key) != 32 {
+		return false
+	}
+	k := new(big.Int).SetBytes(key)
+	return k.Sign() > 0 && k.Cmp(curveN) < 0
+}
+
+func generatePrivateKey() ([]byte, error) {
+	key, err := RandomScalar()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate private key: %w", err)
+	}
+	return key[:], nil
 }
@@ -0,0 +1,112 @@
+package wallet
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"math"
+	"math/big"
+	"math/bits"
+	"strings"
+	"testing"
+
+	"github.com/smallnest/blockchain/wallet/signer"
+)
+
+func TestGenerateKeysDistinct(t *testing.T) {
+	const n = 2000
+
+	seen := make(map[string]bool, n)
+	var ones int
+	var byteCounts [256]int
+	for i := 0; i < n; i++ {
+		key, err := RandomScalar()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !ValidPrivateKey(key[:]) {
+			t.Fatalf("key %x is out of range", key)
+		}
+		if seen[string(key[:])] {
+			t.Fatalf("key %x is generated twice", key)
+		}
+		seen[string(key[:])] = true
+
+		for _, b := range key {
+			ones += bits.OnesCount8(b)
+			byteCounts[b]++
+		}
+	}
+
+	// 单比特频率检验: 1的个数服从二项分布, 偏离均值超过5个标准差说明不是随机的
+	total := float64(n * 256)
+	if z := math.Abs(float64(ones)-total/2) / math.Sqrt(total/4); z > 5 {
+		t.Fatalf("bit frequency deviates from 1/2 by %.1f sigma", z)
+	}
+
+	// 字节分布的卡方检验, 255个自由度, 超过350的概率小于0.01%
+	expected := float64(n*32) / 256
+	var chiSquare float64
+	for _, count := range byteCounts {
+		d := float64(count) - expected
+		chiSquare += d * d / expected
+	}
+	if chiSquare > 350 {
+		t.Fatalf("byte distribution is not uniform, chi-square %.1f", chiSquare)
+	}
+}
+
+func TestGenerateKeysRapidCalls(t *testing.T) {
+	// 以前按时间设置随机种子, 同一时刻生成的私钥是相同的
+	seen := make(map[string]bool)
+	for i := 0; i < 200; i++ {
+		privateKey, _, _, _, _ := GenerateKeys()
+		if seen[privateKey] {
+			t.Fatalf("private key %s is generated twice", privateKey)
+		}
+		seen[privateKey] = true
+	}
+}
+
+func TestRandomScalarRange(t *testing.T) {
+	// 0和不小于n的值会被丢弃
+	n := curveN.Bytes()
+	var max [32]byte
+	for i := range max {
+		max[i] = 0xff
+	}
+	one := new(big.Int).Sub(curveN, big.NewInt(1)).Bytes()
+	r := bytes.NewReader(append(append(append(make([]byte, 32), n...), max[:]...), one...))
+
+	key, err := randomScalar(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hex.EncodeToString(key[:]) != hex.EncodeToString(one) {
+		t.Fatalf("expect n-1 but got %x", key)
+	}
+
+	for _, key := range [][]byte{make([]byte, 32), n, max[:], one[:31]} {
+		if ValidPrivateKey(key) {
+			t.Errorf("%x should be invalid", key)
+		}
+	}
+}
+
+func TestInvalidPrivateKey(t *testing.T) {
+	n := hex.EncodeToString(curveN.Bytes())
+	for _, key := range []string{"", "not hex", strings.Repeat("00", 32), n, onePrivateKey[2:]} {
+		if _, _, err := GetPublicKey(key); !errors.Is(err, signer.ErrInvalidPrivateKey) {
+			t.Errorf("GetPublicKey(%q): expect ErrInvalidPrivateKey, got %v", key, err)
+		}
+		if _, _, err := GetCompressedPublicKey(key); !errors.Is(err, signer.ErrInvalidPrivateKey) {
+			t.Errorf("GetCompressedPublicKey(%q): expect ErrInvalidPrivateKey, got %v", key, err)
+		}
+		if _, err := PrivateKey2Wif(key); !errors.Is(err, signer.ErrInvalidPrivateKey) {
+			t.Errorf("PrivateKey2Wif(%q): expect ErrInvalidPrivateKey, got %v", key, err)
+		}
+	}
+	if _, err := PublicKey2P2PKH("not hex"); err == nil {
+		t.Error("PublicKey2P2PKH should reject a non-hex public key")
+	}
+}
@@ -0,0 +1,344 @@
+package wallet
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// keystore文件的格式版本.
+const keystoreVersion = 1
+
+// scrypt的参数, StandardScryptN解密一次大约需要1秒和256MB内存, LightScryptN用于测试或者低配置的设备.
+const (
+	StandardScryptN = 1 << 18
+	StandardScryptP = 1
+	LightScryptN    = 1 << 12
+	LightScryptP    = 6
+
+	scryptR     = 8
+	scryptDKLen = 32
+
+	// 解密时接受的scrypt参数的上限, 避免构造的keystore文件耗尽内存和CPU.
+	// N=2^20, r=8时需要1GB内存.
+	maxScryptN = 1 << 20
+	maxScryptR = 32
+	maxScryptP = 16
+	// 128*N*r是scrypt需要的内存
+	maxScryptMemory = 1 << 30
+)
+
+var (
+	// ErrDecrypt 密码错误或者keystore文件被篡改.
+	ErrDecrypt = errors.New("could not decrypt key with given password")
+	// ErrNoKey keystore中没有这个地址的私钥.
+	ErrNoKey = errors.New("no key for given address")
+	// ErrScryptParams scrypt的参数超出了允许的范围.
+	ErrScryptParams = errors.New("scrypt parameters out of range")
+)
+
+// KeyFile 是加密后的私钥文件, 格式参考以太坊的v3 keystore.
+// 私钥使用scrypt从密码派生的密钥通过AES-256-GCM加密, 地址作为附加数据参与认证.
+type KeyFile struct {
+	Version int        `json:"version"`
+	ID      string     `json:"id"`
+	Address string     `json:"address"`
+	Crypto  CryptoJSON `json:"crypto"`
+}
+
+// CryptoJSON 是KeyFile中加密相关的参数.
+type CryptoJSON struct {
+	Cipher       string           `json:"cipher"`
+	CipherText   string           `json:"ciphertext"`
+	CipherParams cipherParamsJSON `json:"cipherparams"`
+	KDF          string           `json:"kdf"`
+	KDFParams    scryptParamsJSON `json:"kdfparams"`
+}
+
+type cipherParamsJSON struct {
+	Nonce string `json:"nonce"`
+}
+
+type scryptParamsJSON struct {
+	N     int    `json:"n"`
+	R     int    `json:"r"`
+	P     int    `json:"p"`
+	DKLen int    `json:"dklen"`
+	Salt  string `json:"salt"`
+}
+
+// EncryptKey 使用密码加密十六进制的私钥, 返回JSON格式的keystore文件内容.
+func EncryptKey(privateKey, password string, scryptN, scryptP int) ([]byte, error) {
+	priKey, err := hex.DecodeString(privateKey)
+	if err != nil || !ValidPrivateKey(priKey) {
+		return nil, errors.New("invalid private key")
+	}
+	_, address, err := GetPublicKey(privateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = checkScryptParams(scryptN, scryptR, scryptP); err != nil {
+		return nil, err
+	}
+
+	salt := make([]byte, 32)
+	if _, err = rand.Read(salt); err != nil {
+		return nil, err
+	}
+	derivedKey, err := scrypt.Key([]byte(password), salt, scryptN, scryptR, scryptP, scryptDKLen)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newGCM(derivedKey)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err = rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	id := make([]byte, 16)
+	if _, err = rand.Read(id); err != nil {
+		return nil, err
+	}
+	// UUID version 4
+	id[6] = id[6]&0x0f | 0x40
+	id[8] = id[8]&0x3f | 0x80
+
+	keyFile := KeyFile{
+		Version: keystoreVersion,
+		ID:      fmt.Sprintf("%x-%x-%x-%x-%x", id[0:4], id[4:6], id[6:8], id[8:10], id[10:]),
+		Address: address,
+		Crypto: CryptoJSON{
+			Cipher:       "aes-256-gcm",
+			CipherText:   hex.EncodeToString(aead.Seal(nil, nonce, priKey, []byte(address))),
+			CipherParams: cipherParamsJSON{Nonce: hex.EncodeToString(nonce)},
+			KDF:          "scrypt",
+			KDFParams: scryptParamsJSON{
+				N:     scryptN,
+				R:     scryptR,
+				P:     scryptP,
+				DKLen: scryptDKLen,
+				Salt:  hex.EncodeToString(salt),
+			},
+		},
+	}
+	return json.MarshalIndent(keyFile, "", "  ")
+}
+
+// DecryptKey 使用密码解密keystore文件, 返回十六进制的私钥.
+func DecryptKey(data []byte, password string) (string, error) {
+	var keyFile KeyFile
+	if err := json.Unmarshal(data, &keyFile); err != nil {
+		return "", err
+	}
+	if keyFile.Version != keystoreVersion {
+		return "", fmt.Errorf("unsupported keystore version %d", keyFile.Version)
+	}
+	c := keyFile.Crypto
+	if c.Cipher != "aes-256-gcm" || c.KDF != "scrypt" {
+		return "", fmt.Errorf("unsupported cipher %s or kdf %s", c.Cipher, c.KDF)
+	}
+
+	salt, err := hex.DecodeString(c.KDFParams.Salt)
+	if err != nil {
+		return "", err
+	}
+	nonce, err := hex.DecodeString(c.CipherParams.Nonce)
+	if err != nil {
+		return "", err
+	}
+	cipherText, err := hex.DecodeString(c.CipherText)
+	if err != nil {
+		return "", err
+	}
+
+	p := c.KDFParams
+	if err = checkScryptParams(p.N, p.R, p.P); err != nil {
+		return "", err
+	}
+	if p.DKLen != scryptDKLen {
+		return "", fmt.Errorf("%w: dklen %d", ErrScryptParams, p.DKLen)
+	}
+	derivedKey, err := scrypt.Key([]byte(password), salt, p.N, p.R, p.P, p.DKLen)
+	if err != nil {
+		return "", err
+	}
+	aead, err := newGCM(derivedKey)
+	if err != nil {
+		return "", err
+	}
+	if len(nonce) != aead.NonceSize() {
+		return "", ErrDecrypt
+	}
+	priKey, err := aead.Open(nil, nonce, cipherText, []byte(keyFile.Address))
+	if err != nil {
+		return "", ErrDecrypt
+	}
+	return hex.EncodeToString(priKey), nil
+}
+
+// checkScryptParams 检查scrypt的参数没有超过上限.
+func checkScryptParams(n, r, p int) error {
+	if n <= 1 || n > maxScryptN || r <= 0 || r > maxScryptR || p <= 0 || p > maxScryptP ||
+		128*n*r > maxScryptMemory {
+		return fmt.Errorf("%w: n=%d r=%d p=%d", ErrScryptParams, n, r, p)
+	}
+	return nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// Keystore 是保存keystore文件的目录, 每个私钥一个文件.
+type Keystore struct {
+	Dir     string
+	ScryptN int
+	ScryptP int
+}
+
+// NewKeystore 创建一个使用标准scrypt参数的Keystore.
+func NewKeystore(dir string) *Keystore {
+	return &Keystore{
+		Dir:     dir,
+		ScryptN: StandardScryptN,
+		ScryptP: StandardScryptP,
+	}
+}
+
+// Account 是keystore中的一个私钥文件.
+type Account struct {
+	Address string
+	Path    string
+}
+
+// NewAccount 生成一个新的私钥并使用密码加密保存.
+func (ks *Keystore) NewAccount(password string) (Account, error) {
+	key, err := RandomScalar()
+	if err != nil {
+		return Account{}, err
+	}
+	return ks.Import(hex.EncodeToString(key[:]), password)
+}
+
+// Import 使用密码加密保存十六进制的私钥.
+func (ks *Keystore) Import(privateKey, password string) (Account, error) {
+	data, err := EncryptKey(privateKey, password, ks.ScryptN, ks.ScryptP)
+	if err != nil {
+		return Account{}, err
+	}
+	_, address, err := GetPublicKey(privateKey)
+	if err != nil {
+		return Account{}, err
+	}
+	if _, err = ks.Find(address); err == nil {
+		return Account{}, fmt.Errorf("key of %s already exists", address)
+	}
+
+	if err = os.MkdirAll(ks.Dir, 0700); err != nil {
+		return Account{}, err
+	}
+	name := fmt.Sprintf("UTC--%s--%s.json", time.Now().UTC().Format("2006-01-02T15-04-05.000000000Z"), address)
+	path := filepath.Join(ks.Dir, name)
+	// 先写临时文件再改名, 避免留下不完整的文件
+	tmp := path + ".tmp"
+	if err = ioutil.WriteFile(tmp, data, 0600); err != nil {
+		return Account{}, err
+	}
+	if err = os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return Account{}, err
+	}
+	return Account{Address: address, Path: path}, nil
+}
+
+// Accounts 列出keystore中所有的私钥文件, 按照文件名排序.
+func (ks *Keystore) Accounts() ([]Account, error) {
+	files, err := ioutil.ReadDir(ks.Dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var accounts []Account
+	for _, fi := range files {
+		if fi.IsDir() || !strings.HasSuffix(fi.Name(), ".json") {
+			continue
+		}
+		path := filepath.Join(ks.Dir, fi.Name())
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		var keyFile KeyFile
+		if err = json.Unmarshal(data, &keyFile); err != nil || keyFile.Address == "" {
+			// 忽略不是keystore的文件
+			continue
+		}
+		accounts = append(accounts, Account{Address: keyFile.Address, Path: path})
+	}
+	sort.Slice(accounts, func(i, j int) bool { return accounts[i].Path < accounts[j].Path })
+	return accounts, nil
+}
+
+// Find 查找地址对应的私钥文件.
+func (ks *Keystore) Find(address string) (Account, error) {
+	accounts, err := ks.Accounts()
+	if err != nil {
+		return Account{}, err
+	}
+	for _, account := range accounts {
+		if account.Address == address {
+			return account, nil
+		}
+	}
+	return Account{}, ErrNoKey
+}
+
+// Unlock 使用密码解密地址对应的私钥.
+func (ks *Keystore) Unlock(address, password string) (string, error) {
+	account, err := ks.Find(address)
+	if err != nil {
+		return "", err
+	}
+	return UnlockFile(account.Path, password)
+}
+
+// UnlockFile 使用密码解密keystore文件中的私钥.
+func UnlockFile(path, password string) (string, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return DecryptKey(data, password)
+}
+
+// ReadPasswordFile 读取密码文件, 去掉末尾的换行.
+func ReadPasswordFile(path string) (string, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
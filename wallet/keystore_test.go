@@ -0,0 +1,90 @@
+package wallet
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestKeystore(t *testing.T) {
+	ks := NewKeystore(t.TempDir())
+	ks.ScryptN, ks.ScryptP = LightScryptN, LightScryptP
+
+	account, err := ks.NewAccount("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	privateKey, _, _, _, _ := GenerateKeys()
+	imported, err := ks.Import(privateKey, "other")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = ks.Import(privateKey, "other"); err == nil {
+		t.Fatal("expect error when importing a key twice")
+	}
+
+	accounts, err := ks.Accounts()
+	if err != nil || len(accounts) != 2 {
+		t.Fatalf("expect 2 accounts: %v", err)
+	}
+
+	unlocked, err := ks.Unlock(imported.Address, "other")
+	if err != nil || unlocked != privateKey {
+		t.Fatalf("failed to unlock the imported key: %v", err)
+	}
+	unlocked, err = ks.Unlock(account.Address, "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, address, _ := GetPublicKey(unlocked); address != account.Address {
+		t.Fatalf("unlocked key does not match address %s", account.Address)
+	}
+
+	if _, err = ks.Unlock(account.Address, "wrong"); err != ErrDecrypt {
+		t.Fatalf("expect ErrDecrypt but got %v", err)
+	}
+	if _, err = ks.Unlock("1BoatSLRHtKNngkdXEeobR76b53LETtpyT", "secret"); err != ErrNoKey {
+		t.Fatalf("expect ErrNoKey but got %v", err)
+	}
+
+	// 地址参与认证, 修改地址后无法解密
+	data, _ := ioutil.ReadFile(account.Path)
+	tampered := strings.Replace(string(data), account.Address, imported.Address, 1)
+	if _, err = DecryptKey([]byte(tampered), "secret"); err != ErrDecrypt {
+		t.Fatalf("expect ErrDecrypt for a tampered file but got %v", err)
+	}
+}
+
+func TestScryptParamsLimit(t *testing.T) {
+	privateKey, _, _, _, _ := GenerateKeys()
+	data, err := EncryptKey(privateKey, "secret", LightScryptN, LightScryptP)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var keyFile KeyFile
+	if err = json.Unmarshal(data, &keyFile); err != nil {
+		t.Fatal(err)
+	}
+
+	// 构造的keystore文件不能让解密耗尽内存和CPU
+	for name, modify := range map[string]func(p *scryptParamsJSON){
+		"n":      func(p *scryptParamsJSON) { p.N = 1 << 30 },
+		"r":      func(p *scryptParamsJSON) { p.R = 1 << 20 },
+		"p":      func(p *scryptParamsJSON) { p.P = 1 << 20 },
+		"memory": func(p *scryptParamsJSON) { p.N, p.R = maxScryptN, maxScryptR },
+		"dklen":  func(p *scryptParamsJSON) { p.DKLen = 1 << 30 },
+	} {
+		tampered := keyFile
+		modify(&tampered.Crypto.KDFParams)
+		data, _ := json.Marshal(tampered)
+		if _, err = DecryptKey(data, "secret"); !errors.Is(err, ErrScryptParams) {
+			t.Errorf("%s: expect ErrScryptParams but got %v", name, err)
+		}
+	}
+
+	if _, err = EncryptKey(privateKey, "secret", maxScryptN*2, 1); !errors.Is(err, ErrScryptParams) {
+		t.Errorf("expect ErrScryptParams but got %v", err)
+	}
+}
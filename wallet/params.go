@@ -0,0 +1,16 @@
+package wallet
+
+import "github.com/smallnest/blockchain/chaincfg"
+
+// netParams 是生成和解析地址、WIF使用的网络参数.
+var netParams = &chaincfg.MainNet
+
+// SetNetParams 设置钱包使用的网络, 默认是主网. 需要在生成或者解析地址之前调用.
+func SetNetParams(params *chaincfg.NetParams) {
+	netParams = params
+}
+
+// NetParams 返回钱包使用的网络参数.
+func NetParams() *chaincfg.NetParams {
+	return netParams
+}
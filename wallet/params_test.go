@@ -0,0 +1,47 @@
+package wallet
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/smallnest/blockchain/chaincfg"
+)
+
+func TestTestNetParams(t *testing.T) {
+	SetNetParams(&chaincfg.TestNet)
+	defer SetNetParams(&chaincfg.MainNet)
+
+	_, p2pkh, _ := GetPublicKey(onePrivateKey)
+	if p2pkh != "mtoKs9V381UAhUia3d7Vb9GNak8Qvmcsme" {
+		t.Errorf("unexpected testnet address %s", p2pkh)
+	}
+	publicKey, compressedP2PKH, _ := GetCompressedPublicKey(onePrivateKey)
+	if compressedP2PKH != "mrCDrCybB6J1vRfbwM5hemdJz73FwDBC8r" {
+		t.Errorf("unexpected compressed testnet address %s", compressedP2PKH)
+	}
+	wif, _ := PrivateKey2CompressedWif(onePrivateKey)
+	if wif != "cMahea7zqjxrtgAbB7LSGbcQUr1uX1ojuat9jZodMN87JcbXMTcA" {
+		t.Errorf("unexpected testnet wif %s", wif)
+	}
+	if privateKey, compressed, err := DecodeWIF(wif); err != nil || privateKey != onePrivateKey || !compressed {
+		t.Errorf("DecodeWIF(%s) = %s, %t, %v", wif, privateKey, compressed, err)
+	}
+	segwit, err := PublicKey2P2WPKH(publicKey)
+	if err != nil || segwit != "tb1qw508d6qejxtdg4y5r3zarvary0c5xw7kxpjzsx" {
+		t.Errorf("PublicKey2P2WPKH = %s, %v", segwit, err)
+	}
+	if addr, err := ParseAddress(segwit); err != nil || addr.Type != AddressP2WPKH {
+		t.Errorf("ParseAddress(%s) = %v, %v", segwit, addr, err)
+	}
+	if path := BIP44Path(0, 0, 0); path != "m/44'/1'/0'/0/0" {
+		t.Errorf("unexpected testnet BIP-44 path %s", path)
+	}
+
+	// 主网的地址和私钥不能在测试网使用
+	if _, err = ParseAddress("1BgGZ9tcN4rm9KBzDn7KprQz87SZ26SAMH"); !errors.Is(err, ErrUnknownAddress) {
+		t.Errorf("expect ErrUnknownAddress for a mainnet address, got %v", err)
+	}
+	if _, _, err = DecodeWIF("KwDiBf89QgGbjEhKnhXJuH7LrciVrZi3qYjgd9M7rFU73sVHnoWn"); !errors.Is(err, ErrInvalidWIF) {
+		t.Errorf("expect ErrInvalidWIF for a mainnet wif, got %v", err)
+	}
+}
@@ -0,0 +1,235 @@
+package schnorr
+
+import (
+	"crypto/rand"
+	"math/bits"
+	"runtime"
+	"sync"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	bip340 "github.com/btcsuite/btcd/btcec/v2/schnorr"
+)
+
+// batchWorkerMin 每个goroutine至少批量校验这么多个签名, 批量较小时不值得并发.
+const batchWorkerMin = 16
+
+// Item 是批量校验中的一个签名.
+type Item struct {
+	// Hash 是签名的32字节哈希.
+	Hash [32]byte
+	// Signature 是64字节的签名.
+	Signature []byte
+	// PublicKey 可以是压缩、未压缩或者32字节x-only的公钥.
+	PublicKey []byte
+}
+
+// BatchVerify 批量校验签名, 所有签名都有效时返回true.
+// 按照BIP-340的批量校验算法, 用随机系数把所有的校验等式合并为一个, 再用Strauss算法计算多标量乘法,
+// 所有的点共享倍点运算, 比逐个校验更快. 返回false时不能确定是哪个签名无效, 需要逐个调用Verify.
+func BatchVerify(items []Item) bool {
+	if len(items) == 0 {
+		return true
+	}
+
+	workers := runtime.GOMAXPROCS(0)
+	if n := (len(items) + batchWorkerMin - 1) / batchWorkerMin; n < workers {
+		workers = n
+	}
+	size := (len(items) + workers - 1) / workers
+
+	results := make([]batchSum, workers)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		start, end := i*size, (i+1)*size
+		if end > len(items) {
+			end = len(items)
+		}
+		wg.Add(1)
+		go func(result *batchSum, items []Item, first bool) {
+			defer wg.Done()
+			result.add(items, first)
+		}(&results[i], items[start:end], i == 0)
+	}
+	wg.Wait()
+
+	// 检查 (a_1*s_1 + ... + a_u*s_u)*G == a_1*R_1 + a_1*e_1*P_1 + ... + a_u*R_u + a_u*e_u*P_u
+	var s btcec.ModNScalar
+	var sum btcec.JacobianPoint
+	for i := range results {
+		if !results[i].ok {
+			return false
+		}
+		s.Add(&results[i].s)
+		btcec.AddNonConst(&sum, &results[i].point, &sum)
+	}
+
+	var sG btcec.JacobianPoint
+	s.Negate()
+	btcec.ScalarBaseMultNonConst(&s, &sG)
+	btcec.AddNonConst(&sum, &sG, &sum)
+	return isInfinity(&sum)
+}
+
+// batchSum 是一部分签名的校验等式之和.
+type batchSum struct {
+	s     btcec.ModNScalar
+	point btcec.JacobianPoint
+	ok    bool
+}
+
+// batchCoefficientSize 是随机系数的字节数, 128比特的系数足以让伪造的签名以可忽略的概率通过校验.
+const batchCoefficientSize = 16
+
+// add 累加items的校验等式, first为true时第一个签名的系数为1.
+func (b *batchSum) add(items []Item, first bool) {
+	random := make([]byte, batchCoefficientSize*len(items))
+	if _, err := rand.Read(random); err != nil {
+		return
+	}
+
+	scalars := make([]btcec.ModNScalar, 0, 2*len(items))
+	points := make([]btcec.JacobianPoint, 0, 2*len(items))
+	for i, item := range items {
+		if len(item.Signature) != SignatureSize {
+			return
+		}
+		P, err := ParsePublicKey(item.PublicKey)
+		if err != nil {
+			return
+		}
+		// R = lift_x(r), 如果r >= p或者不在曲线上则失败
+		R, err := bip340.ParsePubKey(item.Signature[:32])
+		if err != nil {
+			return
+		}
+		var s btcec.ModNScalar
+		if overflow := s.SetByteSlice(item.Signature[32:]); overflow {
+			return
+		}
+
+		// e = int(tagged_hash("BIP0340/challenge", bytes(r) || bytes(P) || m)) mod n
+		commitment := taggedHash("BIP0340/challenge", item.Signature[:32], bip340.SerializePubKey(P), item.Hash[:])
+		var e btcec.ModNScalar
+		e.SetBytes(&commitment)
+
+		var a btcec.ModNScalar
+		a.SetByteSlice(random[i*batchCoefficientSize : (i+1)*batchCoefficientSize])
+		if (first && i == 0) || a.IsZero() {
+			a.SetInt(1)
+		}
+
+		// s += a*s_i, point += a*R_i + (a*e_i)*P_i
+		s.Mul(&a)
+		b.s.Add(&s)
+		e.Mul(&a)
+
+		var rJ, pJ btcec.JacobianPoint
+		R.AsJacobian(&rJ)
+		P.AsJacobian(&pJ)
+		scalars = append(scalars, a, e)
+		points = append(points, rJ, pJ)
+	}
+	multiScalarMult(scalars, points, &b.point)
+	b.ok = true
+}
+
+// wnafWidth 是多标量乘法使用的wNAF窗口大小, 每个点预先计算2^(w-2)个奇数倍.
+const wnafWidth = 5
+
+// multiScalarMult 使用Strauss算法计算 k_1*P_1 + ... + k_n*P_n, 所有的点共享同一组倍点运算.
+func multiScalarMult(scalars []btcec.ModNScalar, points []btcec.JacobianPoint, result *btcec.JacobianPoint) {
+	nafs := make([][]int8, len(scalars))
+	tables := make([][]btcec.JacobianPoint, len(points))
+	maxLen := 0
+	for i := range scalars {
+		nafs[i] = wnaf(&scalars[i], wnafWidth)
+		if len(nafs[i]) > maxLen {
+			maxLen = len(nafs[i])
+		}
+		tables[i] = oddMultiples(&points[i], wnafWidth)
+	}
+
+	var sum btcec.JacobianPoint
+	for bit := maxLen - 1; bit >= 0; bit-- {
+		btcec.DoubleNonConst(&sum, &sum)
+		for i, naf := range nafs {
+			if bit >= len(naf) || naf[bit] == 0 {
+				continue
+			}
+			d := naf[bit]
+			if d > 0 {
+				btcec.AddNonConst(&sum, &tables[i][d/2], &sum)
+				continue
+			}
+			neg := tables[i][-d/2]
+			neg.Y.Negate(1).Normalize()
+			btcec.AddNonConst(&sum, &neg, &sum)
+		}
+	}
+	result.Set(&sum)
+}
+
+// oddMultiples 计算P, 3P, 5P, ..., (2^(w-1)-1)P.
+func oddMultiples(p *btcec.JacobianPoint, w uint) []btcec.JacobianPoint {
+	table := make([]btcec.JacobianPoint, 1<<(w-2))
+	table[0].Set(p)
+	var double btcec.JacobianPoint
+	btcec.DoubleNonConst(p, &double)
+	for i := 1; i < len(table); i++ {
+		btcec.AddNonConst(&table[i-1], &double, &table[i])
+	}
+	return table
+}
+
+// wnaf 计算标量k的宽度为w的非相邻形式, 从最低位开始, 每一位是0或者绝对值小于2^(w-1)的奇数.
+func wnaf(k *btcec.ModNScalar, w uint) []int8 {
+	b := k.Bytes()
+	// 小端的64位limb, 多一个limb用于进位
+	var limbs [5]uint64
+	for i := 0; i < 4; i++ {
+		for j := 0; j < 8; j++ {
+			limbs[i] |= uint64(b[31-i*8-j]) << (8 * uint(j))
+		}
+	}
+
+	isZero := func() bool {
+		return limbs[0]|limbs[1]|limbs[2]|limbs[3]|limbs[4] == 0
+	}
+	window := uint64(1) << w
+	naf := make([]int8, 0, 257)
+	for !isZero() {
+		var d int64
+		if limbs[0]&1 == 1 {
+			d = int64(limbs[0] & (window - 1))
+			if d >= int64(window/2) {
+				d -= int64(window)
+			}
+			// k -= d
+			if d > 0 {
+				var borrow uint64
+				limbs[0], borrow = bits.Sub64(limbs[0], uint64(d), 0)
+				for i := 1; i < len(limbs) && borrow != 0; i++ {
+					limbs[i], borrow = bits.Sub64(limbs[i], 0, borrow)
+				}
+			} else {
+				var carry uint64
+				limbs[0], carry = bits.Add64(limbs[0], uint64(-d), 0)
+				for i := 1; i < len(limbs) && carry != 0; i++ {
+					limbs[i], carry = bits.Add64(limbs[i], 0, carry)
+				}
+			}
+		}
+		naf = append(naf, int8(d))
+
+		// k >>= 1
+		for i := 0; i < len(limbs)-1; i++ {
+			limbs[i] = limbs[i]>>1 | limbs[i+1]<<63
+		}
+		limbs[len(limbs)-1] >>= 1
+	}
+	return naf
+}
+
+func isInfinity(p *btcec.JacobianPoint) bool {
+	return p.Z.IsZero() || (p.X.IsZero() && p.Y.IsZero())
+}
@@ -0,0 +1,68 @@
+// Package schnorr 实现BIP-340的Schnorr签名, 以及使用Strauss多标量乘法的批量校验.
+package schnorr
+
+import (
+	"crypto/sha256"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	bip340 "github.com/btcsuite/btcd/btcec/v2/schnorr"
+	"github.com/smallnest/blockchain/wallet/signer"
+)
+
+// SignatureSize 是BIP-340签名的长度.
+const SignatureSize = 64
+
+// Sign 使用辅助数据aux对32字节的哈希签名, 返回64字节的签名.
+// 私钥无效时返回signer.ErrInvalidPrivateKey. 相同的参数总是得到相同的签名,
+// BIP-340推荐使用随机的aux生成nonce, 避免侧信道攻击.
+func Sign(hash, privateKey, aux [32]byte) ([]byte, error) {
+	var d btcec.ModNScalar
+	if overflow := d.SetBytes(&privateKey); overflow != 0 || d.IsZero() {
+		return nil, signer.ErrInvalidPrivateKey
+	}
+	key := btcec.PrivKeyFromScalar(&d)
+	sig, err := bip340.Sign(key, hash[:], bip340.CustomNonce(aux))
+	if err != nil {
+		return nil, err
+	}
+	return sig.Serialize(), nil
+}
+
+// Verify 校验对32字节的哈希的签名, publicKey可以是压缩、未压缩或者32字节x-only的公钥.
+func Verify(hash [32]byte, signature, publicKey []byte) bool {
+	pubKey, err := ParsePublicKey(publicKey)
+	if err != nil {
+		return false
+	}
+	sig, err := bip340.ParseSignature(signature)
+	if err != nil {
+		return false
+	}
+	return sig.Verify(hash[:], pubKey)
+}
+
+// ParsePublicKey 解析公钥, 返回y坐标为偶数的点, 即BIP-340的lift_x(x).
+func ParsePublicKey(publicKey []byte) (*btcec.PublicKey, error) {
+	if len(publicKey) == 32 {
+		return bip340.ParsePubKey(publicKey)
+	}
+	key, err := btcec.ParsePubKey(publicKey)
+	if err != nil {
+		return nil, err
+	}
+	return bip340.ParsePubKey(bip340.SerializePubKey(key))
+}
+
+// taggedHash 是BIP-340的带标签的哈希: sha256(sha256(tag) || sha256(tag) || msgs).
+func taggedHash(tag string, msgs ...[]byte) [32]byte {
+	tagHash := sha256.Sum256([]byte(tag))
+	h := sha256.New()
+	h.Write(tagHash[:])
+	h.Write(tagHash[:])
+	for _, msg := range msgs {
+		h.Write(msg)
+	}
+	var sum [32]byte
+	copy(sum[:], h.Sum(nil))
+	return sum
+}
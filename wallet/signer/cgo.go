@@ -0,0 +1,69 @@
+//go:build cgo && !purego
+
+package signer
+
+import (
+	"sync"
+
+	secp256k1 "github.com/toxeus/go-secp256k1"
+)
+
+// cgoSigner 通过cgo调用libsecp256k1.
+type cgoSigner struct{}
+
+func init() {
+	register(cgoSigner{})
+	defaultSigner = cgoSigner{}
+}
+
+var startOnce sync.Once
+
+// start 在第一次使用时创建libsecp256k1的上下文, 之后一直复用, 不再调用Stop.
+// 每次调用都Start/Stop不但要重新计算预计算表, 并发时还会释放其它goroutine正在使用的上下文.
+func start() {
+	startOnce.Do(secp256k1.Start)
+}
+
+func (cgoSigner) Name() string {
+	return "cgo"
+}
+
+func (cgoSigner) PublicKey(privateKey [32]byte, compressed bool) ([]byte, error) {
+	if !validScalar(privateKey) {
+		return nil, ErrInvalidPrivateKey
+	}
+	start()
+	pubKey, success := secp256k1.Pubkey_create(privateKey, compressed)
+	if !success {
+		return nil, ErrInvalidPrivateKey
+	}
+	return pubKey, nil
+}
+
+func (cgoSigner) Sign(hash, privateKey, nonce [32]byte) ([]byte, error) {
+	if !validScalar(privateKey) {
+		return nil, ErrInvalidPrivateKey
+	}
+	start()
+	signed, success := secp256k1.Sign(hash, privateKey, &nonce)
+	if !success {
+		return nil, ErrInvalidNonce
+	}
+	return signed, nil
+}
+
+func (cgoSigner) Verify(hash [32]byte, signature, publicKey []byte) bool {
+	start()
+	return secp256k1.Verify(hash, signature, publicKey)
+}
+
+// SignCompact 使用纯Go的实现, go-secp256k1没有封装libsecp256k1的可恢复签名.
+// 签名由nonce决定, 两种实现的结果相同.
+func (cgoSigner) SignCompact(hash, privateKey, nonce [32]byte, compressed bool) ([]byte, error) {
+	return pureGo.SignCompact(hash, privateKey, nonce, compressed)
+}
+
+// RecoverCompact 使用纯Go的实现, 原因同SignCompact.
+func (cgoSigner) RecoverCompact(hash [32]byte, signature []byte) ([]byte, bool, error) {
+	return pureGo.RecoverCompact(hash, signature)
+}
@@ -0,0 +1,118 @@
+package signer
+
+import (
+	"fmt"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcec/v2/ecdsa"
+)
+
+// pureGo 是基于btcec的纯Go实现.
+var pureGo Signer = goSigner{}
+
+type goSigner struct{}
+
+func (goSigner) Name() string {
+	return "go"
+}
+
+func (goSigner) PublicKey(privateKey [32]byte, compressed bool) ([]byte, error) {
+	if !validScalar(privateKey) {
+		return nil, ErrInvalidPrivateKey
+	}
+	_, pubKey := btcec.PrivKeyFromBytes(privateKey[:])
+	if compressed {
+		return pubKey.SerializeCompressed(), nil
+	}
+	return pubKey.SerializeUncompressed(), nil
+}
+
+func (goSigner) Sign(hash, privateKey, nonce [32]byte) ([]byte, error) {
+	r, s, _, err := sign(hash, privateKey, nonce)
+	if err != nil {
+		return nil, err
+	}
+	return ecdsa.NewSignature(&r, &s).Serialize(), nil
+}
+
+func (goSigner) SignCompact(hash, privateKey, nonce [32]byte, compressed bool) ([]byte, error) {
+	r, s, recoveryID, err := sign(hash, privateKey, nonce)
+	if err != nil {
+		return nil, err
+	}
+	// 恢复标记是27 + recoveryID, 压缩公钥再加4
+	signed := make([]byte, CompactSignatureSize)
+	signed[0] = 27 + recoveryID
+	if compressed {
+		signed[0] += 4
+	}
+	r.PutBytesUnchecked(signed[1:33])
+	s.PutBytesUnchecked(signed[33:65])
+	return signed, nil
+}
+
+// sign 计算ECDSA签名的r和s, 以及从签名恢复公钥需要的recoveryID.
+func sign(hash, privateKey, nonce [32]byte) (r, s btcec.ModNScalar, recoveryID byte, err error) {
+	if !validScalar(privateKey) {
+		return r, s, 0, ErrInvalidPrivateKey
+	}
+	if !validScalar(nonce) {
+		return r, s, 0, ErrInvalidNonce
+	}
+	var d, k btcec.ModNScalar
+	d.SetBytes(&privateKey)
+	k.SetBytes(&nonce)
+
+	// r = (k*G).x mod n, recoveryID记录R.y的奇偶以及R.x是否不小于n
+	var R btcec.JacobianPoint
+	btcec.ScalarBaseMultNonConst(&k, &R)
+	R.ToAffine()
+	if overflow := r.SetBytes(R.X.Bytes()); overflow != 0 {
+		recoveryID |= 2
+	}
+	if r.IsZero() {
+		return r, s, 0, ErrInvalidNonce
+	}
+	recoveryID |= byte(R.Y.IsOddBit())
+
+	// s = k^-1 * (e + r*d) mod n
+	var e btcec.ModNScalar
+	e.SetBytes(&hash)
+	s.Mul2(&r, &d).Add(&e)
+	s.Mul(new(btcec.ModNScalar).InverseValNonConst(&k))
+	if s.IsZero() {
+		return r, s, 0, ErrInvalidNonce
+	}
+	// 和libsecp256k1一样使用较小的s, 避免签名的延展性. s取反相当于R取反, y的奇偶也随之改变
+	if s.IsOverHalfOrder() {
+		s.Negate()
+		recoveryID ^= 1
+	}
+	return r, s, recoveryID, nil
+}
+
+func (goSigner) Verify(hash [32]byte, signature, publicKey []byte) bool {
+	sig, err := ecdsa.ParseDERSignature(signature)
+	if err != nil {
+		return false
+	}
+	pubKey, err := btcec.ParsePubKey(publicKey)
+	if err != nil {
+		return false
+	}
+	return sig.Verify(hash[:], pubKey)
+}
+
+func (goSigner) RecoverCompact(hash [32]byte, signature []byte) ([]byte, bool, error) {
+	if len(signature) != CompactSignatureSize {
+		return nil, false, ErrInvalidSignature
+	}
+	pubKey, compressed, err := ecdsa.RecoverCompact(signature, hash[:])
+	if err != nil {
+		return nil, false, fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+	}
+	if compressed {
+		return pubKey.SerializeCompressed(), true, nil
+	}
+	return pubKey.SerializeUncompressed(), false, nil
+}
@@ -0,0 +1,97 @@
+// Package signer 封装secp256k1的公钥生成、ECDSA签名和校验, 提供纯Go和cgo两种实现.
+//
+// 纯Go的实现总是可用, 可以静态编译. 启用cgo并且没有指定purego编译标签时还会注册基于libsecp256k1的实现,
+// 并作为默认的实现. 使用 go build -tags purego 或者 CGO_ENABLED=0 编译时只有纯Go的实现,
+// 运行时也可以通过Use选择实现.
+package signer
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+)
+
+var (
+	// ErrInvalidPrivateKey 私钥不在[1, n-1]之内.
+	ErrInvalidPrivateKey = errors.New("invalid private key")
+	// ErrInvalidNonce 签名的随机数不在[1, n-1]之内, 或者由它得到的签名无效.
+	ErrInvalidNonce = errors.New("invalid nonce")
+	// ErrUnknownSigner 没有注册这个名字的实现.
+	ErrUnknownSigner = errors.New("unknown signer")
+	// ErrInvalidSignature 不是合法的可恢复签名, 或者无法从它恢复出公钥.
+	ErrInvalidSignature = errors.New("invalid recoverable signature")
+)
+
+// CompactSignatureSize 是可恢复签名的长度: 1字节的恢复标记、32字节的r和32字节的s.
+const CompactSignatureSize = 65
+
+// Signer 是secp256k1的一种实现, 所有的方法都可以并发调用.
+type Signer interface {
+	// Name 返回实现的名字.
+	Name() string
+	// PublicKey 根据私钥生成公钥, compressed为true时生成33字节的压缩公钥, 否则生成65字节的未压缩公钥.
+	PublicKey(privateKey [32]byte, compressed bool) ([]byte, error)
+	// Sign 使用随机数nonce作为k对32字节的哈希签名, 返回DER编码的low-S签名.
+	// 相同的参数在不同的实现中得到相同的签名.
+	Sign(hash, privateKey, nonce [32]byte) ([]byte, error)
+	// Verify 校验DER编码的签名, publicKey可以是压缩或者未压缩的公钥.
+	Verify(hash [32]byte, signature, publicKey []byte) bool
+	// SignCompact 使用随机数nonce作为k对32字节的哈希签名, 返回和比特币signmessage相同格式的low-S可恢复签名.
+	// compressed表示签名者使用压缩公钥, 它记录在恢复标记中.
+	SignCompact(hash, privateKey, nonce [32]byte, compressed bool) ([]byte, error)
+	// RecoverCompact 从可恢复签名恢复签名者的公钥, 按照恢复标记返回压缩或者未压缩的公钥.
+	RecoverCompact(hash [32]byte, signature []byte) (publicKey []byte, compressed bool, err error)
+}
+
+// signers 是已经注册的实现.
+var signers = map[string]Signer{pureGo.Name(): pureGo}
+
+// defaultSigner 是钱包和区块签名使用的实现.
+var defaultSigner Signer = pureGo
+
+func register(s Signer) {
+	signers[s.Name()] = s
+}
+
+// Default 返回默认的实现.
+func Default() Signer {
+	return defaultSigner
+}
+
+// Use 把名字为name的实现设置为默认的实现. 需要在生成密钥或者签名之前调用.
+func Use(name string) error {
+	s, err := Lookup(name)
+	if err != nil {
+		return err
+	}
+	defaultSigner = s
+	return nil
+}
+
+// Lookup 返回名字为name的实现.
+func Lookup(name string) (Signer, error) {
+	s, ok := signers[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownSigner, name)
+	}
+	return s, nil
+}
+
+// Names 返回所有可用的实现的名字.
+func Names() []string {
+	names := make([]string, 0, len(signers))
+	for name := range signers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// validScalar 判断b是否在[1, n-1]之内, n是secp256k1曲线的阶.
+func validScalar(b [32]byte) bool {
+	var k btcec.ModNScalar
+	overflow := k.SetBytes(&b)
+	return overflow == 0 && !k.IsZero()
+}
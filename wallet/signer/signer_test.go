@@ -0,0 +1,269 @@
+package signer
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcec/v2/ecdsa"
+)
+
+func mustHex32(t *testing.T, s string) [32]byte {
+	t.Helper()
+	var b [32]byte
+	if n, err := hex.Decode(b[:], []byte(s)); err != nil || n != 32 {
+		t.Fatalf("invalid hex %q: %v", s, err)
+	}
+	return b
+}
+
+// allSigners 返回当前编译的所有实现, 启用cgo时同时包含cgo和纯Go的实现.
+func allSigners(t *testing.T) []Signer {
+	var all []Signer
+	for _, name := range Names() {
+		s, err := Lookup(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		all = append(all, s)
+	}
+	return all
+}
+
+// 所有的实现都必须得到相同的结果. 签名的k取自RFC 6979的secp256k1测试向量.
+func TestVectors(t *testing.T) {
+	publicKeys := []struct {
+		privateKey   string
+		compressed   string
+		uncompressed string
+	}{
+		{
+			privateKey:   "0000000000000000000000000000000000000000000000000000000000000001",
+			compressed:   "0279be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798",
+			uncompressed: "0479be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798483ada7726a3c4655da4fbfc0e1108a8fd17b448a68554199c47d08ffb10d4b8",
+		},
+		{
+			privateKey:   "0000000000000000000000000000000000000000000000000000000000000003",
+			compressed:   "02f9308a019258c31049344f85f89d5229b531c845836f99b08601f113bce036f9",
+			uncompressed: "04f9308a019258c31049344f85f89d5229b531c845836f99b08601f113bce036f9388f7b0f632de8140fe337e62a37f3566500a99934c2231b6cb9fd7584b8e672",
+		},
+	}
+	signatures := []struct {
+		privateKey string
+		message    string
+		nonce      string
+		signature  string
+	}{
+		{
+			privateKey: "0000000000000000000000000000000000000000000000000000000000000001",
+			message:    "Satoshi Nakamoto",
+			nonce:      "8f8a276c19f4149656b280621e358cce24f5f52542772691ee69063b74f15d15",
+			signature:  "3045022100934b1ea10a4b3c1757e2b0c017d0b6143ce3c9a7e6a4a49860d7a6ab210ee3d802202442ce9d2b916064108014783e923ec36b49743e2ffa1c4496f01a512aafd9e5",
+		},
+		{
+			privateKey: "0000000000000000000000000000000000000000000000000000000000000001",
+			message:    "All those moments will be lost in time, like tears in rain. Time to die...",
+			nonce:      "38aa22d72376b4dbc472e06c3ba403ee0a394da63fc58d88686c611aba98d6b3",
+			signature:  "30450221008600dbd41e348fe5c9465ab92d23e3db8b98b873beecd930736488696438cb6b0220547fe64427496db33bf66019dacbf0039c04199abb0122918601db38a72cfc21",
+		},
+	}
+
+	for _, s := range allSigners(t) {
+		for _, v := range publicKeys {
+			priv := mustHex32(t, v.privateKey)
+			for compressed, want := range map[bool]string{true: v.compressed, false: v.uncompressed} {
+				pubKey, err := s.PublicKey(priv, compressed)
+				if err != nil {
+					t.Fatalf("%s: %v", s.Name(), err)
+				}
+				if hex.EncodeToString(pubKey) != want {
+					t.Errorf("%s: public key of %s: expect %s but got %x", s.Name(), v.privateKey, want, pubKey)
+				}
+			}
+		}
+
+		for _, v := range signatures {
+			priv := mustHex32(t, v.privateKey)
+			hash := sha256.Sum256([]byte(v.message))
+			signed, err := s.Sign(hash, priv, mustHex32(t, v.nonce))
+			if err != nil {
+				t.Fatalf("%s: %v", s.Name(), err)
+			}
+			if hex.EncodeToString(signed) != v.signature {
+				t.Errorf("%s: signature of %q: expect %s but got %x", s.Name(), v.message, v.signature, signed)
+			}
+
+			pubKey, _ := s.PublicKey(priv, true)
+			if !s.Verify(hash, signed, pubKey) {
+				t.Errorf("%s: signature of %q should be valid", s.Name(), v.message)
+			}
+			hash[0] ^= 1
+			if s.Verify(hash, signed, pubKey) {
+				t.Errorf("%s: signature of another hash should be invalid", s.Name())
+			}
+		}
+	}
+}
+
+// 一种实现生成的签名可以被其它的实现校验.
+func TestCrossVerify(t *testing.T) {
+	all := allSigners(t)
+	for i := 0; i < 20; i++ {
+		priv, nonce, hash := randomBytes(t), randomBytes(t), randomBytes(t)
+		for _, signer := range all {
+			signed, err := signer.Sign(hash, priv, nonce)
+			if err != nil {
+				t.Fatalf("%s: %v", signer.Name(), err)
+			}
+			for _, verifier := range all {
+				for _, compressed := range []bool{true, false} {
+					pubKey, err := verifier.PublicKey(priv, compressed)
+					if err != nil {
+						t.Fatal(err)
+					}
+					if !verifier.Verify(hash, signed, pubKey) {
+						t.Errorf("signature of %s should be valid for %s", signer.Name(), verifier.Name())
+					}
+				}
+			}
+		}
+	}
+}
+
+// 使用RFC 6979的k时可恢复签名和btcec的SignCompact相同, 并且能恢复出签名者的公钥.
+func TestSignCompact(t *testing.T) {
+	for _, s := range allSigners(t) {
+		for i := 0; i < 20; i++ {
+			priv, hash := randomBytes(t), randomBytes(t)
+			nonce := btcec.NonceRFC6979(priv[:], hash[:], nil, nil, 0)
+			key, _ := btcec.PrivKeyFromBytes(priv[:])
+			for _, compressed := range []bool{true, false} {
+				signed, err := s.SignCompact(hash, priv, nonce.Bytes(), compressed)
+				if err != nil {
+					t.Fatalf("%s: %v", s.Name(), err)
+				}
+				if want := ecdsa.SignCompact(key, hash[:], compressed); !bytes.Equal(signed, want) {
+					t.Fatalf("%s: expect %x but got %x", s.Name(), want, signed)
+				}
+
+				pubKey, recoveredCompressed, err := s.RecoverCompact(hash, signed)
+				if err != nil {
+					t.Fatalf("%s: %v", s.Name(), err)
+				}
+				want, _ := s.PublicKey(priv, compressed)
+				if !bytes.Equal(pubKey, want) || recoveredCompressed != compressed {
+					t.Errorf("%s: expect public key %x but recovered %x", s.Name(), want, pubKey)
+				}
+			}
+		}
+
+		hash := randomBytes(t)
+		if _, _, err := s.RecoverCompact(hash, make([]byte, 64)); !errors.Is(err, ErrInvalidSignature) {
+			t.Errorf("%s: expect ErrInvalidSignature for a short signature but got %v", s.Name(), err)
+		}
+		if _, _, err := s.RecoverCompact(hash, make([]byte, CompactSignatureSize)); !errors.Is(err, ErrInvalidSignature) {
+			t.Errorf("%s: expect ErrInvalidSignature for a zero signature but got %v", s.Name(), err)
+		}
+	}
+}
+
+func randomBytes(t *testing.T) [32]byte {
+	var b [32]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		t.Fatal(err)
+	}
+	if !validScalar(b) {
+		return randomBytes(t)
+	}
+	return b
+}
+
+func TestInvalidKeys(t *testing.T) {
+	// 0和曲线的阶n都不是合法的私钥
+	invalid := [][32]byte{{}, mustHex32(t, "fffffffffffffffffffffffffffffffebaaedce6af48a03bbfd25e8cd0364141")}
+	hash, nonce := randomBytes(t), randomBytes(t)
+	for _, s := range allSigners(t) {
+		for _, priv := range invalid {
+			if _, err := s.PublicKey(priv, true); !errors.Is(err, ErrInvalidPrivateKey) {
+				t.Errorf("%s: expect ErrInvalidPrivateKey but got %v", s.Name(), err)
+			}
+			if _, err := s.Sign(hash, priv, nonce); !errors.Is(err, ErrInvalidPrivateKey) {
+				t.Errorf("%s: expect ErrInvalidPrivateKey but got %v", s.Name(), err)
+			}
+			if _, err := s.SignCompact(hash, priv, nonce, true); !errors.Is(err, ErrInvalidPrivateKey) {
+				t.Errorf("%s: expect ErrInvalidPrivateKey but got %v", s.Name(), err)
+			}
+		}
+		if s.Verify(hash, []byte{0x30, 0x00}, []byte{0x02}) {
+			t.Errorf("%s: malformed signature should be invalid", s.Name())
+		}
+	}
+
+	if _, err := pureGo.Sign(hash, randomBytes(t), [32]byte{}); !errors.Is(err, ErrInvalidNonce) {
+		t.Errorf("expect ErrInvalidNonce but got %v", err)
+	}
+}
+
+func TestUse(t *testing.T) {
+	defer func(s Signer) { defaultSigner = s }(Default())
+
+	for _, name := range Names() {
+		if err := Use(name); err != nil {
+			t.Fatal(err)
+		}
+		if Default().Name() != name {
+			t.Errorf("expect default signer %s but got %s", name, Default().Name())
+		}
+	}
+	if err := Use("openssl"); !errors.Is(err, ErrUnknownSigner) {
+		t.Errorf("expect ErrUnknownSigner but got %v", err)
+	}
+}
+
+// 并发签名和校验, 配合go test -race检查实现共享的状态.
+func TestConcurrent(t *testing.T) {
+	for _, s := range allSigners(t) {
+		var wg sync.WaitGroup
+		errs := make(chan error, 8)
+		for i := 0; i < cap(errs); i++ {
+			priv, nonce := randomBytes(t), randomBytes(t)
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				hash := sha256.Sum256([]byte(fmt.Sprint(i)))
+				for j := 0; j < 20; j++ {
+					signed, err := s.Sign(hash, priv, nonce)
+					if err != nil {
+						errs <- err
+						return
+					}
+					pubKey, _ := s.PublicKey(priv, false)
+					if !s.Verify(hash, signed, pubKey) {
+						errs <- fmt.Errorf("%s: invalid signature", s.Name())
+						return
+					}
+				}
+			}(i)
+		}
+		wg.Wait()
+		close(errs)
+		for err := range errs {
+			t.Error(err)
+		}
+	}
+}
+
+func TestDeterministic(t *testing.T) {
+	priv, nonce, hash := randomBytes(t), randomBytes(t), randomBytes(t)
+	a, _ := pureGo.Sign(hash, priv, nonce)
+	b, _ := pureGo.Sign(hash, priv, nonce)
+	if !bytes.Equal(a, b) {
+		t.Error("the same nonce should produce the same signature")
+	}
+}